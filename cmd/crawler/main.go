@@ -1,24 +1,41 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 
 	"email-crawler/internal/cache"
 	"email-crawler/internal/config"
+	"email-crawler/internal/crawler"
 	"email-crawler/internal/handler"
 	"email-crawler/internal/jobs"
+	"email-crawler/internal/metrics"
 )
 
 func main() {
 	// Load configuration
 	cfg := config.Load()
+	if _, err := crawler.CompileExcludePatterns(cfg.CrawlerExcludePatterns); err != nil {
+		log.Fatalf("Invalid CRAWLER_EXCLUDE_PATTERNS: %v", err)
+	}
+	if _, err := crawler.ParseProxyURL(cfg.CrawlerProxyURL); err != nil {
+		log.Fatalf("Invalid CRAWLER_PROXY_URL: %v", err)
+	}
+	if cfg.CrawlerStrategy != crawler.StrategyDFS && cfg.CrawlerStrategy != crawler.StrategyBFS {
+		log.Fatalf("Invalid CRAWLER_STRATEGY %q (use dfs or bfs)", cfg.CrawlerStrategy)
+	}
+	if _, err := crawler.ParseLogLevel(cfg.CrawlerLogLevel); err != nil {
+		log.Fatalf("Invalid CRAWLER_LOG_LEVEL: %v", err)
+	}
 
 	// Initialize Redis client for both cache and jobs
 	redisClient := redis.NewClient(&redis.Options{
@@ -28,54 +45,73 @@ func main() {
 	})
 	defer redisClient.Close()
 
+	metrics.Init(cfg)
+
 	// Initialize cache manager
-	cacheManager := cache.NewCacheManager(cfg)
+	cacheManager := cache.New(cfg)
 	defer cacheManager.Close()
 
 	// Initialize job queue and worker pool
-	var jobQueue *jobs.Queue
+	var jobQueue jobs.JobStore
 	var workerPool *jobs.WorkerPool
 
 	if cfg.AsyncEnabled {
-		jobQueue = jobs.NewQueue(redisClient, cfg)
+		jobQueue = jobs.NewJobStore(redisClient, cfg)
 		workerPool = jobs.NewWorkerPool(jobQueue, cacheManager, cfg)
 		workerPool.Start()
-
-		// Setup graceful shutdown for workers
-		setupGracefulShutdown(workerPool)
 	}
 
 	// Initialize handler
 	h := handler.NewHandler(cfg, cacheManager, jobQueue)
+	h.SetWorkerPool(workerPool)
+
+	// Setup routes; anything unmatched gets a JSON 404
+	mux := http.NewServeMux()
+	mux.HandleFunc("/scan", h.ScanHandler)
+	mux.HandleFunc("/scan/batch", h.BatchScanHandler)
+	mux.HandleFunc("/scan/stream", h.StreamScanHandler)
+	mux.HandleFunc("/cache/stats", h.CacheStatsHandler)
+	mux.HandleFunc("/cache/entry", h.CacheEntryHandler)
+	mux.HandleFunc("/cache/invalidate", h.InvalidateCacheHandler)
+	mux.HandleFunc("/cache/warm", h.WarmCacheHandler)
+	mux.HandleFunc("/scan/webhook/test", h.WebhookTestHandler)
+	mux.HandleFunc("/emails/normalize", h.NormalizeEmailsHandler)
+	mux.HandleFunc("/health", h.HealthHandler)
+	mux.HandleFunc("/ready", h.ReadyHandler)
+	if metrics.Enabled() {
+		mux.Handle("/metrics", metrics.Handler())
+	}
 
-	// Setup routes
-	http.HandleFunc("/scan", h.ScanHandler)
-	http.HandleFunc("/cache/stats", h.CacheStatsHandler)
-	http.HandleFunc("/cache/invalidate", h.InvalidateCacheHandler)
+	// Async endpoints answer 503 ASYNC_DISABLED when async is off
+	mux.HandleFunc("/scan/async", h.AsyncScanHandler)
+	mux.HandleFunc("/scan/status/{id}", h.JobStatusHandler)
+	mux.HandleFunc("/scan/cancel/{id}", h.CancelJobHandler)
+	mux.HandleFunc("/scan/jobs", h.JobsListHandler)
+	mux.HandleFunc("/scan/dead-letter", h.DeadLetterHandler)
+	mux.HandleFunc("/scan/dead-letter/{id}/retry", h.RetryDeadLetterHandler)
+	mux.HandleFunc("/workers", h.WorkerStatusHandler)
+	mux.HandleFunc("/workers/scale", h.ScaleWorkersHandler)
 
-	// Async endpoints (if enabled)
-	if cfg.AsyncEnabled {
-		http.HandleFunc("/scan/async", h.AsyncScanHandler)
-		http.HandleFunc("/scan/status/", h.JobStatusHandler)
-		http.HandleFunc("/scan/cancel/", h.CancelJobHandler)
-		http.HandleFunc("/scan/jobs", h.JobsListHandler)
-	}
+	mux.HandleFunc("/", h.NotFoundHandler)
 
 	address := cfg.ServerHost + ":" + cfg.ServerPort
 
 	fmt.Printf("=== Email Crawler Service ===\n")
 	fmt.Printf("Server listening on http://%s\n", address)
 	fmt.Printf("Max crawl depth: %d\n", cfg.MaxDepth)
+	fmt.Printf("User-Agent: %s\n", cfg.CrawlerUserAgent)
 	fmt.Printf("Cache enabled: %v\n", cfg.CacheEnabled)
 	fmt.Printf("Email deduplication: %v\n", cfg.DeduplicateEmails)
 	fmt.Printf("Async processing: %v\n", cfg.AsyncEnabled)
 
 	if cfg.CacheEnabled {
+		fmt.Printf("Cache backend: %s\n", cfg.CacheBackend)
 		fmt.Printf("Redis: %s\n", cfg.RedisAddress())
 		fmt.Printf("Cache TTL: %.0f hours\n", cfg.CacheExpirationTime.Hours())
 	}
 
 	if cfg.AsyncEnabled {
+		fmt.Printf("Job store: %s\n", cfg.JobStoreBackend)
 		fmt.Printf("Workers: %d\n", cfg.AsyncWorkers)
 		fmt.Printf("Job timeout: %s\n", cfg.AsyncJobTimeout)
 		fmt.Printf("Webhook retries: %d\n", cfg.AsyncWebhookRetries)
@@ -83,16 +119,31 @@ func main() {
 
 	fmt.Printf("\n=== API Endpoints ===\n")
 	fmt.Printf("GET    /scan?url=<website>   - Scan website for emails (sync)\n")
+	fmt.Printf("POST   /scan                 - Scan with options as a JSON body (sync)\n")
+	fmt.Printf("POST   /scan/batch           - Scan up to %d URLs in one request (sync)\n", cfg.MaxBatchSize)
+	fmt.Printf("GET    /scan/stream?url=<website> - Stream scan progress as server-sent events\n")
 	fmt.Printf("GET    /cache/stats          - View cache statistics\n")
+	fmt.Printf("GET    /cache/entry?url=<website> - Show the cached result for a URL without crawling\n")
 	fmt.Printf("DELETE /cache/invalidate     - Clear all cache\n")
 	fmt.Printf("DELETE /cache/invalidate?url=<website> - Clear specific URL cache\n")
+	fmt.Printf("DELETE /cache/invalidate?domain=<domain> - Clear every cached URL of a domain\n")
+	fmt.Printf("POST   /cache/warm           - Crawl up to %d URLs in the background to fill the cache\n", cfg.CacheWarmMaxURLs)
+	fmt.Printf("POST   /scan/webhook/test    - Send a sample payload to a webhook URL\n")
+	fmt.Printf("POST   /emails/normalize     - Show how a list of emails would be normalized\n")
+	if metrics.Enabled() {
+		fmt.Printf("GET    /metrics              - Prometheus metrics\n")
+	}
 
 	if cfg.AsyncEnabled {
 		fmt.Printf("\n=== Async Endpoints ===\n")
 		fmt.Printf("POST   /scan/async          - Queue async scan job\n")
 		fmt.Printf("GET    /scan/status/<id>    - Check job status\n")
 		fmt.Printf("DELETE /scan/cancel/<id>    - Cancel queued job\n")
-		fmt.Printf("GET    /scan/jobs           - List active jobs\n")
+		fmt.Printf("GET    /scan/jobs           - Queue stats; jobs for admins (?status=&limit=&offset=)\n")
+		fmt.Printf("GET    /scan/dead-letter    - List jobs that failed for good (admin)\n")
+		fmt.Printf("POST   /scan/dead-letter/<job_id>/retry - Queue a failed job again (admin)\n")
+		fmt.Printf("GET    /workers             - State of each worker\n")
+		fmt.Printf("POST   /workers/scale       - Scale the worker pool (admin)\n")
 	}
 
 	fmt.Printf("\n=== Examples ===\n")
@@ -106,19 +157,50 @@ func main() {
 
 	fmt.Printf("=============================\n\n")
 
-	log.Fatal(http.ListenAndServe(address, nil))
+	// CORS wraps every route so browser clients can call the API
+	srv := &http.Server{
+		Addr:    address,
+		Handler: handler.CORS(cfg.CORSAllowedOrigins, mux),
+	}
+	stopped := setupGracefulShutdown(srv, workerPool, cfg.ServerShutdownTimeout, cfg.AsyncDrainTimeout)
+
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+	// Wait for the shutdown to finish before the deferred closes run
+	<-stopped
 }
 
-func setupGracefulShutdown(workerPool *jobs.WorkerPool) {
+// setupGracefulShutdown stops the server and workers on SIGINT or SIGTERM. The
+// HTTP server drains in-flight requests for up to timeout first, so requests
+// can still read job state, then the workers get up to drainTimeout to finish
+// their current jobs. The returned channel is closed once both have stopped.
+func setupGracefulShutdown(srv *http.Server, workerPool *jobs.WorkerPool, timeout, drainTimeout time.Duration) <-chan struct{} {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	stopped := make(chan struct{})
 
 	go func() {
-		<-c
-		log.Println("Received shutdown signal...")
+		defer close(stopped)
+		sig := <-c
+		log.Printf("Received %s, shutting down...", sig)
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		log.Printf("Draining HTTP connections (up to %s)", timeout)
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Printf("HTTP server did not drain in time, closing remaining connections: %v", err)
+			srv.Close()
+		} else {
+			log.Println("HTTP server stopped")
+		}
+
 		if workerPool != nil {
-			workerPool.Stop()
+			drainCtx, drainCancel := context.WithTimeout(context.Background(), drainTimeout)
+			defer drainCancel()
+			workerPool.Drain(drainCtx)
 		}
-		os.Exit(0)
+		log.Println("Shutdown complete")
 	}()
+	return stopped
 }
\ No newline at end of file
@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"email-crawler/internal/config"
+)
+
+// Cache stores crawl results keyed by normalized URL. CacheManager is the
+// Redis-backed implementation; MemoryCache and NoopCache allow running the
+// service without Redis.
+type Cache interface {
+	Get(rawURL string) (*CachedResult, bool)
+	Set(rawURL string, result CachedResult) error
+	InvalidateURL(rawURL string) error
+	// InvalidateDomain removes every result cached for a registrable domain
+	// and returns how many were removed
+	InvalidateDomain(domain string) (int, error)
+	ClearAll() error
+	Stats() map[string]interface{}
+	// ResetStats zeroes any lookup counters reported by Stats
+	ResetStats() error
+	DeduplicateEmails(emails []string) []string
+	MarkCrawled(rawURL string, interval time.Duration) error
+	LastCrawled(rawURL string) (time.Time, bool)
+	// LockRefresh claims the background refresh of rawURL for up to ttl and
+	// reports whether no other refresh held it. UnlockRefresh releases it.
+	LockRefresh(rawURL string, ttl time.Duration) bool
+	UnlockRefresh(rawURL string) error
+	Close() error
+
+	// Ping checks that the backing store is reachable
+	Ping(ctx context.Context) error
+
+	// WithPrefix returns a view whose entries are isolated under prefix, used
+	// to keep tenants' results apart. ClearAll and Stats still span every prefix.
+	WithPrefix(prefix string) Cache
+}
+
+// New returns the cache backend selected by CACHE_BACKEND. Disabling the cache
+// with CACHE_ENABLED=false always yields a NoopCache.
+func New(cfg *config.Config) Cache {
+	if !cfg.CacheEnabled {
+		log.Println("Cache is disabled")
+		return NewNoopCache(cfg)
+	}
+
+	switch cfg.CacheBackend {
+	case "memory":
+		log.Println("Using in-memory cache")
+		return NewMemoryCache(cfg)
+	case "noop":
+		return NewNoopCache(cfg)
+	case "redis", "":
+		return NewCacheManager(cfg)
+	default:
+		log.Printf("Unknown cache backend %q, falling back to redis", cfg.CacheBackend)
+		return NewCacheManager(cfg)
+	}
+}
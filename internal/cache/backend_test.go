@@ -0,0 +1,171 @@
+package cache
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"email-crawler/internal/config"
+)
+
+// forEachBackend runs test against every Cache implementation that stores
+// results.
+func forEachBackend(t *testing.T, test func(t *testing.T, cache Cache)) {
+	t.Run("memory", func(t *testing.T) {
+		cfg := config.Load()
+		cfg.CacheKeyPrefix = "gurl-test:"
+		test(t, NewMemoryCache(cfg))
+	})
+	t.Run("redis", func(t *testing.T) {
+		test(t, newTestManager(t, fmt.Sprintf("gurl-test:%d:", time.Now().UnixNano())))
+	})
+}
+
+func TestCacheRoundTrip(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, cache Cache) {
+		const rawURL = "https://example.com/contact"
+		if _, found := cache.Get(rawURL); found {
+			t.Fatal("Get found a result before Set")
+		}
+
+		result := CachedResult{
+			Emails:    []string{"Sales@Example.com", "sales@example.com ", "info@example.com"},
+			CrawlInfo: CrawlInfo{Depth: 2, PagesVisited: 5},
+		}
+		if err := cache.Set(rawURL, result); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+		cached, found := cache.Get(rawURL)
+		if !found {
+			t.Fatal("Get missed after Set")
+		}
+		if want := []string{"info@example.com", "sales@example.com"}; !reflect.DeepEqual(cached.Emails, want) {
+			t.Errorf("cached emails = %v, want %v", cached.Emails, want)
+		}
+		if cached.CrawlInfo != result.CrawlInfo || time.Since(cached.Timestamp) > time.Minute {
+			t.Errorf("cached result = %+v, want the crawl info kept and a fresh timestamp", cached)
+		}
+
+		if err := cache.InvalidateURL(rawURL); err != nil {
+			t.Fatalf("InvalidateURL: %v", err)
+		}
+		if _, found := cache.Get(rawURL); found {
+			t.Error("Get found a result after InvalidateURL")
+		}
+	})
+}
+
+func TestCacheInvalidateDomain(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, cache Cache) {
+		for _, rawURL := range []string{
+			"https://example.com",
+			"https://example.com/about",
+			"https://shop.example.com",
+			"https://other.com",
+		} {
+			if err := cache.Set(rawURL, CachedResult{Emails: []string{"info@example.com"}}); err != nil {
+				t.Fatalf("Set(%s): %v", rawURL, err)
+			}
+		}
+
+		removed, err := cache.InvalidateDomain("www.example.com")
+		if err != nil {
+			t.Fatalf("InvalidateDomain: %v", err)
+		}
+		if removed != 3 {
+			t.Errorf("InvalidateDomain removed %d results, want 3", removed)
+		}
+		if _, found := cache.Get("https://shop.example.com"); found {
+			t.Error("a subdomain's result survived InvalidateDomain")
+		}
+		if _, found := cache.Get("https://other.com"); !found {
+			t.Error("InvalidateDomain removed another domain's result")
+		}
+	})
+}
+
+func TestCachePrefixesAndClearAll(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, cache Cache) {
+		const rawURL = "https://example.com"
+		tenantA, tenantB := cache.WithPrefix("a"), cache.WithPrefix("b")
+		if err := tenantA.Set(rawURL, CachedResult{Emails: []string{"a@example.com"}}); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+		if _, found := tenantB.Get(rawURL); found {
+			t.Error("tenant b sees tenant a's result")
+		}
+		if _, found := cache.Get(rawURL); found {
+			t.Error("the unprefixed cache sees tenant a's result")
+		}
+		if err := cache.Set(rawURL, CachedResult{Emails: []string{"info@example.com"}}); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+
+		if urls := cache.Stats()["cached_urls"]; urls != 2 {
+			t.Errorf("cached_urls = %v, want 2", urls)
+		}
+		if err := cache.ClearAll(); err != nil {
+			t.Fatalf("ClearAll: %v", err)
+		}
+		if _, found := tenantA.Get(rawURL); found {
+			t.Error("ClearAll left tenant a's result")
+		}
+		if urls := cache.Stats()["cached_urls"]; urls != 0 {
+			t.Errorf("cached_urls after ClearAll = %v, want 0", urls)
+		}
+	})
+}
+
+func TestCacheCrawlMarksAndRefreshLocks(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, cache Cache) {
+		const rawURL = "https://example.com"
+		if _, found := cache.LastCrawled(rawURL); found {
+			t.Fatal("LastCrawled found a crawl before MarkCrawled")
+		}
+		if err := cache.MarkCrawled(rawURL, time.Minute); err != nil {
+			t.Fatalf("MarkCrawled: %v", err)
+		}
+		if at, found := cache.LastCrawled(rawURL); !found || time.Since(at) > time.Minute {
+			t.Errorf("LastCrawled = %v, %v; want the crawl just marked", at, found)
+		}
+
+		if !cache.LockRefresh(rawURL, time.Minute) {
+			t.Fatal("LockRefresh failed on an unlocked URL")
+		}
+		if cache.LockRefresh(rawURL, time.Minute) {
+			t.Error("LockRefresh succeeded while the refresh was locked")
+		}
+		if err := cache.UnlockRefresh(rawURL); err != nil {
+			t.Fatalf("UnlockRefresh: %v", err)
+		}
+		if !cache.LockRefresh(rawURL, time.Minute) {
+			t.Error("LockRefresh failed after UnlockRefresh")
+		}
+	})
+}
+
+func TestNoopCacheStoresNothing(t *testing.T) {
+	cache := NewNoopCache(config.Load())
+	const rawURL = "https://example.com"
+
+	if err := cache.Set(rawURL, CachedResult{Emails: []string{"info@example.com"}}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if _, found := cache.Get(rawURL); found {
+		t.Error("Get found a result in the noop cache")
+	}
+	if err := cache.MarkCrawled(rawURL, time.Minute); err != nil {
+		t.Fatalf("MarkCrawled: %v", err)
+	}
+	if _, found := cache.LastCrawled(rawURL); found {
+		t.Error("LastCrawled found a crawl in the noop cache")
+	}
+	// With nothing cached there is no stale result to refresh
+	if cache.LockRefresh(rawURL, time.Minute) {
+		t.Error("LockRefresh succeeded on the noop cache")
+	}
+	if got := cache.DeduplicateEmails([]string{"A@example.com", "a@example.com"}); len(got) != 1 {
+		t.Errorf("DeduplicateEmails = %v, want one address", got)
+	}
+}
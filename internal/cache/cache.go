@@ -8,28 +8,78 @@ import (
 	"log"
 	"net/url"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	"golang.org/x/net/publicsuffix"
 
 	"email-crawler/internal/config"
 )
 
+type CrawlInfo struct {
+	Depth        int `json:"depth"`
+	PagesVisited int `json:"pages_visited"`
+	DepthReached int `json:"depth_reached"`
+
+	// Truncated marks a crawl stopped at CRAWLER_MAX_EMAILS
+	Truncated bool `json:"truncated,omitempty"`
+}
+
 type CachedResult struct {
 	Emails    []string  `json:"emails"`
 	Timestamp time.Time `json:"timestamp"`
-	CrawlInfo struct {
-		Depth        int `json:"depth"`
-		PagesVisited int `json:"pages_visited"`
-	} `json:"crawl_info"`
+	CrawlInfo CrawlInfo `json:"crawl_info"`
+
+	// Labels maps an email to the label it appeared under (e.g. "Sales")
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// OriginalCase maps a lowercased email to the casing it was first seen with
+	OriginalCase map[string]string `json:"original_case,omitempty"`
+
+	// Sources maps a lowercased email to the pages it was found on
+	Sources map[string][]string `json:"sources,omitempty"`
+}
+
+// CoversDepth reports whether the result came from a crawl at least depth
+// levels deep. Entries cached under the plain URL may predate a change to the
+// default depth, so callers check this before serving them.
+func (r CachedResult) CoversDepth(depth int) bool {
+	return r.CrawlInfo.Depth >= depth
+}
+
+// IsStale reports whether the result is older than ttl. Stale results are only
+// returned by Get within the CACHE_STALE_WHILE_REVALIDATE_SECONDS window.
+func (r CachedResult) IsStale(ttl time.Duration) bool {
+	return time.Since(r.Timestamp) > ttl
+}
+
+// entryTTL is how long results are kept: the cache TTL plus the window in which
+// a stale result may still be served while it is refreshed.
+func entryTTL(cfg *config.Config) time.Duration {
+	return cfg.CacheExpirationTime + cfg.CacheStaleWhileRevalidate
+}
+
+// prepare normalizes a result before it is stored. Emails are deduplicated
+// and the timestamp is set to now.
+func (r CachedResult) prepare(cfg *config.Config) CachedResult {
+	r.Emails = deduplicateEmails(cfg, r.Emails)
+	r.Timestamp = time.Now()
+	return r
 }
 
 type CacheManager struct {
-	client    *redis.Client
+	// shards holds one client per Redis instance; keys are routed by ring
+	shards    []*redis.Client
+	addresses []string
+	ring      *hashRing
 	config    *config.Config
 	ctx       context.Context
 	enabled   bool
+
+	// prefix namespaces keys for a tenant; see WithPrefix
+	prefix string
 }
 
 func NewCacheManager(cfg *config.Config) *CacheManager {
@@ -44,47 +94,149 @@ func NewCacheManager(cfg *config.Config) *CacheManager {
 		}
 	}
 
-	// Create Redis client
-	client := redis.NewClient(&redis.Options{
-		Addr:     cfg.RedisAddress(),
-		Password: cfg.RedisPassword,
-		DB:       cfg.RedisDB,
-	})
+	addresses := cfg.CacheRedisShards
+	if len(addresses) == 0 {
+		addresses = []string{cfg.RedisAddress()}
+	}
 
-	// Test connection
-	if err := client.Ping(ctx).Err(); err != nil {
-		log.Printf("Failed to connect to Redis: %v. Cache will be disabled.", err)
-		return &CacheManager{
-			config:  cfg,
-			ctx:     ctx,
-			enabled: false,
+	// Create one Redis client per shard
+	shards := make([]*redis.Client, 0, len(addresses))
+	for _, address := range addresses {
+		client := redis.NewClient(&redis.Options{
+			Addr:     address,
+			Password: cfg.RedisPassword,
+			DB:       cfg.RedisDB,
+		})
+
+		// Test connection
+		if err := client.Ping(ctx).Err(); err != nil {
+			log.Printf("Failed to connect to Redis at %s: %v. Cache will be disabled.", address, err)
+			client.Close()
+			for _, shard := range shards {
+				shard.Close()
+			}
+			return &CacheManager{
+				config:  cfg,
+				ctx:     ctx,
+				enabled: false,
+			}
 		}
-	}
 
-	log.Printf("Connected to Redis at %s", cfg.RedisAddress())
+		log.Printf("Connected to Redis at %s", address)
+		shards = append(shards, client)
+	}
 
 	return &CacheManager{
-		client:  client,
-		config:  cfg,
-		ctx:     ctx,
-		enabled: true,
+		shards:    shards,
+		addresses: addresses,
+		ring:      newHashRing(addresses),
+		config:    cfg,
+		ctx:       ctx,
+		enabled:   true,
+	}
+}
+
+// clientFor returns the shard responsible for key.
+func (cm *CacheManager) clientFor(key string) *redis.Client {
+	return cm.shards[cm.ring.locate(key)]
+}
+
+// metaKeyMarker starts the segment, right after CACHE_KEY_PREFIX, of every key
+// that isn't a cached result, so instances with different prefixes never share
+// them and SCANs over the results can skip them. Tenant cache prefixes must
+// not start with it.
+const metaKeyMarker = "_"
+
+// lastCrawlKeySegment marks when a URL was last crawled; see MarkCrawled
+const lastCrawlKeySegment = "lastcrawl:"
+
+// metaKey returns the key of kind (one of the ...KeySegment constants)
+// followed by rest, under CACHE_KEY_PREFIX.
+func (cm *CacheManager) metaKey(kind, rest string) string {
+	return cm.config.CacheKeyPrefix + metaKeyMarker + kind + rest
+}
+
+// refreshLockKeySegment marks a URL whose stale result is being refreshed
+const refreshLockKeySegment = "refresh:"
+
+// depthFragment marks a URL crawled to a non-default depth; see DepthKey
+const depthFragment = "depth="
+
+// DepthKey returns the key rawURL is cached under when crawled to depth rather
+// than defaultDepth, so results of different depths are never mixed up.
+// InvalidateURL only removes the default-depth entry.
+func DepthKey(rawURL string, depth, defaultDepth int) string {
+	if depth == defaultDepth {
+		return rawURL
+	}
+	if i := strings.IndexByte(rawURL, '#'); i >= 0 {
+		rawURL = rawURL[:i]
+	}
+	return rawURL + "#" + depthFragment + strconv.Itoa(depth)
+}
+
+// generateKey returns the key a result is stored under: keyPrefix (from
+// CACHE_KEY_PREFIX) followed by the tenant-prefixed URL hash.
+func generateKey(keyPrefix, prefix, rawURL string) string {
+	return keyPrefix + prefixedHash(prefix, rawURL)
+}
+
+// prefixedHash returns the URL hash, namespaced by prefix when one is set.
+func prefixedHash(prefix, rawURL string) string {
+	if prefix == "" {
+		return urlHash(rawURL)
 	}
+	return prefix + ":" + urlHash(rawURL)
 }
 
-func (cm *CacheManager) generateKey(rawURL string) string {
+// urlHash returns the hex SHA256 of the normalized URL.
+func urlHash(rawURL string) string {
 	// Normalize URL
 	parsedURL, err := url.Parse(rawURL)
 	if err != nil {
-		return fmt.Sprintf("crawler:emails:%x", sha256.Sum256([]byte(rawURL)))
+		return fmt.Sprintf("%x", sha256.Sum256([]byte(rawURL)))
 	}
 	
 	// Create normalized URL (lowercase domain, remove trailing slash)
 	normalizedURL := strings.ToLower(parsedURL.Host) + parsedURL.Path
 	normalizedURL = strings.TrimSuffix(normalizedURL, "/")
+	if strings.HasPrefix(parsedURL.Fragment, depthFragment) {
+		normalizedURL += "#" + parsedURL.Fragment
+	}
 	
 	// Generate SHA256 hash
 	hash := sha256.Sum256([]byte(normalizedURL))
-	return fmt.Sprintf("crawler:emails:%x", hash)
+	return fmt.Sprintf("%x", hash)
+}
+
+// registrableDomain returns the registrable domain (example.com for
+// https://www.example.com/contact) that InvalidateDomain groups rawURL under.
+// rawURL may also be a bare host. Hosts without one, like IPs, are used as is.
+func registrableDomain(rawURL string) string {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return strings.ToLower(rawURL)
+	}
+	host := strings.ToLower(parsedURL.Hostname())
+	if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return domain
+	}
+	return host
+}
+
+// domainKeySegment names the set of cache keys stored for each domain
+const domainKeySegment = "domain:"
+
+// domainKey returns the key of the set listing cache keys under domain for
+// this manager's prefix.
+func (cm *CacheManager) domainKey(domain string) string {
+	if cm.prefix == "" {
+		return cm.metaKey(domainKeySegment, domain)
+	}
+	return cm.metaKey(domainKeySegment, cm.prefix+":"+domain)
 }
 
 func (cm *CacheManager) Get(rawURL string) (*CachedResult, bool) {
@@ -92,63 +244,158 @@ func (cm *CacheManager) Get(rawURL string) (*CachedResult, bool) {
 		return nil, false
 	}
 
-	key := cm.generateKey(rawURL)
+	key := generateKey(cm.config.CacheKeyPrefix, cm.prefix, rawURL)
 	
-	data, err := cm.client.Get(cm.ctx, key).Result()
+	data, err := cm.clientFor(key).Get(cm.ctx, key).Result()
 	if err != nil {
 		if err != redis.Nil {
 			log.Printf("Redis GET error: %v", err)
+		} else {
+			cm.countLookup(false)
 		}
 		return nil, false
 	}
 
+	value, err := decodeValue([]byte(data))
+	if err != nil {
+		log.Printf("Failed to read cached result: %v", err)
+		cm.countLookup(false)
+		return nil, false
+	}
+
 	var result CachedResult
-	if err := json.Unmarshal([]byte(data), &result); err != nil {
+	if err := json.Unmarshal(value, &result); err != nil {
 		log.Printf("Failed to unmarshal cached result: %v", err)
+		cm.countLookup(false)
 		return nil, false
 	}
 
+	cm.countLookup(true)
 	return &result, true
 }
 
-func (cm *CacheManager) Set(rawURL string, emails []string, depth int, pagesVisited int) error {
+// Lookup counters shared by every tenant prefix; see Stats and ResetStats
+const (
+	statsHitsKeySegment   = "stats:hits"
+	statsMissesKeySegment = "stats:misses"
+)
+
+// statsTimeout bounds a counter increment, which runs in the background
+const statsTimeout = time.Second
+
+// countLookup increments the hit or miss counter without waiting for Redis,
+// so counting never slows down or fails a lookup.
+func (cm *CacheManager) countLookup(hit bool) {
+	key := cm.metaKey(statsMissesKeySegment, "")
+	if hit {
+		key = cm.metaKey(statsHitsKeySegment, "")
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), statsTimeout)
+		defer cancel()
+		cm.clientFor(key).Incr(ctx, key)
+	}()
+}
+
+func (cm *CacheManager) Set(rawURL string, result CachedResult) error {
 	if !cm.enabled {
 		return nil
 	}
 
 	// Deduplicate and sort emails
-	deduplicatedEmails := cm.DeduplicateEmails(emails)
-
-	result := CachedResult{
-		Emails:    deduplicatedEmails,
-		Timestamp: time.Now(),
-		CrawlInfo: struct {
-			Depth        int `json:"depth"`
-			PagesVisited int `json:"pages_visited"`
-		}{
-			Depth:        depth,
-			PagesVisited: pagesVisited,
-		},
-	}
+	result = result.prepare(cm.config)
 
 	data, err := json.Marshal(result)
 	if err != nil {
 		return fmt.Errorf("failed to marshal cache data: %v", err)
 	}
+	data, err = encodeValue(data, cm.config.CacheCompression)
+	if err != nil {
+		return err
+	}
 
-	key := cm.generateKey(rawURL)
+	key := generateKey(cm.config.CacheKeyPrefix, cm.prefix, rawURL)
 	
-	err = cm.client.Set(cm.ctx, key, data, cm.config.CacheExpirationTime).Err()
+	err = cm.clientFor(key).Set(cm.ctx, key, data, entryTTL(cm.config)).Err()
 	if err != nil {
 		return fmt.Errorf("failed to set cache: %v", err)
 	}
 
-	log.Printf("Cached result for %s with %d emails", rawURL, len(deduplicatedEmails))
+	// List the key under its domain for InvalidateDomain. The set's TTL is
+	// pushed out with each entry, so it expires along with the newest one.
+	domainKey := cm.domainKey(registrableDomain(rawURL))
+	pipe := cm.clientFor(domainKey).TxPipeline()
+	pipe.SAdd(cm.ctx, domainKey, key)
+	pipe.Expire(cm.ctx, domainKey, entryTTL(cm.config))
+	if _, err := pipe.Exec(cm.ctx); err != nil {
+		log.Printf("Failed to index %s under its domain: %v", rawURL, err)
+	}
+
+	log.Printf("Cached result for %s with %d emails", rawURL, len(result.Emails))
 	return nil
 }
 
+// MarkCrawled records that rawURL was just crawled. The marker expires after
+// interval, so LastCrawled only reports crawls within the cooldown window.
+func (cm *CacheManager) MarkCrawled(rawURL string, interval time.Duration) error {
+	if !cm.enabled || interval <= 0 {
+		return nil
+	}
+
+	key := cm.metaKey(lastCrawlKeySegment, prefixedHash(cm.prefix, rawURL))
+	return cm.clientFor(key).Set(cm.ctx, key, time.Now().Unix(), interval).Err()
+}
+
+// LastCrawled returns when rawURL was last crawled, if still within the
+// interval passed to MarkCrawled.
+func (cm *CacheManager) LastCrawled(rawURL string) (time.Time, bool) {
+	if !cm.enabled {
+		return time.Time{}, false
+	}
+
+	key := cm.metaKey(lastCrawlKeySegment, prefixedHash(cm.prefix, rawURL))
+	unix, err := cm.clientFor(key).Get(cm.ctx, key).Int64()
+	if err != nil {
+		if err != redis.Nil {
+			log.Printf("Redis GET error: %v", err)
+		}
+		return time.Time{}, false
+	}
+	return time.Unix(unix, 0), true
+}
+
+// LockRefresh takes a Redis lock on refreshing rawURL that expires after ttl,
+// so a crashed refresh can't block later ones. Errors count as locked.
+func (cm *CacheManager) LockRefresh(rawURL string, ttl time.Duration) bool {
+	if !cm.enabled {
+		return false
+	}
+
+	key := cm.metaKey(refreshLockKeySegment, prefixedHash(cm.prefix, rawURL))
+	ok, err := cm.clientFor(key).SetNX(cm.ctx, key, time.Now().Unix(), ttl).Result()
+	if err != nil {
+		log.Printf("Redis SETNX error: %v", err)
+		return false
+	}
+	return ok
+}
+
+func (cm *CacheManager) UnlockRefresh(rawURL string) error {
+	if !cm.enabled {
+		return nil
+	}
+
+	key := cm.metaKey(refreshLockKeySegment, prefixedHash(cm.prefix, rawURL))
+	return cm.clientFor(key).Del(cm.ctx, key).Err()
+}
+
 func (cm *CacheManager) DeduplicateEmails(emails []string) []string {
-	if !cm.config.DeduplicateEmails {
+	return deduplicateEmails(cm.config, emails)
+}
+
+// deduplicateEmails is the normalization shared by every Cache implementation.
+func deduplicateEmails(cfg *config.Config, emails []string) []string {
+	if !cfg.DeduplicateEmails {
 		return emails
 	}
 
@@ -180,55 +427,203 @@ func (cm *CacheManager) InvalidateURL(rawURL string) error {
 		return nil
 	}
 
-	key := cm.generateKey(rawURL)
-	return cm.client.Del(cm.ctx, key).Err()
+	key := generateKey(cm.config.CacheKeyPrefix, cm.prefix, rawURL)
+	if err := cm.clientFor(key).Del(cm.ctx, key).Err(); err != nil {
+		return err
+	}
+
+	domainKey := cm.domainKey(registrableDomain(rawURL))
+	return cm.clientFor(domainKey).SRem(cm.ctx, domainKey, key).Err()
 }
 
-func (cm *CacheManager) ClearAll() error {
+// InvalidateDomain removes every cached result under domain's registrable
+// domain, whatever the path or depth, and returns how many were removed.
+// Keys in the domain's set that already expired are dropped with it.
+func (cm *CacheManager) InvalidateDomain(domain string) (int, error) {
 	if !cm.enabled {
-		return nil
+		return 0, nil
 	}
 
-	// Get all keys matching our pattern
-	keys, err := cm.client.Keys(cm.ctx, "crawler:emails:*").Result()
+	domainKey := cm.domainKey(registrableDomain(domain))
+	domainClient := cm.clientFor(domainKey)
+	keys, err := domainClient.SMembers(cm.ctx, domainKey).Result()
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	if len(keys) > 0 {
-		return cm.client.Del(cm.ctx, keys...).Err()
+	removed := 0
+	for _, key := range keys {
+		n, err := cm.clientFor(key).Del(cm.ctx, key).Result()
+		if err != nil {
+			return removed, err
+		}
+		removed += int(n)
+	}
+	return removed, domainClient.Del(cm.ctx, domainKey).Err()
+}
+
+func (cm *CacheManager) ClearAll() error {
+	if !cm.enabled {
+		return nil
+	}
+
+	for _, client := range cm.shards {
+		err := cm.scanKeys(client, func(keys []string) error {
+			// UNLINK frees memory in the background; Redis before 4.0 only has DEL
+			err := client.Unlink(cm.ctx, keys...).Err()
+			if err != nil && strings.Contains(err.Error(), "unknown command") {
+				err = client.Del(cm.ctx, keys...).Err()
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// scanBatchSize is the COUNT hint for each SCAN call over the cache keys.
+const scanBatchSize = 500
+
+// scanKeys calls fn with each batch of cached result keys on client, leaving
+// out the other keys under CACHE_KEY_PREFIX (see metaKey). It uses SCAN rather
+// than KEYS so large keyspaces don't block Redis.
+func (cm *CacheManager) scanKeys(client *redis.Client, fn func(keys []string) error) error {
+	metaPrefix := cm.config.CacheKeyPrefix + metaKeyMarker
+	var cursor uint64
+	for {
+		scanned, next, err := client.Scan(cm.ctx, cursor, cm.config.CacheKeyPrefix+"*", scanBatchSize).Result()
+		if err != nil {
+			return err
+		}
+		keys := scanned[:0]
+		for _, key := range scanned {
+			if !strings.HasPrefix(key, metaPrefix) {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
 func (cm *CacheManager) Stats() map[string]interface{} {
 	stats := map[string]interface{}{
 		"enabled": cm.enabled,
+		"backend": "redis",
 	}
 
 	if !cm.enabled {
 		return stats
 	}
 
-	// Get Redis info
-	info, err := cm.client.Info(cm.ctx, "memory").Result()
-	if err == nil {
-		stats["redis_info"] = info
+	totalKeys := 0
+	shardStats := make([]map[string]interface{}, 0, len(cm.shards))
+	for i, client := range cm.shards {
+		shardStat := map[string]interface{}{
+			"address": cm.addresses[i],
+		}
+
+		// Get Redis info
+		info, err := client.Info(cm.ctx, "memory").Result()
+		if err == nil {
+			shardStat["redis_info"] = info
+		}
+
+		// Count our keys; SCAN can repeat a key while Redis rehashes, so this
+		// is approximate on a busy shard
+		count := 0
+		err = cm.scanKeys(client, func(keys []string) error {
+			count += len(keys)
+			return nil
+		})
+		if err == nil {
+			shardStat["cached_urls"] = count
+			totalKeys += count
+		}
+
+		shardStats = append(shardStats, shardStat)
 	}
 
-	// Count our keys
-	keys, err := cm.client.Keys(cm.ctx, "crawler:emails:*").Result()
-	if err == nil {
-		stats["cached_urls"] = len(keys)
+	stats["cached_urls"] = totalKeys
+
+	hitsKey, missesKey := cm.metaKey(statsHitsKeySegment, ""), cm.metaKey(statsMissesKeySegment, "")
+	hits, _ := cm.clientFor(hitsKey).Get(cm.ctx, hitsKey).Int64()
+	misses, _ := cm.clientFor(missesKey).Get(cm.ctx, missesKey).Int64()
+	stats["hits"] = hits
+	stats["misses"] = misses
+	stats["hit_ratio"] = 0.0
+	if hits+misses > 0 {
+		stats["hit_ratio"] = float64(hits) / float64(hits+misses)
+	}
+	if len(cm.shards) == 1 {
+		if info, ok := shardStats[0]["redis_info"]; ok {
+			stats["redis_info"] = info
+		}
+	} else {
+		stats["shards"] = shardStats
 	}
 
 	return stats
 }
 
-func (cm *CacheManager) Close() error {
-	if cm.enabled && cm.client != nil {
-		return cm.client.Close()
+// ResetStats zeroes the hit and miss counters, leaving cached results alone.
+func (cm *CacheManager) ResetStats() error {
+	if !cm.enabled {
+		return nil
+	}
+
+	for _, key := range []string{cm.metaKey(statsHitsKeySegment, ""), cm.metaKey(statsMissesKeySegment, "")} {
+		if err := cm.clientFor(key).Del(cm.ctx, key).Err(); err != nil {
+			return err
+		}
 	}
 	return nil
+}
+
+// WithPrefix returns a view of the cache whose entries are isolated under
+// prefix. It shares the underlying connections, so only the original should be
+// closed.
+func (cm *CacheManager) WithPrefix(prefix string) Cache {
+	view := *cm
+	view.prefix = prefix
+	return &view
+}
+
+// Ping checks every shard. A cache that failed to connect at startup runs
+// disabled and always pings successfully.
+func (cm *CacheManager) Ping(ctx context.Context) error {
+	if !cm.enabled {
+		return nil
+	}
+
+	for i, client := range cm.shards {
+		if err := client.Ping(ctx).Err(); err != nil {
+			return fmt.Errorf("redis %s: %w", cm.addresses[i], err)
+		}
+	}
+	return nil
+}
+
+func (cm *CacheManager) Close() error {
+	if !cm.enabled {
+		return nil
+	}
+
+	var firstErr error
+	for _, client := range cm.shards {
+		if err := client.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
 }
\ No newline at end of file
@@ -0,0 +1,195 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"email-crawler/internal/config"
+)
+
+// newTestManager connects to the Redis at REDIS_TEST_ADDR with keys under
+// keyPrefix, skipping the test when the variable isn't set. Every key under
+// keyPrefix is deleted when the test ends.
+func newTestManager(t *testing.T, keyPrefix string) *CacheManager {
+	t.Helper()
+	address := os.Getenv("REDIS_TEST_ADDR")
+	if address == "" {
+		t.Skip("REDIS_TEST_ADDR not set")
+	}
+	return newShardedTestManager(t, keyPrefix, []string{address})
+}
+
+// newShardedTestManager connects to every Redis in addresses with keys under
+// keyPrefix, cleaning up like newTestManager.
+func newShardedTestManager(t *testing.T, keyPrefix string, addresses []string) *CacheManager {
+	t.Helper()
+	cfg := config.Load()
+	cfg.CacheEnabled = true
+	cfg.CacheRedisShards = addresses
+	cfg.CacheKeyPrefix = keyPrefix
+	cm := NewCacheManager(cfg)
+	if !cm.enabled {
+		t.Fatalf("could not connect to Redis at %v", addresses)
+	}
+
+	t.Cleanup(func() {
+		for _, client := range cm.shards {
+			keys, _ := client.Keys(context.Background(), keyPrefix+"*").Result()
+			if len(keys) > 0 {
+				client.Del(context.Background(), keys...)
+			}
+		}
+		cm.Close()
+	})
+	return cm
+}
+
+func TestKeysStayUnderCacheKeyPrefix(t *testing.T) {
+	cfg := config.Load()
+	cfg.CacheKeyPrefix = "staging:emails:"
+	cm := &CacheManager{config: cfg, prefix: "team-a"}
+
+	keys := []string{
+		generateKey(cfg.CacheKeyPrefix, cm.prefix, "https://example.com"),
+		cm.metaKey(lastCrawlKeySegment, prefixedHash(cm.prefix, "https://example.com")),
+		cm.metaKey(statsHitsKeySegment, ""),
+		cm.metaKey(statsMissesKeySegment, ""),
+		cm.metaKey(refreshLockKeySegment, prefixedHash(cm.prefix, "https://example.com")),
+		cm.domainKey("example.com"),
+	}
+	for _, key := range keys {
+		if !strings.HasPrefix(key, cfg.CacheKeyPrefix) {
+			t.Errorf("key %q is not under CACHE_KEY_PREFIX %q", key, cfg.CacheKeyPrefix)
+		}
+	}
+}
+
+func TestManagersWithDifferentPrefixesAreIsolated(t *testing.T) {
+	const rawURL = "https://example.com/contact"
+	prod := newTestManager(t, "gurl-test:prod:")
+	staging := newTestManager(t, "gurl-test:staging:")
+
+	if err := prod.Set(rawURL, CachedResult{Emails: []string{"prod@example.com"}}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if _, found := staging.Get(rawURL); found {
+		t.Error("staging sees prod's cached result")
+	}
+
+	if err := prod.MarkCrawled(rawURL, time.Minute); err != nil {
+		t.Fatalf("MarkCrawled: %v", err)
+	}
+	if _, found := staging.LastCrawled(rawURL); found {
+		t.Error("staging is in prod's SCAN_MIN_INTERVAL cooldown")
+	}
+
+	if !prod.LockRefresh(rawURL, time.Minute) {
+		t.Fatal("prod could not take the refresh lock")
+	}
+	if !staging.LockRefresh(rawURL, time.Minute) {
+		t.Error("staging is blocked by prod's refresh lock")
+	}
+
+	if err := staging.Set(rawURL, CachedResult{Emails: []string{"staging@example.com"}}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if _, err := staging.InvalidateDomain("example.com"); err != nil {
+		t.Fatalf("InvalidateDomain: %v", err)
+	}
+	if _, found := staging.Get(rawURL); found {
+		t.Error("staging's InvalidateDomain left staging's result")
+	}
+	if result, found := prod.Get(rawURL); !found || result.Emails[0] != "prod@example.com" {
+		t.Error("staging's InvalidateDomain removed prod's result")
+	}
+
+	if err := staging.ClearAll(); err != nil {
+		t.Fatalf("ClearAll: %v", err)
+	}
+	if _, found := prod.Get(rawURL); !found {
+		t.Error("staging's ClearAll removed prod's cached result")
+	}
+	if _, found := prod.LastCrawled(rawURL); !found {
+		t.Error("staging's ClearAll removed prod's cooldown")
+	}
+
+	// Counters are bumped in the background
+	time.Sleep(100 * time.Millisecond)
+	if hits := staging.Stats()["hits"].(int64); hits != 0 {
+		t.Errorf("staging counts %d hits from prod's lookups, want 0", hits)
+	}
+	if urls := prod.Stats()["cached_urls"].(int); urls != 1 {
+		t.Errorf("prod cached_urls = %d, want 1 (meta keys must not be counted)", urls)
+	}
+}
+
+func TestShardedCacheDistributesKeys(t *testing.T) {
+	addresses := strings.Split(os.Getenv("REDIS_TEST_SHARDS"), ",")
+	if len(addresses) < 2 {
+		t.Skip("REDIS_TEST_SHARDS does not list two or more Redis addresses")
+	}
+	cm := newShardedTestManager(t, fmt.Sprintf("gurl-test:%d:", time.Now().UnixNano()), addresses)
+
+	const total = 50
+	for i := 0; i < total; i++ {
+		rawURL := fmt.Sprintf("https://site%d.example.com", i)
+		if err := cm.Set(rawURL, CachedResult{Emails: []string{fmt.Sprintf("info@site%d.example.com", i)}}); err != nil {
+			t.Fatalf("Set(%s): %v", rawURL, err)
+		}
+	}
+	for i := 0; i < total; i++ {
+		rawURL := fmt.Sprintf("https://site%d.example.com", i)
+		if result, found := cm.Get(rawURL); !found || result.Emails[0] != fmt.Sprintf("info@site%d.example.com", i) {
+			t.Errorf("Get(%s) = %v, %v; want the result just set", rawURL, result.Emails, found)
+		}
+	}
+
+	stats := cm.Stats()
+	if urls := stats["cached_urls"]; urls != total {
+		t.Errorf("cached_urls = %v, want %d", urls, total)
+	}
+	shards := stats["shards"].([]map[string]interface{})
+	if len(shards) != len(addresses) {
+		t.Fatalf("stats list %d shards, want %d", len(shards), len(addresses))
+	}
+	for _, shard := range shards {
+		if urls, _ := shard["cached_urls"].(int); urls == 0 || urls == total {
+			t.Errorf("shard %v holds %d of %d results, want them spread", shard["address"], urls, total)
+		}
+	}
+
+	if err := cm.ClearAll(); err != nil {
+		t.Fatalf("ClearAll: %v", err)
+	}
+	if urls := cm.Stats()["cached_urls"]; urls != 0 {
+		t.Errorf("cached_urls after ClearAll = %v, want 0", urls)
+	}
+}
+
+func TestStatsAndClearAllPageThroughManyKeys(t *testing.T) {
+	cm := newTestManager(t, fmt.Sprintf("gurl-test:%d:", time.Now().UnixNano()))
+
+	// More keys than one SCAN batch, so the cursor has to be followed
+	const total = 2*scanBatchSize + 100
+	for i := 0; i < total; i++ {
+		if err := cm.Set(fmt.Sprintf("https://site%d.example.com", i), CachedResult{}); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+	}
+	// Writes a hit counter, which is not a cached result
+	cm.Get("https://site0.example.com")
+
+	if urls := cm.Stats()["cached_urls"]; urls != total {
+		t.Errorf("cached_urls = %v, want %d", urls, total)
+	}
+	if err := cm.ClearAll(); err != nil {
+		t.Fatalf("ClearAll: %v", err)
+	}
+	if urls := cm.Stats()["cached_urls"]; urls != 0 {
+		t.Errorf("cached_urls after ClearAll = %v, want 0", urls)
+	}
+}
@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// gzipMarker is the first byte of a gzip-compressed cache value. Plain values
+// are JSON objects starting with '{', so entries written before compression
+// was enabled (or with it disabled) still decode.
+const gzipMarker byte = 0x01
+
+// encodeValue returns data as stored in Redis, gzipped behind gzipMarker when
+// compress is set.
+func encodeValue(data []byte, compress bool) ([]byte, error) {
+	if !compress {
+		return data, nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(gzipMarker)
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress cache data: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress cache data: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeValue returns the JSON of a stored value, compressed or not.
+func decodeValue(data []byte) ([]byte, error) {
+	if len(data) == 0 || data[0] != gzipMarker {
+		return data, nil
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(data[1:]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress cache data: %v", err)
+	}
+	defer gz.Close()
+	decoded, err := io.ReadAll(gz)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress cache data: %v", err)
+	}
+	return decoded, nil
+}
@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCompressedValuesRoundTrip(t *testing.T) {
+	result := CachedResult{
+		Labels:  map[string]string{},
+		Sources: map[string][]string{},
+	}
+	for i := 0; i < 500; i++ {
+		email := fmt.Sprintf("person%d@example.com", i)
+		result.Emails = append(result.Emails, email)
+		result.Labels[email] = "Sales"
+		result.Sources[email] = []string{fmt.Sprintf("https://example.com/team/%d", i/20)}
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	stored, err := encodeValue(data, true)
+	if err != nil {
+		t.Fatalf("encodeValue: %v", err)
+	}
+	if len(stored)*2 > len(data) {
+		t.Errorf("compressed value is %d bytes for %d bytes of JSON, want under half", len(stored), len(data))
+	}
+	decoded, err := decodeValue(stored)
+	if err != nil {
+		t.Fatalf("decodeValue: %v", err)
+	}
+	var got CachedResult
+	if err := json.Unmarshal(decoded, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if !reflect.DeepEqual(got, result) {
+		t.Error("decoded result differs from the one stored")
+	}
+
+	// Values written without compression decode as they are
+	if plain, err := decodeValue(data); err != nil || !bytes.Equal(plain, data) {
+		t.Errorf("decodeValue of a plain value = %d bytes, %v; want it unchanged", len(plain), err)
+	}
+	if uncompressed, _ := encodeValue(data, false); !bytes.Equal(uncompressed, data) {
+		t.Error("encodeValue changed the value with compression off")
+	}
+}
+
+func TestCompressionKeepsEarlierEntriesReadable(t *testing.T) {
+	cm := newTestManager(t, fmt.Sprintf("gurl-test:%d:", time.Now().UnixNano()))
+	cm.config.CacheCompression = false
+	if err := cm.Set("https://plain.example.com", CachedResult{Emails: []string{"plain@example.com"}}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	cm.config.CacheCompression = true
+	if err := cm.Set("https://gzip.example.com", CachedResult{Emails: []string{"gzip@example.com"}}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	for rawURL, want := range map[string]string{
+		"https://plain.example.com": "plain@example.com",
+		"https://gzip.example.com":  "gzip@example.com",
+	} {
+		if result, found := cm.Get(rawURL); !found || len(result.Emails) != 1 || result.Emails[0] != want {
+			t.Errorf("Get(%s) = %v, %v; want [%s]", rawURL, result, found, want)
+		}
+	}
+}
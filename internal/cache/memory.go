@@ -0,0 +1,277 @@
+package cache
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"email-crawler/internal/config"
+)
+
+type memoryEntry struct {
+	result    CachedResult
+	expiresAt time.Time
+
+	// prefix and domain let InvalidateDomain find the entry
+	prefix string
+	domain string
+}
+
+// memorySweepInterval is how often MemoryCache drops expired entries that were
+// never read again
+const memorySweepInterval = time.Minute
+
+// MemoryCache keeps crawl results in-process. Entries are lost on restart.
+type MemoryCache struct {
+	mu        *sync.RWMutex
+	config    *config.Config
+	entries   map[string]memoryEntry
+	lastCrawl map[string]memoryCrawlMark
+	// refreshing maps URLs being refreshed to when their lock expires
+	refreshing map[string]time.Time
+
+	// prefix namespaces keys for a tenant; see WithPrefix
+	prefix string
+
+	// done stops the sweep loop; closeOnce lets every view call Close
+	done      chan struct{}
+	closeOnce *sync.Once
+}
+
+type memoryCrawlMark struct {
+	at        time.Time
+	expiresAt time.Time
+}
+
+func NewMemoryCache(cfg *config.Config) *MemoryCache {
+	mc := &MemoryCache{
+		mu:        &sync.RWMutex{},
+		config:    cfg,
+		entries:   make(map[string]memoryEntry),
+		lastCrawl: make(map[string]memoryCrawlMark),
+
+		refreshing: make(map[string]time.Time),
+		done:       make(chan struct{}),
+		closeOnce:  &sync.Once{},
+	}
+	go mc.sweepLoop()
+	return mc
+}
+
+// sweepLoop runs sweep every memorySweepInterval until Close.
+func (mc *MemoryCache) sweepLoop() {
+	ticker := time.NewTicker(memorySweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-mc.done:
+			return
+		case <-ticker.C:
+			if removed := mc.sweep(); removed > 0 {
+				log.Printf("Cache cleanup: removed %d expired entries", removed)
+			}
+		}
+	}
+}
+
+// sweep drops expired results, crawl marks and refresh locks, which are
+// otherwise only pruned when their URL is looked up again, and returns how
+// many were removed.
+func (mc *MemoryCache) sweep() int {
+	now := time.Now()
+	removed := 0
+
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	for key, entry := range mc.entries {
+		if now.After(entry.expiresAt) {
+			delete(mc.entries, key)
+			removed++
+		}
+	}
+	for key, mark := range mc.lastCrawl {
+		if now.After(mark.expiresAt) {
+			delete(mc.lastCrawl, key)
+			removed++
+		}
+	}
+	for key, expiresAt := range mc.refreshing {
+		if now.After(expiresAt) {
+			delete(mc.refreshing, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+func (mc *MemoryCache) Get(rawURL string) (*CachedResult, bool) {
+	key := generateKey(mc.config.CacheKeyPrefix, mc.prefix, rawURL)
+
+	mc.mu.RLock()
+	entry, ok := mc.entries[key]
+	mc.mu.RUnlock()
+
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		mc.mu.Lock()
+		// A Set may have replaced the entry since the read lock was released
+		if current, ok := mc.entries[key]; ok && time.Now().After(current.expiresAt) {
+			delete(mc.entries, key)
+		}
+		mc.mu.Unlock()
+		return nil, false
+	}
+
+	result := entry.result
+	return &result, true
+}
+
+func (mc *MemoryCache) Set(rawURL string, result CachedResult) error {
+	result = result.prepare(mc.config)
+
+	mc.mu.Lock()
+	mc.entries[generateKey(mc.config.CacheKeyPrefix, mc.prefix, rawURL)] = memoryEntry{
+		result:    result,
+		expiresAt: time.Now().Add(entryTTL(mc.config)),
+		prefix:    mc.prefix,
+		domain:    registrableDomain(rawURL),
+	}
+	mc.mu.Unlock()
+
+	log.Printf("Cached result for %s with %d emails", rawURL, len(result.Emails))
+	return nil
+}
+
+func (mc *MemoryCache) InvalidateURL(rawURL string) error {
+	mc.mu.Lock()
+	delete(mc.entries, generateKey(mc.config.CacheKeyPrefix, mc.prefix, rawURL))
+	mc.mu.Unlock()
+	return nil
+}
+
+func (mc *MemoryCache) InvalidateDomain(domain string) (int, error) {
+	domain = registrableDomain(domain)
+	removed := 0
+
+	mc.mu.Lock()
+	for key, entry := range mc.entries {
+		if entry.prefix == mc.prefix && entry.domain == domain {
+			delete(mc.entries, key)
+			removed++
+		}
+	}
+	mc.mu.Unlock()
+	return removed, nil
+}
+
+func (mc *MemoryCache) ClearAll() error {
+	mc.mu.Lock()
+	for key := range mc.entries {
+		if strings.HasPrefix(key, mc.config.CacheKeyPrefix) {
+			delete(mc.entries, key)
+		}
+	}
+	mc.mu.Unlock()
+	return nil
+}
+
+func (mc *MemoryCache) Stats() map[string]interface{} {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+
+	now := time.Now()
+	cached := 0
+	for _, entry := range mc.entries {
+		if now.Before(entry.expiresAt) {
+			cached++
+		}
+	}
+
+	return map[string]interface{}{
+		"enabled":     true,
+		"backend":     "memory",
+		"cached_urls": cached,
+	}
+}
+
+func (mc *MemoryCache) ResetStats() error {
+	return nil
+}
+
+func (mc *MemoryCache) MarkCrawled(rawURL string, interval time.Duration) error {
+	if interval <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	mc.mu.Lock()
+	mc.lastCrawl[prefixedHash(mc.prefix, rawURL)] = memoryCrawlMark{at: now, expiresAt: now.Add(interval)}
+	mc.mu.Unlock()
+	return nil
+}
+
+func (mc *MemoryCache) LastCrawled(rawURL string) (time.Time, bool) {
+	key := prefixedHash(mc.prefix, rawURL)
+
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mark, ok := mc.lastCrawl[key]
+	if !ok {
+		return time.Time{}, false
+	}
+	if time.Now().After(mark.expiresAt) {
+		delete(mc.lastCrawl, key)
+		return time.Time{}, false
+	}
+	return mark.at, true
+}
+
+func (mc *MemoryCache) LockRefresh(rawURL string, ttl time.Duration) bool {
+	key := prefixedHash(mc.prefix, rawURL)
+	now := time.Now()
+
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	if expiresAt, ok := mc.refreshing[key]; ok && now.Before(expiresAt) {
+		return false
+	}
+	mc.refreshing[key] = now.Add(ttl)
+	return true
+}
+
+func (mc *MemoryCache) UnlockRefresh(rawURL string) error {
+	mc.mu.Lock()
+	delete(mc.refreshing, prefixedHash(mc.prefix, rawURL))
+	mc.mu.Unlock()
+	return nil
+}
+
+func (mc *MemoryCache) DeduplicateEmails(emails []string) []string {
+	return deduplicateEmails(mc.config, emails)
+}
+
+// WithPrefix returns a view of the cache whose entries are isolated under
+// prefix. Views share storage, so ClearAll and Stats cover every prefix.
+func (mc *MemoryCache) WithPrefix(prefix string) Cache {
+	view := *mc
+	view.prefix = prefix
+	return &view
+}
+
+func (mc *MemoryCache) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Close stops the sweep loop shared by mc and its views.
+func (mc *MemoryCache) Close() error {
+	mc.closeOnce.Do(func() { close(mc.done) })
+	return nil
+}
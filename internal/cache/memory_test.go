@@ -0,0 +1,38 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"email-crawler/internal/config"
+)
+
+func TestMemoryCacheSweepDropsExpiredEntries(t *testing.T) {
+	cfg := config.Load()
+	cfg.CacheExpirationTime = time.Millisecond
+	cfg.CacheStaleWhileRevalidate = 0
+	mc := NewMemoryCache(cfg)
+	defer mc.Close()
+
+	// Written and never looked up again
+	mc.Set("https://old.example.com", CachedResult{})
+	mc.MarkCrawled("https://old.example.com", time.Millisecond)
+	mc.LockRefresh("https://old.example.com", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	cfg.CacheExpirationTime = time.Hour
+	mc.Set("https://new.example.com", CachedResult{})
+	mc.MarkCrawled("https://new.example.com", time.Hour)
+	mc.LockRefresh("https://new.example.com", time.Hour)
+
+	if removed := mc.sweep(); removed != 3 {
+		t.Errorf("sweep removed %d entries, want 3", removed)
+	}
+	if len(mc.entries) != 1 || len(mc.lastCrawl) != 1 || len(mc.refreshing) != 1 {
+		t.Errorf("after sweep: %d results, %d crawl marks, %d refresh locks; want only the new URL's",
+			len(mc.entries), len(mc.lastCrawl), len(mc.refreshing))
+	}
+	if _, found := mc.Get("https://new.example.com"); !found {
+		t.Error("sweep removed a result that has not expired")
+	}
+}
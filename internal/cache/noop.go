@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"email-crawler/internal/config"
+)
+
+// NoopCache never stores anything; every lookup is a miss.
+type NoopCache struct {
+	config *config.Config
+}
+
+func NewNoopCache(cfg *config.Config) *NoopCache {
+	return &NoopCache{config: cfg}
+}
+
+func (nc *NoopCache) Get(rawURL string) (*CachedResult, bool) {
+	return nil, false
+}
+
+func (nc *NoopCache) Set(rawURL string, result CachedResult) error {
+	return nil
+}
+
+func (nc *NoopCache) InvalidateURL(rawURL string) error {
+	return nil
+}
+
+func (nc *NoopCache) InvalidateDomain(domain string) (int, error) {
+	return 0, nil
+}
+
+func (nc *NoopCache) ClearAll() error {
+	return nil
+}
+
+func (nc *NoopCache) Stats() map[string]interface{} {
+	return map[string]interface{}{
+		"enabled": false,
+		"backend": "noop",
+	}
+}
+
+func (nc *NoopCache) ResetStats() error {
+	return nil
+}
+
+func (nc *NoopCache) MarkCrawled(rawURL string, interval time.Duration) error {
+	return nil
+}
+
+func (nc *NoopCache) LastCrawled(rawURL string) (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (nc *NoopCache) LockRefresh(rawURL string, ttl time.Duration) bool {
+	return false
+}
+
+func (nc *NoopCache) UnlockRefresh(rawURL string) error {
+	return nil
+}
+
+func (nc *NoopCache) DeduplicateEmails(emails []string) []string {
+	return deduplicateEmails(nc.config, emails)
+}
+
+func (nc *NoopCache) WithPrefix(prefix string) Cache {
+	return nc
+}
+
+func (nc *NoopCache) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (nc *NoopCache) Close() error {
+	return nil
+}
@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+)
+
+// ringReplicas is the number of virtual nodes per shard, which keeps keys evenly
+// spread and limits how many move when a shard is added or removed.
+const ringReplicas = 100
+
+// hashRing maps keys onto shards using consistent hashing.
+type hashRing struct {
+	points []uint32
+	owners map[uint32]int
+}
+
+func newHashRing(nodes []string) *hashRing {
+	ring := &hashRing{
+		owners: make(map[uint32]int, len(nodes)*ringReplicas),
+	}
+
+	for i, node := range nodes {
+		for replica := 0; replica < ringReplicas; replica++ {
+			point := crc32.ChecksumIEEE([]byte(node + "#" + strconv.Itoa(replica)))
+			if _, taken := ring.owners[point]; taken {
+				continue
+			}
+			ring.owners[point] = i
+			ring.points = append(ring.points, point)
+		}
+	}
+
+	sort.Slice(ring.points, func(a, b int) bool { return ring.points[a] < ring.points[b] })
+	return ring
+}
+
+// locate returns the index of the shard that owns key.
+func (r *hashRing) locate(key string) int {
+	if len(r.points) == 0 {
+		return 0
+	}
+
+	hash := crc32.ChecksumIEEE([]byte(key))
+	idx := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= hash })
+	if idx == len(r.points) {
+		idx = 0
+	}
+	return r.owners[r.points[idx]]
+}
@@ -0,0 +1,39 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestHashRingSpreadsKeysAndKeepsMostOnResize(t *testing.T) {
+	nodes := []string{"redis-a:6379", "redis-b:6379", "redis-c:6379"}
+	ring := newHashRing(nodes)
+
+	const total = 3000
+	owners := make([]int, total)
+	counts := make([]int, len(nodes))
+	for i := range owners {
+		owners[i] = ring.locate(fmt.Sprintf("crawler:emails:%d", i))
+		counts[owners[i]]++
+	}
+	for i, count := range counts {
+		if count < total/len(nodes)/2 {
+			t.Errorf("shard %s owns %d of %d keys, want about a third", nodes[i], count, total)
+		}
+	}
+
+	// Adding a shard only moves keys onto the new shard
+	grown := newHashRing(append(nodes, "redis-d:6379"))
+	moved := 0
+	for i, owner := range owners {
+		if now := grown.locate(fmt.Sprintf("crawler:emails:%d", i)); now != owner {
+			if now != len(nodes) {
+				t.Fatalf("key %d moved from shard %d to existing shard %d", i, owner, now)
+			}
+			moved++
+		}
+	}
+	if moved > total/2 {
+		t.Errorf("%d of %d keys moved when adding a fourth shard, want about a quarter", moved, total)
+	}
+}
@@ -3,25 +3,79 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type Config struct {
 	// Crawler settings
-	MaxDepth           int  `json:"max_depth"`
-	DeduplicateEmails  bool `json:"deduplicate_emails"`
+	MaxDepth                 int           `json:"max_depth"`
+	CrawlerMaxDepthLimit     int           `json:"crawler_max_depth_limit"`
+	CrawlerMaxPages          int           `json:"crawler_max_pages"`
+	CrawlerMaxEmails         int           `json:"crawler_max_emails"`
+	DeduplicateEmails        bool          `json:"deduplicate_emails"`
+	CrawlerJoinSplitEmails   bool          `json:"crawler_join_split_emails"`
+	CrawlerExtractObfuscated bool          `json:"crawler_extract_obfuscated"`
+	CrawlerAutoLanguage      bool          `json:"crawler_auto_language"`
+	CrawlerDetectSoft404     bool          `json:"crawler_detect_soft_404"`
+	CrawlerEmailBlocklist    []string      `json:"crawler_email_blocklist"`
+	CrawlerValidateMX        bool          `json:"crawler_validate_mx"`
+	CrawlerMaxLinksPerPage   int           `json:"crawler_max_links_per_page"`
+	CrawlerMaxAlternates     int           `json:"crawler_max_alternates"`
+	CrawlerMaxPagination     int           `json:"crawler_max_pagination"`
+	CrawlerStripQueryParams  []string      `json:"crawler_strip_query_params"`
+	CrawlerIncludeSubdomains bool          `json:"crawler_include_subdomains"`
+	CrawlerAllowedDomains    []string      `json:"crawler_allowed_domains"`
+	CrawlerExcludePatterns   []string      `json:"crawler_exclude_patterns"`
+	CrawlerContactKeywords   []string      `json:"crawler_contact_keywords"`
+	CrawlerReplaceKeywords   bool          `json:"crawler_replace_contact_keywords"`
+	CrawlerGlobalHostRPS     float64       `json:"crawler_global_host_rps"`
+	CrawlerHTTPTimeout       time.Duration `json:"crawler_http_timeout"`
+	CrawlerUserAgent         string        `json:"crawler_user_agent"`
+	CrawlerMaxRedirects      int           `json:"crawler_max_redirects"`
+	CrawlerProxyURL          string        `json:"-"`
+	CrawlerConcurrency       int           `json:"crawler_concurrency"`
+	CrawlerStrategy          string        `json:"crawler_strategy"`
+	CrawlerLogLevel          string        `json:"crawler_log_level"`
+	CrawlerRequestsPerSecond float64       `json:"crawler_requests_per_second"`
+	CrawlerMaxBodyBytes      int64         `json:"crawler_max_body_bytes"`
 
 	// Cache settings
-	CacheEnabled        bool          `json:"cache_enabled"`
-	CacheExpirationTime time.Duration `json:"cache_expiration_time"`
+	CacheEnabled              bool          `json:"cache_enabled"`
+	CacheExpirationTime       time.Duration `json:"cache_expiration_time"`
+	CacheBackend              string        `json:"cache_backend"`
+	CacheRedisShards          []string      `json:"cache_redis_shards"`
+	CacheKeyPrefix            string        `json:"cache_key_prefix"`
+	CacheCompression          bool          `json:"cache_compression"`
+	CacheStaleWhileRevalidate time.Duration `json:"cache_stale_while_revalidate"`
+	CacheWarmMaxURLs          int           `json:"cache_warm_max_urls"`
 
 	// Async processing settings
 	AsyncEnabled         bool          `json:"async_enabled"`
+	AsyncDisabledMessage string        `json:"async_disabled_message"`
 	AsyncWorkers         int           `json:"async_workers"`
+	AsyncMaxWorkers      int           `json:"async_max_workers"`
 	AsyncQueueSize       int           `json:"async_queue_size"`
 	AsyncJobTimeout      time.Duration `json:"async_job_timeout"`
 	AsyncWebhookTimeout  time.Duration `json:"async_webhook_timeout"`
 	AsyncWebhookRetries  int           `json:"async_webhook_retries"`
+	AsyncMaxRetries      int           `json:"async_max_retries"`
+	AsyncRetryDelay      time.Duration `json:"async_retry_delay"`
+	AsyncDeadLetterSize  int           `json:"async_dead_letter_size"`
+	AsyncJobTTL          time.Duration `json:"async_job_ttl"`
+	AsyncCleanupInterval time.Duration `json:"async_cleanup_interval"`
+	JobStoreBackend      string        `json:"job_store_backend"`
+	JobKeyPrefix         string        `json:"job_key_prefix"`
+	AsyncResumableCrawls bool          `json:"async_resumable_crawls"`
+	AsyncDeduplicateJobs bool          `json:"async_deduplicate_jobs"`
+	AsyncDrainTimeout    time.Duration `json:"async_drain_timeout"`
+	AsyncLeaseTTL        time.Duration `json:"async_lease_ttl"`
+	AsyncReaperInterval  time.Duration `json:"async_reaper_interval"`
+
+	// Webhook settings
+	WebhookCompress         bool   `json:"webhook_compress"`
+	WebhookCompressMinBytes int    `json:"webhook_compress_min_bytes"`
+	WebhookSigningSecret    string `json:"-"`
 
 	// Redis settings
 	RedisHost        string `json:"redis_host"`
@@ -38,25 +92,120 @@ type Config struct {
 	// Server settings
 	ServerPort string `json:"server_port"`
 	ServerHost string `json:"server_host"`
+
+	// ServerShutdownTimeout bounds how long SIGTERM waits for in-flight requests
+	ServerShutdownTimeout time.Duration `json:"server_shutdown_timeout"`
+
+	// Batch scans (POST /scan/batch)
+	MaxBatchSize     int `json:"max_batch_size"`
+	BatchConcurrency int `json:"batch_concurrency"`
+
+	// Security settings
+	AllowPrivateTargets bool     `json:"allow_private_targets"`
+	AdminAPIKey         string   `json:"-"`
+	CORSAllowedOrigins  []string `json:"cors_allowed_origins"`
+
+	// Email quality tagging
+	EmailCheckMX           bool     `json:"email_check_mx"`
+	EmailDisposableDomains []string `json:"email_disposable_domains"`
+
+	// Metrics
+	MetricsEnabled           bool `json:"metrics_enabled"`
+	MetricsPerDomain         bool `json:"metrics_per_domain"`
+	MetricsDomainCardinality int  `json:"metrics_domain_cardinality"`
+
+	// Tenants maps a tenant ID to its overrides (TENANTS_CONFIG_FILE)
+	Tenants map[string]*TenantConfig `json:"-"`
+
+	// Abuse protection
+	ScanMinInterval time.Duration `json:"scan_min_interval"`
+}
+
+// defaultEmailBlocklist holds placeholder and tracking domains that are never
+// real contacts.
+var defaultEmailBlocklist = []string{
+	"example.com", "example.org", "example.net", "domain.com", "email.com",
+	"yourdomain.com", "youremail.com", "sentry.io", "wixpress.com",
+}
+
+// defaultStripQueryParams are tracking parameters that never change a page's
+// content.
+var defaultStripQueryParams = []string{
+	"utm_*", "gclid", "fbclid", "msclkid", "mc_cid", "mc_eid", "_ga", "_hsenc", "_hsmi",
 }
 
 func Load() *Config {
 	return &Config{
 		// Crawler settings
-		MaxDepth:          getEnvAsInt("CRAWLER_MAX_DEPTH", 3),
-		DeduplicateEmails: getEnvAsBool("CRAWLER_DEDUPLICATE_EMAILS", true),
+		MaxDepth:                 getEnvAsInt("CRAWLER_MAX_DEPTH", 3),
+		CrawlerMaxDepthLimit:     getEnvAsInt("CRAWLER_MAX_DEPTH_LIMIT", 5),
+		CrawlerMaxPages:          getEnvAsInt("CRAWLER_MAX_PAGES", 200),
+		CrawlerMaxEmails:         getEnvAsInt("CRAWLER_MAX_EMAILS", 0),
+		DeduplicateEmails:        getEnvAsBool("CRAWLER_DEDUPLICATE_EMAILS", true),
+		CrawlerJoinSplitEmails:   getEnvAsBool("CRAWLER_JOIN_SPLIT_EMAILS", false),
+		CrawlerExtractObfuscated: getEnvAsBool("CRAWLER_EXTRACT_OBFUSCATED", false),
+		CrawlerAutoLanguage:      getEnvAsBool("CRAWLER_AUTO_LANGUAGE", false),
+		CrawlerEmailBlocklist:    getEnvAsList("CRAWLER_EMAIL_BLOCKLIST", defaultEmailBlocklist),
+		CrawlerValidateMX:        getEnvAsBool("CRAWLER_VALIDATE_MX", false),
+		CrawlerDetectSoft404:     getEnvAsBool("CRAWLER_DETECT_SOFT_404", false),
+		CrawlerMaxLinksPerPage:   getEnvAsInt("CRAWLER_MAX_LINKS_PER_PAGE", 0),
+		CrawlerMaxAlternates:     getEnvAsInt("CRAWLER_MAX_ALTERNATES", 0),
+		CrawlerMaxPagination:     getEnvAsInt("CRAWLER_MAX_PAGINATION", 10),
+		CrawlerStripQueryParams:  getEnvAsList("CRAWLER_STRIP_QUERY_PARAMS", defaultStripQueryParams),
+		CrawlerIncludeSubdomains: getEnvAsBool("CRAWLER_INCLUDE_SUBDOMAINS", false),
+		CrawlerAllowedDomains:    getEnvAsList("CRAWLER_ALLOWED_DOMAINS", nil),
+		CrawlerExcludePatterns:   getEnvAsList("CRAWLER_EXCLUDE_PATTERNS", nil),
+		CrawlerContactKeywords:   getEnvAsList("CRAWLER_CONTACT_KEYWORDS", nil),
+		CrawlerReplaceKeywords:   getEnvAsBool("CRAWLER_CONTACT_KEYWORDS_REPLACE", false),
+		CrawlerGlobalHostRPS:     getEnvAsFloat("CRAWLER_GLOBAL_HOST_RPS", 0),
+		CrawlerHTTPTimeout:       time.Duration(getEnvAsInt("CRAWLER_HTTP_TIMEOUT_SECONDS", 15)) * time.Second,
+		CrawlerUserAgent:         getEnv("CRAWLER_USER_AGENT", "gurl-email-crawler/1.0"),
+		CrawlerMaxRedirects:      getEnvAsInt("CRAWLER_MAX_REDIRECTS", 5),
+		CrawlerProxyURL:          getEnv("CRAWLER_PROXY_URL", ""),
+		CrawlerConcurrency:       getEnvAsInt("CRAWLER_CONCURRENCY", 5),
+		CrawlerStrategy:          getEnv("CRAWLER_STRATEGY", "dfs"),
+		CrawlerLogLevel:          getEnv("CRAWLER_LOG_LEVEL", "info"),
+		CrawlerRequestsPerSecond: getEnvAsFloat("CRAWLER_REQUESTS_PER_SECOND", 2),
+		CrawlerMaxBodyBytes:      int64(getEnvAsInt("CRAWLER_MAX_BODY_BYTES", 5*1024*1024)),
 
 		// Cache settings
-		CacheEnabled:        getEnvAsBool("CACHE_ENABLED", true),
-		CacheExpirationTime: time.Duration(getEnvAsInt("CACHE_EXPIRATION_MONTHS", 12)) * 24 * 30 * time.Hour,
+		CacheEnabled:              getEnvAsBool("CACHE_ENABLED", true),
+		CacheExpirationTime:       time.Duration(getEnvAsInt("CACHE_EXPIRATION_MONTHS", 12)) * 24 * 30 * time.Hour,
+		CacheBackend:              getEnv("CACHE_BACKEND", "redis"), // redis, memory or noop
+		CacheRedisShards:          getEnvAsList("CACHE_REDIS_SHARDS", nil),
+		CacheKeyPrefix:            getEnv("CACHE_KEY_PREFIX", "crawler:emails:"),
+		CacheCompression:          getEnvAsBool("CACHE_COMPRESSION", false),
+		CacheStaleWhileRevalidate: time.Duration(getEnvAsInt("CACHE_STALE_WHILE_REVALIDATE_SECONDS", 0)) * time.Second,
+		CacheWarmMaxURLs:          getEnvAsInt("CACHE_WARM_MAX_URLS", 500),
 
 		// Async processing settings
-		AsyncEnabled:        getEnvAsBool("ASYNC_ENABLED", true),
-		AsyncWorkers:        getEnvAsInt("ASYNC_WORKERS", 3),
-		AsyncQueueSize:      getEnvAsInt("ASYNC_QUEUE_SIZE", 100),
-		AsyncJobTimeout:     time.Duration(getEnvAsInt("ASYNC_JOB_TIMEOUT_SECONDS", 300)) * time.Second,
-		AsyncWebhookTimeout: time.Duration(getEnvAsInt("ASYNC_WEBHOOK_TIMEOUT_SECONDS", 10)) * time.Second,
-		AsyncWebhookRetries: getEnvAsInt("ASYNC_WEBHOOK_RETRIES", 3),
+		AsyncEnabled:         getEnvAsBool("ASYNC_ENABLED", true),
+		AsyncDisabledMessage: getEnv("ASYNC_DISABLED_MESSAGE", "Async scanning is disabled"),
+		AsyncWorkers:         getEnvAsInt("ASYNC_WORKERS", 3),
+		AsyncMaxWorkers:      getEnvAsInt("ASYNC_MAX_WORKERS", 20),
+		AsyncQueueSize:       getEnvAsInt("ASYNC_QUEUE_SIZE", 100),
+		AsyncJobTimeout:      time.Duration(getEnvAsInt("ASYNC_JOB_TIMEOUT_SECONDS", 300)) * time.Second,
+		AsyncWebhookTimeout:  time.Duration(getEnvAsInt("ASYNC_WEBHOOK_TIMEOUT_SECONDS", 10)) * time.Second,
+		AsyncWebhookRetries:  getEnvAsInt("ASYNC_WEBHOOK_RETRIES", 3),
+		AsyncMaxRetries:      getEnvAsInt("ASYNC_MAX_RETRIES", 2),
+		AsyncRetryDelay:      time.Duration(getEnvAsInt("ASYNC_RETRY_DELAY_SECONDS", 30)) * time.Second,
+		AsyncDeadLetterSize:  getEnvAsInt("ASYNC_DEAD_LETTER_SIZE", 1000),
+		AsyncJobTTL:          time.Duration(getEnvAsInt("ASYNC_JOB_TTL_HOURS", 24)) * time.Hour,
+		// Interval for pruning active-set/queue entries whose job key has expired (0 disables)
+		AsyncCleanupInterval: time.Duration(getEnvAsInt("ASYNC_CLEANUP_INTERVAL_SECONDS", 300)) * time.Second,
+		JobStoreBackend:      getEnv("JOB_STORE_BACKEND", "redis"), // redis or memory
+		JobKeyPrefix:         getEnv("JOB_KEY_PREFIX", "crawler:"),
+		AsyncResumableCrawls: getEnvAsBool("ASYNC_RESUMABLE_CRAWLS", false),
+		AsyncDeduplicateJobs: getEnvAsBool("ASYNC_DEDUPLICATE_JOBS", false),
+		AsyncDrainTimeout:    time.Duration(getEnvAsInt("ASYNC_DRAIN_TIMEOUT_SECONDS", 60)) * time.Second,
+		// Workers renew a lease on their job; jobs whose lease lapses are reaped
+		AsyncLeaseTTL:       time.Duration(getEnvAsInt("ASYNC_LEASE_SECONDS", 30)) * time.Second,
+		AsyncReaperInterval: time.Duration(getEnvAsInt("ASYNC_REAPER_INTERVAL_SECONDS", 30)) * time.Second,
+
+		// Webhook settings
+		WebhookCompress:         getEnvAsBool("WEBHOOK_COMPRESS", false),
+		WebhookCompressMinBytes: getEnvAsInt("WEBHOOK_COMPRESS_MIN_BYTES", 1024),
+		WebhookSigningSecret:    getEnv("WEBHOOK_SIGNING_SECRET", ""),
 
 		// Redis settings
 		RedisHost:        getEnv("REDIS_HOST", "localhost"),
@@ -73,6 +222,32 @@ func Load() *Config {
 		// Server settings
 		ServerPort: getEnv("SERVER_PORT", "8080"),
 		ServerHost: getEnv("SERVER_HOST", "0.0.0.0"),
+
+		ServerShutdownTimeout: time.Duration(getEnvAsInt("SERVER_SHUTDOWN_TIMEOUT_SECONDS", 30)) * time.Second,
+
+		// Batch scans
+		MaxBatchSize:     getEnvAsInt("MAX_BATCH_SIZE", 20),
+		BatchConcurrency: getEnvAsInt("BATCH_CONCURRENCY", 4),
+
+		// Security settings
+		AllowPrivateTargets: getEnvAsBool("ALLOW_PRIVATE_TARGETS", false),
+		AdminAPIKey:         getEnv("ADMIN_API_KEY", ""),
+		CORSAllowedOrigins:  getEnvAsList("CORS_ALLOWED_ORIGINS", []string{"*"}),
+
+		// Email quality tagging
+		EmailCheckMX:           getEnvAsBool("EMAIL_CHECK_MX", true),
+		EmailDisposableDomains: getEnvAsList("EMAIL_DISPOSABLE_DOMAINS", nil),
+
+		// Metrics
+		MetricsEnabled:           getEnvAsBool("METRICS_ENABLED", false),
+		MetricsPerDomain:         getEnvAsBool("METRICS_PER_DOMAIN", false),
+		MetricsDomainCardinality: getEnvAsInt("METRICS_DOMAIN_CARDINALITY", 100),
+
+		// Tenant overrides
+		Tenants: loadTenants(getEnv("TENANTS_CONFIG_FILE", "")),
+
+		// Abuse protection
+		ScanMinInterval: time.Duration(getEnvAsInt("SCAN_MIN_INTERVAL_SECONDS", 0)) * time.Second,
 	}
 }
 
@@ -97,6 +272,15 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
 func getEnvAsBool(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
 		if boolValue, err := strconv.ParseBool(value); err == nil {
@@ -104,4 +288,20 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 		}
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+// getEnvAsList parses a comma-separated variable, dropping empty entries.
+func getEnvAsList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
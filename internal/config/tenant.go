@@ -0,0 +1,71 @@
+package config
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"os"
+	"time"
+)
+
+// TenantConfig holds per-tenant overrides. Zero values fall back to the global
+// settings.
+type TenantConfig struct {
+	MaxDepth     *int     `json:"max_depth,omitempty"`
+	MaxPages     int      `json:"max_pages,omitempty"`
+	CrawlTimeout int      `json:"crawl_timeout_seconds,omitempty"`
+	CachePrefix  string   `json:"cache_prefix,omitempty"`
+	APIKeys      []string `json:"api_keys,omitempty"`
+}
+
+// Timeout returns the tenant's crawl timeout, or 0 if it doesn't set one.
+func (t *TenantConfig) Timeout() time.Duration {
+	return time.Duration(t.CrawlTimeout) * time.Second
+}
+
+// loadTenants reads the tenant file, a JSON object keyed by tenant ID. A
+// missing or invalid file is logged and leaves every request on the defaults.
+func loadTenants(path string) map[string]*TenantConfig {
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("Failed to read tenants file %s: %v", path, err)
+		return nil
+	}
+
+	var tenants map[string]*TenantConfig
+	if err := json.Unmarshal(data, &tenants); err != nil {
+		log.Printf("Failed to parse tenants file %s: %v", path, err)
+		return nil
+	}
+
+	log.Printf("Loaded %d tenants from %s", len(tenants), path)
+	return tenants
+}
+
+// Tenant returns the tenant with the given ID.
+func (c *Config) Tenant(id string) (*TenantConfig, bool) {
+	tenant, ok := c.Tenants[id]
+	return tenant, ok && tenant != nil
+}
+
+// TenantByAPIKey returns the tenant that owns key.
+func (c *Config) TenantByAPIKey(key string) (string, *TenantConfig, bool) {
+	if key == "" {
+		return "", nil, false
+	}
+	for id, tenant := range c.Tenants {
+		if tenant == nil {
+			continue
+		}
+		for _, tenantKey := range tenant.APIKeys {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(tenantKey)) == 1 {
+				return id, tenant, true
+			}
+		}
+	}
+	return "", nil, false
+}
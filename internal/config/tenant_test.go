@@ -0,0 +1,40 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadTenants(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tenants.json")
+	data := `{
+		"team-a": {"max_depth": 0, "crawl_timeout_seconds": 5, "cache_prefix": "a", "api_keys": ["key-a"]},
+		"team-b": {"max_pages": 20}
+	}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{Tenants: loadTenants(path)}
+
+	teamA, ok := cfg.Tenant("team-a")
+	if !ok || teamA.MaxDepth == nil || *teamA.MaxDepth != 0 || teamA.Timeout() != 5*time.Second || teamA.CachePrefix != "a" {
+		t.Errorf("team-a = %+v, want depth 0, a 5s timeout and cache prefix a", teamA)
+	}
+	if teamB, ok := cfg.Tenant("team-b"); !ok || teamB.MaxDepth != nil || teamB.MaxPages != 20 {
+		t.Errorf("team-b = %+v, want 20 pages and the default depth", teamB)
+	}
+	if id, _, ok := cfg.TenantByAPIKey("key-a"); !ok || id != "team-a" {
+		t.Errorf("TenantByAPIKey(key-a) = %q, %v; want team-a", id, ok)
+	}
+	if _, _, ok := cfg.TenantByAPIKey(""); ok {
+		t.Error("an empty API key matched a tenant")
+	}
+
+	for _, path := range []string{"", filepath.Join(t.TempDir(), "missing.json")} {
+		if tenants := loadTenants(path); tenants != nil {
+			t.Errorf("loadTenants(%q) = %v, want no tenants", path, tenants)
+		}
+	}
+}
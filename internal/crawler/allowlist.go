@@ -0,0 +1,30 @@
+package crawler
+
+import (
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// DomainAllowed reports whether host's registrable domain (eTLD+1) is in
+// allowed. Entries are reduced to their registrable domain too, so
+// www.example.com allows all of example.com. An empty list allows every host.
+func DomainAllowed(host string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(host))
+	if err != nil {
+		return false
+	}
+	for _, entry := range allowed {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if registrable, err := publicsuffix.EffectiveTLDPlusOne(entry); err == nil {
+			entry = registrable
+		}
+		if entry == domain {
+			return true
+		}
+	}
+	return false
+}
@@ -1,13 +1,24 @@
 package crawler
 
 import (
-	"log"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
 	"net/http"
 	"net/url"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/publicsuffix"
+
+	"email-crawler/internal/config"
 )
 
 func min(a, b int) int {
@@ -18,131 +29,1051 @@ func min(a, b int) int {
 }
 
 var emailRegex = regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`)
-var contactKeywords = []string{
-	// Español
-	"contact", "contacto", "about", "info", "acerca", "informacion", "información",
-	"equipo", "team", "nosotros", "empresa", "quienes-somos",
-	// Inglés
-	"contact-us", "about-us", "team", "support", "help", "reach", "get-in-touch",
-	"who-we-are", "our-team", "meet-team", "staff", "office", "headquarters",
-	// Francés  
-	"nous-contacter", "au-sujet", "à-propos", "propos", "équipe", "qui-sommes-nous",
-	"notre-équipe", "mentions-legales", "aide", "assistance", "bureau",
-	// Alemán
-	"kontakt", "kontaktiere", "kontaktieren", "über-uns", "über", "ueber",
-	"impressum", "team", "unser-team", "wir", "firma", "unternehmen",
-	"hilfe", "unterstützung", "büro",
-	// Italiano
-	"contatti", "chi-siamo", "su-di-noi", "squadra", "team", "ufficio",
-	"informazioni", "aiuto", "supporto", "sede",
-	// Portugués
-	"contato", "sobre", "sobre-nos", "equipe", "time", "quem-somos",
-	"informacoes", "ajuda", "suporte", "escritorio",
-	// Términos genéricos comunes
-	"staff", "people", "directory", "location", "address", "phone", "email",
-	"reach-us", "get-help", "customer-service", "atendimento", "servicio-cliente",
+
+// paginationRegex matches ?page=N / &p=N query parameters and /page/N path segments.
+var paginationRegex = regexp.MustCompile(`(?i)(?:[?&](?:page|p|pg)=\d+|/page/\d+/?$)`)
+
+// strongContactKeywords mark pages that are almost certainly contact pages,
+// grouped by language. Links matching them are followed without consuming depth.
+var strongContactKeywords = map[string][]string{
+	"es": {"contacto"},
+	"en": {"contact", "contact-us", "get-in-touch", "reach-us"},
+	"fr": {"nous-contacter"},
+	"de": {"kontakt", "impressum"},
+	"it": {"contatti"},
+	"pt": {"contato"},
+}
+
+// contactKeywords are weaker signals (team, about, support pages), grouped by
+// language; "" holds language-neutral terms. Links matching only these cost
+// half a level of depth.
+var contactKeywords = map[string][]string{
+	"es": {
+		"about", "info", "acerca", "informacion", "información",
+		"equipo", "team", "nosotros", "empresa", "quienes-somos",
+	},
+	"en": {
+		"about-us", "team", "support", "help", "reach",
+		"who-we-are", "our-team", "meet-team", "staff", "office", "headquarters",
+	},
+	"fr": {
+		"au-sujet", "à-propos", "propos", "équipe", "qui-sommes-nous",
+		"notre-équipe", "mentions-legales", "aide", "assistance", "bureau",
+	},
+	"de": {
+		"über-uns", "über", "ueber",
+		"team", "unser-team", "wir", "firma", "unternehmen",
+		"hilfe", "unterstützung", "büro",
+	},
+	"it": {
+		"chi-siamo", "su-di-noi", "squadra", "team", "ufficio",
+		"informazioni", "aiuto", "supporto", "sede",
+	},
+	"pt": {
+		"sobre", "sobre-nos", "equipe", "time", "quem-somos",
+		"informacoes", "ajuda", "suporte", "escritorio",
+	},
+	"": {
+		"staff", "people", "directory", "location", "address", "phone", "email",
+		"get-help", "customer-service", "atendimento", "servicio-cliente",
+	},
+}
+
+// Crawl orders for sequential crawls (Options.Strategy).
+const (
+	StrategyDFS = "dfs"
+	StrategyBFS = "bfs"
+)
+
+// Options controls how a Crawler fetches and traverses a site.
+type Options struct {
+	MaxDepth int
+
+	// MaxPages stops the crawl after this many pages have been visited. 0 means
+	// no limit.
+	MaxPages int
+
+	// MaxEmails stops the crawl once this many emails have been found, and
+	// Truncated then reports true. New emails on the same page past the cap
+	// are dropped. 0 means no limit.
+	MaxEmails int
+
+	// IfModifiedSince, when set, sends conditional requests and skips email
+	// extraction on pages the server reports as unchanged since that time.
+	IfModifiedSince time.Time
+
+	// JoinSplitEmails rejoins addresses split across elements by whitespace.
+	JoinSplitEmails bool
+
+	// ExtractObfuscatedEmails reconstructs addresses split across data-*
+	// attributes or assembled by string concatenation in inline scripts.
+	ExtractObfuscatedEmails bool
+
+	// AutoLanguage detects the site's language from the first page's <html lang>
+	// or Content-Language and only matches that language's keywords plus English.
+	AutoLanguage bool
+
+	// EmailBlocklist drops placeholder addresses. Entries containing "@" match
+	// a full address; others match a domain and its subdomains.
+	EmailBlocklist []string
+
+	// DetectSoft404 skips pages that return 200 but are really "not found" pages.
+	DetectSoft404 bool
+
+	// ContactKeywords are extra language-neutral keywords marking contact
+	// pages, matched like strongContactKeywords. With ReplaceContactKeywords
+	// they are the only keywords used.
+	ContactKeywords        []string
+	ReplaceContactKeywords bool
+
+	// IncludeSubdomains follows links to any host under the start URL's
+	// registrable domain (shop.example.com from www.example.com).
+	IncludeSubdomains bool
+
+	// AllowedDomains, when set, limits the crawl to hosts whose registrable
+	// domain is listed; links and redirects elsewhere are never followed. See
+	// DomainAllowed.
+	AllowedDomains []string
+
+	// ExcludePatterns skips links whose path matches any of them. See
+	// CompileExcludePatterns.
+	ExcludePatterns []*regexp.Regexp
+
+	// StripQueryParams lists query parameters removed from URLs before they
+	// are deduplicated; a trailing "*" matches any suffix.
+	StripQueryParams []string
+
+	// MaxPagination is how many pagination links (rel="next", ?page=N, /page/N)
+	// may be followed without consuming depth. 0 treats them as regular links.
+	MaxPagination int
+
+	// MaxLinksPerPage caps how many links are followed from a single page,
+	// preferring contact links. 0 follows every link.
+	MaxLinksPerPage int
+
+	// MaxAlternates is how many <link rel="alternate" hreflang> and AMP
+	// variants may be followed per crawl. 0 ignores them.
+	MaxAlternates int
+
+	// RawHTMLLimit, when positive, keeps up to that many bytes of the raw HTML
+	// of each contact-keyword page for debugging. See RawPages.
+	RawHTMLLimit int
+
+	// HostLimiter, when set, is consulted before every fetch so crawls sharing
+	// it never exceed its per-host rate combined.
+	HostLimiter *HostLimiter
+
+	// MaxBodyBytes bounds how much of each response is read and parsed. 0
+	// reads the whole body.
+	MaxBodyBytes int64
+
+	// RequestsPerSecond caps this crawl's request rate to each host, on top of
+	// any shared HostLimiter. 0 disables it.
+	RequestsPerSecond float64
+
+	// HTTPTimeout bounds each fetch, including every redirect it follows and
+	// reading the body. 0 means no timeout.
+	HTTPTimeout time.Duration
+
+	// Proxy routes every fetch, redirects included, through an http, https or
+	// socks5 proxy. nil uses the proxy environment variables, but only with
+	// AllowPrivateTargets; otherwise fetches connect directly. An unreachable
+	// proxy fails each fetch like any other network error.
+	Proxy *url.URL
+
+	// AllowPrivateTargets lets fetches reach loopback, private and link-local
+	// addresses. Otherwise every connection is refused once DNS resolves to
+	// one, redirects included; through a proxy, each fetch and redirect
+	// target is resolved and checked before it is requested.
+	AllowPrivateTargets bool
+
+	// MaxRedirects is how many redirects a single fetch may follow. 0 keeps
+	// the HTTP client's default of 10.
+	MaxRedirects int
+
+	// UserAgent is sent with every fetch. Empty keeps Go's default.
+	UserAgent string
+
+	// Logger receives the crawl's structured logs. nil uses slog.Default().
+	Logger *slog.Logger
+
+	// Concurrency is how many pages are fetched at once. Below 2, pages are
+	// crawled one at a time in the order set by Strategy.
+	Concurrency int
+
+	// Strategy is StrategyDFS (default) or StrategyBFS for sequential crawls.
+	// Concurrent crawls always go shallowest first.
+	Strategy string
+
+	// Resume continues a previously checkpointed crawl instead of starting
+	// from the start URL.
+	Resume *Frontier
+
+	// Checkpoint, when set, receives the crawl frontier after every page so an
+	// interrupted crawl can later be resumed.
+	Checkpoint func(*Frontier)
+
+	// OnPage and OnEmail, when set, report progress as the crawl runs: each
+	// fetched HTML page with its depth, and each email the first time it is
+	// found. They are called one at a time, with the crawler's lock held, so
+	// they should return quickly.
+	OnPage  func(pageURL string, depth int)
+	OnEmail func(email, pageURL string)
+}
+
+// OptionsFromConfig returns the crawler options configured for the service.
+// Per-request settings are applied by the caller on top of these.
+func OptionsFromConfig(cfg *config.Config) Options {
+	return Options{
+		MaxDepth:                cfg.MaxDepth,
+		MaxPages:                cfg.CrawlerMaxPages,
+		MaxEmails:               cfg.CrawlerMaxEmails,
+		JoinSplitEmails:         cfg.CrawlerJoinSplitEmails,
+		ExtractObfuscatedEmails: cfg.CrawlerExtractObfuscated,
+		AutoLanguage:            cfg.CrawlerAutoLanguage,
+		DetectSoft404:           cfg.CrawlerDetectSoft404,
+		EmailBlocklist:          cfg.CrawlerEmailBlocklist,
+		MaxPagination:           cfg.CrawlerMaxPagination,
+		StripQueryParams:        cfg.CrawlerStripQueryParams,
+		IncludeSubdomains:       cfg.CrawlerIncludeSubdomains,
+		AllowedDomains:          cfg.CrawlerAllowedDomains,
+		ExcludePatterns:         SharedExcludePatterns(cfg.CrawlerExcludePatterns),
+		ContactKeywords:         cfg.CrawlerContactKeywords,
+		ReplaceContactKeywords:  cfg.CrawlerReplaceKeywords,
+		MaxLinksPerPage:         cfg.CrawlerMaxLinksPerPage,
+		MaxAlternates:           cfg.CrawlerMaxAlternates,
+		HostLimiter:             SharedHostLimiter(cfg.CrawlerGlobalHostRPS),
+		RequestsPerSecond:       cfg.CrawlerRequestsPerSecond,
+		MaxBodyBytes:            cfg.CrawlerMaxBodyBytes,
+		HTTPTimeout:             cfg.CrawlerHTTPTimeout,
+		UserAgent:               cfg.CrawlerUserAgent,
+		MaxRedirects:            cfg.CrawlerMaxRedirects,
+		Proxy:                   configuredProxy(cfg.CrawlerProxyURL),
+		AllowPrivateTargets:     cfg.AllowPrivateTargets,
+		Concurrency:             cfg.CrawlerConcurrency,
+		Logger:                  SharedLogger(cfg.CrawlerLogLevel),
+		Strategy:                cfg.CrawlerStrategy,
+	}
+}
+
+// OptionsForTenant returns the configured options with a tenant's overrides
+// applied. A nil tenant yields the global defaults.
+func OptionsForTenant(cfg *config.Config, tenant *config.TenantConfig) Options {
+	opts := OptionsFromConfig(cfg)
+	if tenant == nil {
+		return opts
+	}
+	if tenant.MaxDepth != nil {
+		opts.MaxDepth = *tenant.MaxDepth
+	}
+	if tenant.MaxPages > 0 {
+		opts.MaxPages = tenant.MaxPages
+	}
+	return opts
 }
 
 type Crawler struct {
+	ctx      context.Context
+	client   *http.Client
+	limiter  *HostLimiter
+	log      *slog.Logger
+	mu       sync.Mutex // guards the maps and counters below during concurrent crawls
 	maxDepth int
+	opts     Options
 	visited  map[string]bool
+	pending  map[string]int
 	emails   map[string]bool
+	labels   map[string]string
+	original map[string]string
+	sources  map[string][]string
 	baseURL  *url.URL
+	soft404  *soft404Fingerprint
+	rawPages map[string]string
+
+	// baseDomain is the registrable domain (eTLD+1) of baseURL when
+	// Options.IncludeSubdomains is set
+	baseDomain string
+	// redirectHost is where the start URL redirected to, which is crawled as
+	// if it were the start URL's host
+	redirectHost string
+
+	// strongKeywords and weakKeywords are the built-in keyword maps combined
+	// with Options.ContactKeywords
+	strongKeywords map[string][]string
+	weakKeywords   map[string][]string
+
+	// language restricts keyword matching once detected; "" matches all
+	language         string
+	languageDetected bool
+
+	// depthReached is the deepest depth claimed so far, in half levels
+	depthReached int
+
+	// startErr is why the start page could not be crawled; see StartPageError
+	startErr error
+
+	// truncated is set once Options.MaxEmails emails have been found
+	truncated bool
+
+	paginationFollowed int
+	alternatesFollowed int
 }
 
 func New(maxDepth int) *Crawler {
-	return &Crawler{
-		maxDepth: maxDepth,
-		visited:  make(map[string]bool),
-		emails:   make(map[string]bool),
+	return NewWithOptions(Options{MaxDepth: maxDepth})
+}
+
+// NewWithKeywords returns a crawler that recognizes contact pages by keywords
+// alone instead of the built-in lists.
+func NewWithKeywords(maxDepth int, keywords []string) *Crawler {
+	return NewWithOptions(Options{MaxDepth: maxDepth, ContactKeywords: keywords, ReplaceContactKeywords: true})
+}
+
+func NewWithOptions(opts Options) *Crawler {
+	var limiter *HostLimiter
+	if opts.RequestsPerSecond > 0 {
+		limiter = NewHostLimiter(opts.RequestsPerSecond)
 	}
+	strong, weak := strongContactKeywords, contactKeywords
+	if len(opts.ContactKeywords) > 0 {
+		custom := make([]string, 0, len(opts.ContactKeywords))
+		for _, keyword := range opts.ContactKeywords {
+			custom = append(custom, strings.ToLower(keyword))
+		}
+		if opts.ReplaceContactKeywords {
+			strong, weak = map[string][]string{"": custom}, nil
+		} else {
+			strong = make(map[string][]string, len(strongContactKeywords)+1)
+			for lang, words := range strongContactKeywords {
+				strong[lang] = words
+			}
+			strong[""] = append(strong[""], custom...)
+		}
+	}
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	c := &Crawler{
+		limiter:        limiter,
+		log:            logger,
+		strongKeywords: strong,
+		weakKeywords:   weak,
+		maxDepth:       opts.MaxDepth,
+		opts:           opts,
+		visited:        make(map[string]bool),
+		pending:        make(map[string]int),
+		emails:         make(map[string]bool),
+		labels:         make(map[string]string),
+		original:       make(map[string]string),
+		sources:        make(map[string][]string),
+		rawPages:       make(map[string]string),
+	}
+	c.client = &http.Client{
+		Transport:     transportFor(opts.Proxy, opts.AllowPrivateTargets),
+		Timeout:       opts.HTTPTimeout,
+		CheckRedirect: c.checkRedirect,
+	}
+	return c
 }
 
 func (c *Crawler) Crawl(startURL *url.URL) map[string]bool {
+	return c.CrawlWithContext(context.Background(), startURL)
+}
+
+// CrawlWithContext crawls like Crawl but stops fetching new pages once ctx is
+// done, returning the emails found up to that point.
+func (c *Crawler) CrawlWithContext(ctx context.Context, startURL *url.URL) map[string]bool {
+	c.ctx = ctx
+	startURL = c.normalizeURL(startURL)
 	c.baseURL = startURL
-	c.crawlRecursive(startURL, 0)
+	if c.opts.IncludeSubdomains {
+		c.baseDomain, _ = publicsuffix.EffectiveTLDPlusOne(startURL.Hostname())
+	}
+	if c.opts.DetectSoft404 {
+		c.probeSoft404()
+	}
+	if c.opts.Resume != nil {
+		c.resume(c.opts.Resume)
+	} else {
+		c.crawlFrom([]target{{u: startURL, depth: 0}})
+	}
 	return c.emails
 }
 
-func (c *Crawler) crawlRecursive(u *url.URL, depth int) {
-	if depth > c.maxDepth || c.visited[u.String()] || u.Host != c.baseURL.Host {
+// Labels returns the label each email appeared under, keyed by lowercased
+// email. Only emails that were preceded by a short label are included.
+func (c *Crawler) Labels() map[string]string {
+	return c.labels
+}
+
+// PagesVisited returns how many pages the crawl fetched or attempted.
+func (c *Crawler) PagesVisited() int {
+	return len(c.visited)
+}
+
+// MaxDepthReached returns the deepest depth the crawl visited, in the units of
+// Options.MaxDepth and rounded up. Contact links cost less than a full level,
+// so it can be lower than the number of links followed.
+func (c *Crawler) MaxDepthReached() int {
+	return (c.depthReached + depthStep - 1) / depthStep
+}
+
+// Truncated reports whether the crawl stopped at Options.MaxEmails emails, so
+// the site may have more than were returned.
+func (c *Crawler) Truncated() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.truncated
+}
+
+// StartPageError returns why the start page could not be crawled: the fetch
+// error, or a *StatusError for a response other than 200. It is nil when the
+// start page was crawled.
+func (c *Crawler) StartPageError() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.startErr
+}
+
+// StatusError reports an unexpected HTTP status for a fetched page.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d", e.StatusCode)
+}
+
+// failStart records err as the start page's failure if u is the start page.
+func (c *Crawler) failStart(u *url.URL, err error) {
+	if u.String() != c.baseURL.String() {
 		return
 	}
+	c.mu.Lock()
+	c.startErr = err
+	c.mu.Unlock()
+}
+
+// OriginalCase maps each lowercased email to the casing it was first seen with.
+func (c *Crawler) OriginalCase() map[string]string {
+	return c.original
+}
+
+// Sources maps each lowercased email to the pages it was found on, in crawl
+// order.
+func (c *Crawler) Sources() map[string][]string {
+	return c.sources
+}
+
+// RawPages returns the captured raw HTML of contact-keyword pages keyed by URL.
+// It is empty unless Options.RawHTMLLimit is set.
+func (c *Crawler) RawPages() map[string]string {
+	return c.rawPages
+}
+
+// Depth is tracked in half levels so weak contact links can cost less than a
+// full level. depthStep is the cost of a regular link.
+const depthStep = 2
+
+type linkTier int
+
+const (
+	tierNone linkTier = iota
+	tierWeak
+	tierStrong
+)
+
+// depthCost is the depth, in half levels, spent following a link of this tier.
+func (t linkTier) depthCost() int {
+	switch t {
+	case tierStrong:
+		return 0
+	case tierWeak:
+		return 1
+	default:
+		return depthStep
+	}
+}
+
+// target is a URL to crawl at a given depth, in half levels.
+type target struct {
+	u     *url.URL
+	depth int
+	tier  linkTier
+}
+
+// crawlFrom crawls depth-first from targets using an explicit stack, so long
+// link chains grow a slice rather than the call stack. With Options.Concurrency
+// above 1 it hands off to crawlConcurrent, and with StrategyBFS to
+// crawlBreadthFirst.
+func (c *Crawler) crawlFrom(targets []target) {
+	if c.opts.Concurrency > 1 {
+		c.crawlConcurrent(targets)
+		return
+	}
+	if c.opts.Strategy == StrategyBFS {
+		c.crawlBreadthFirst(targets)
+		return
+	}
+
+	stack := make([]target, 0, len(targets))
+	for i := len(targets) - 1; i >= 0; i-- {
+		stack = append(stack, targets[i])
+	}
+
+	for len(stack) > 0 && c.ctx.Err() == nil && !c.limitReached() {
+		t := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		// Push in reverse so links are visited in page order
+		next := c.visit(t.u, t.depth)
+		for i := len(next) - 1; i >= 0; i-- {
+			stack = append(stack, next[i])
+		}
+	}
+}
+
+// crawlBreadthFirst crawls from targets level by level with a FIFO queue, so
+// shallow pages are visited before any deep branch.
+func (c *Crawler) crawlBreadthFirst(targets []target) {
+	queue := append([]target(nil), targets...)
+	for len(queue) > 0 && c.ctx.Err() == nil && !c.limitReached() {
+		t := queue[0]
+		queue = queue[1:]
+		queue = append(queue, c.visit(t.u, t.depth)...)
+		sortFrontier(queue)
+	}
+}
+
+// sortFrontier orders queued targets shallowest first and, within a depth,
+// contact links first. The sort is stable, so ties stay in discovery order.
+func sortFrontier(queue []target) {
+	sort.SliceStable(queue, func(i, j int) bool {
+		if queue[i].depth != queue[j].depth {
+			return queue[i].depth < queue[j].depth
+		}
+		return queue[i].tier > queue[j].tier
+	})
+}
+
+// crawlConcurrent crawls with Options.Concurrency fetches in flight, always
+// handing out the shallowest pending page next (see sortFrontier). It returns once the frontier
+// is empty and every in-flight fetch has finished; after ctx is done no new
+// page is started.
+func (c *Crawler) crawlConcurrent(targets []target) {
+	work := make(chan target)
+	results := make(chan []target)
+	for i := 0; i < c.opts.Concurrency; i++ {
+		go func() {
+			for t := range work {
+				results <- c.visit(t.u, t.depth)
+			}
+		}()
+	}
+	defer close(work)
+
+	queue := append([]target(nil), targets...)
+	inFlight := 0
+	for len(queue) > 0 || inFlight > 0 {
+		if len(queue) > 0 && (c.ctx.Err() != nil || c.limitReached()) {
+			// Drop the frontier and wait out any fetches still in flight
+			queue = nil
+			continue
+		}
+
+		// A nil channel disables the send case while nothing is queued
+		var send chan target
+		var next target
+		if len(queue) > 0 {
+			send, next = work, queue[0]
+		}
+
+		select {
+		case send <- next:
+			queue = queue[1:]
+			inFlight++
+		case links := <-results:
+			inFlight--
+			queue = append(queue, links...)
+			sortFrontier(queue)
+		}
+	}
+}
+
+// limitReached reports whether MaxPages pages have been visited or MaxEmails
+// emails found.
+func (c *Crawler) limitReached() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.truncated || (c.opts.MaxPages > 0 && len(c.visited) >= c.opts.MaxPages)
+}
+
+// claim marks u as visited if it may be crawled at depth. It also reports
+// whether the page's raw HTML should be kept.
+func (c *Crawler) claim(u *url.URL, depth int) (ok, keepRaw bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if depth > c.maxDepth*depthStep || c.visited[u.String()] || !c.inScope(u) || c.excluded(u) {
+		return false, false
+	}
+	if c.truncated || (c.opts.MaxPages > 0 && len(c.visited) >= c.opts.MaxPages) {
+		return false, false
+	}
 	c.visited[u.String()] = true
-	log.Printf("Crawling [Depth: %d]: %s", depth, u.String())
+	delete(c.pending, u.String())
+	if depth > c.depthReached {
+		c.depthReached = depth
+	}
+	return true, c.opts.RawHTMLLimit > 0 && c.isContactLink(u.Path)
+}
+
+// visit fetches one page, records its emails and returns the links to follow.
+// Only the fetch runs unlocked, so concurrent visits share the crawler state
+// safely.
+func (c *Crawler) visit(u *url.URL, depth int) []target {
+	ok, keepRaw := c.claim(u, depth)
+	if !ok {
+		return nil
+	}
+	c.log.Info("crawling", "url", u.String(), "depth", float64(depth)/depthStep)
 
-	resp, err := http.Get(u.String())
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
-		log.Printf("Error fetching %s: %v", u.String(), err)
-		return
+		c.log.Error("building request failed", "url", u.String(), "error", err)
+		return nil
+	}
+	// The start page is always fetched in full so its links can be followed
+	if !c.opts.IfModifiedSince.IsZero() && u.String() != c.baseURL.String() {
+		req.Header.Set("If-Modified-Since", c.opts.IfModifiedSince.UTC().Format(http.TimeFormat))
+	}
+
+	resp, err := c.fetch(req)
+	if err != nil {
+		c.log.Warn("fetch failed", "url", u.String(), "error", err)
+		c.failStart(u, err)
+		return nil
 	}
 	defer resp.Body.Close()
+	// Links are relative to where any redirects ended up
+	pageURL := resp.Request.URL
+
+	if resp.StatusCode == http.StatusNotModified {
+		c.log.Info("not modified", "url", u.String(), "since", c.opts.IfModifiedSince.Format(time.RFC3339))
+		return nil
+	}
 
 	if resp.StatusCode != http.StatusOK {
-		log.Printf("Error status code %d for %s", resp.StatusCode, u.String())
-		return
+		c.log.Warn("unexpected status", "url", u.String(), "status", resp.StatusCode)
+		c.failStart(u, &StatusError{StatusCode: resp.StatusCode})
+		return nil
 	}
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if contentType := resp.Header.Get("Content-Type"); !isHTML(contentType) {
+		c.log.Info("skipping non-HTML content", "url", u.String(), "content_type", contentType)
+		return nil
+	}
+
+	var body io.Reader = resp.Body
+	var limited *io.LimitedReader
+	if c.opts.MaxBodyBytes > 0 {
+		limited = &io.LimitedReader{R: resp.Body, N: c.opts.MaxBodyBytes}
+		body = limited
+	}
+	var raw *bytes.Buffer
+	if keepRaw {
+		raw = &bytes.Buffer{}
+		body = io.TeeReader(body, raw)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(body)
 	if err != nil {
-		log.Printf("Error parsing %s: %v", u.String(), err)
-		return
+		c.log.Warn("parsing failed", "url", u.String(), "error", err)
+		return nil
+	}
+	// Parse what fit, but say so if the page went on past the limit
+	if limited != nil && limited.N == 0 {
+		if n, _ := resp.Body.Read(make([]byte, 1)); n > 0 {
+			c.log.Warn("page truncated", "url", u.String(), "max_bytes", c.opts.MaxBodyBytes)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.opts.OnPage != nil {
+		c.opts.OnPage(u.String(), (depth+depthStep-1)/depthStep)
+	}
+	if raw != nil {
+		c.rawPages[u.String()] = string(raw.Bytes()[:min(c.opts.RawHTMLLimit, raw.Len())])
+	}
+
+	if c.opts.AutoLanguage && !c.languageDetected {
+		c.detectLanguage(doc, resp)
 	}
 
 	// Check for meta refresh redirect
 	metaRefresh := doc.Find("meta[http-equiv='refresh']").AttrOr("content", "")
 	if metaRefresh != "" {
-		log.Printf("Found meta refresh: %s", metaRefresh)
-		if redirectURL := c.parseMetaRefresh(metaRefresh, u); redirectURL != nil {
-			log.Printf("Following meta redirect to: %s", redirectURL.String())
-			c.crawlRecursive(redirectURL, depth)
-			return
+		c.log.Debug("found meta refresh", "url", u.String(), "content", metaRefresh)
+		if redirectURL := c.parseMetaRefresh(metaRefresh, pageURL); redirectURL != nil {
+			c.log.Info("following meta refresh", "url", u.String(), "target", redirectURL.String())
+			return []target{{u: redirectURL, depth: depth}}
 		}
 	}
 
-	bodyText := doc.Find("body").Text()
-	foundEmails := emailRegex.FindAllString(bodyText, -1)
-	log.Printf("Body text preview (first 200 chars): %s", strings.ReplaceAll(bodyText[:min(200, len(bodyText))], "\n", " "))
-	log.Printf("Found %d emails: %v", len(foundEmails), foundEmails)
-	for _, email := range foundEmails {
-		c.emails[strings.ToLower(email)] = true
+	// The start page is never treated as a soft 404; it was explicitly requested
+	if c.opts.DetectSoft404 && u.String() != c.baseURL.String() && c.isSoft404(doc) {
+		c.log.Info("skipping soft 404 page", "url", u.String())
+		return nil
 	}
 
+	if c.unchangedSince(resp) {
+		// Still follow links: newer pages may be reachable from an unchanged one
+		c.log.Info("skipping extraction, unchanged", "url", u.String(), "since", c.opts.IfModifiedSince.Format(time.RFC3339))
+	} else {
+		bodyText := doc.Find("body").Text()
+		foundEmails := c.extractEmails(bodyText)
+		foundEmails = append(foundEmails, extractMailtoEmails(doc)...)
+		foundEmails = append(foundEmails, extractJSONLDEmails(doc)...)
+		if c.opts.ExtractObfuscatedEmails {
+			foundEmails = append(foundEmails, c.extractObfuscatedEmails(doc)...)
+		}
+		c.log.Debug("body preview", "url", u.String(), "text", strings.ReplaceAll(bodyText[:min(200, len(bodyText))], "\n", " "))
+		c.log.Info("extracted emails", "url", u.String(), "depth", float64(depth)/depthStep, "status", resp.StatusCode, "emails_found", len(foundEmails))
+		c.log.Debug("emails", "url", u.String(), "emails", foundEmails)
+		for _, email := range foundEmails {
+			lower := strings.ToLower(email)
+			if c.blocked(lower) {
+				continue
+			}
+			if !c.emails[lower] && c.truncated {
+				continue
+			}
+			if !c.emails[lower] && c.opts.OnEmail != nil {
+				c.opts.OnEmail(lower, u.String())
+			}
+			c.emails[lower] = true
+			if c.opts.MaxEmails > 0 && len(c.emails) >= c.opts.MaxEmails {
+				c.truncated = true
+			}
+			if _, seen := c.original[lower]; !seen {
+				c.original[lower] = email
+			}
+			if pages := c.sources[lower]; len(pages) == 0 || pages[len(pages)-1] != u.String() {
+				c.sources[lower] = append(pages, u.String())
+			}
+		}
+		c.extractLabels(doc)
+	}
+
+	// Record every link as pending so a checkpoint taken mid-crawl includes
+	// this page's unvisited siblings
+	var links []target
+	seen := make(map[string]bool)
 	doc.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
 		href, exists := s.Attr("href")
 		if !exists {
 			return
 		}
 
-		nextURL := c.resolveURL(u, href)
-		if nextURL == nil {
+		nextURL := c.resolveURL(pageURL, href)
+		if nextURL == nil || seen[nextURL.String()] {
 			return
 		}
+		seen[nextURL.String()] = true
 
-		if c.isContactLink(nextURL.Path) {
-			c.crawlRecursive(nextURL, depth)
-		} else {
-			c.crawlRecursive(nextURL, depth+1)
+		tier := c.contactTier(nextURL.Path)
+		nextDepth := depth + tier.depthCost()
+		if c.followPagination(s, nextURL) {
+			nextDepth = depth
 		}
+		links = append(links, target{nextURL, nextDepth, tier})
 	})
+
+	// Language and AMP variants of this page share its depth
+	if c.opts.MaxAlternates > 0 {
+		doc.Find("link[rel][href]").Each(func(_ int, s *goquery.Selection) {
+			if c.alternatesFollowed >= c.opts.MaxAlternates || !isAlternateLink(s) {
+				return
+			}
+			altURL := c.resolveURL(pageURL, s.AttrOr("href", ""))
+			if altURL == nil || seen[altURL.String()] || c.visited[altURL.String()] || !c.inScope(altURL) {
+				return
+			}
+			seen[altURL.String()] = true
+			c.alternatesFollowed++
+			links = append(links, target{altURL, depth, tierStrong})
+		})
+	}
+
+	// Over the fan-out cap, keep the most contact-like links
+	if c.opts.MaxLinksPerPage > 0 && len(links) > c.opts.MaxLinksPerPage {
+		sort.SliceStable(links, func(i, j int) bool { return links[i].tier > links[j].tier })
+		c.log.Info("capping links", "url", u.String(), "following", c.opts.MaxLinksPerPage, "found", len(links))
+		links = links[:c.opts.MaxLinksPerPage]
+	}
+
+	for _, l := range links {
+		if c.visited[l.u.String()] || l.depth > c.maxDepth*depthStep || !c.inScope(l.u) || c.excluded(l.u) {
+			continue
+		}
+		if queued, ok := c.pending[l.u.String()]; !ok || l.depth < queued {
+			c.pending[l.u.String()] = l.depth
+		}
+	}
+	c.checkpoint()
+
+	return links
+}
+
+// fetch sends req once the host limiters, if any, allow it. Every request asks
+// for HTML and carries the configured User-Agent, and compressed responses are
+// returned decoded.
+func (c *Crawler) fetch(req *http.Request) (*http.Response, error) {
+	if c.opts.UserAgent != "" {
+		req.Header.Set("User-Agent", c.opts.UserAgent)
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.1")
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+
+	for _, limiter := range []*HostLimiter{c.limiter, c.opts.HostLimiter} {
+		if limiter == nil {
+			continue
+		}
+		if err := limiter.Wait(req.Context(), req.URL.Host); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.checkTarget(req); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := decodeBody(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+// isHTML reports whether a Content-Type header denotes an HTML page. A missing
+// header is assumed to be HTML, as many servers omit it.
+func isHTML(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+// unchangedSince reports whether the response's Last-Modified header places the
+// page at or before the configured IfModifiedSince time.
+func (c *Crawler) unchangedSince(resp *http.Response) bool {
+	if c.opts.IfModifiedSince.IsZero() {
+		return false
+	}
+	lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.After(c.opts.IfModifiedSince)
 }
 
 func (c *Crawler) isContactLink(path string) bool {
+	return c.contactTier(path) != tierNone
+}
+
+// contactTier classifies a link path by the strongest contact keyword it contains.
+func (c *Crawler) contactTier(path string) linkTier {
 	lowerPath := strings.ToLower(path)
-	for _, keyword := range contactKeywords {
-		if strings.Contains(lowerPath, keyword) {
+	if c.matchesKeywords(lowerPath, c.strongKeywords) {
+		return tierStrong
+	}
+	if c.matchesKeywords(lowerPath, c.weakKeywords) {
+		return tierWeak
+	}
+	return tierNone
+}
+
+// matchesKeywords reports whether path contains a keyword of an active language.
+func (c *Crawler) matchesKeywords(lowerPath string, keywords map[string][]string) bool {
+	for lang, words := range keywords {
+		if c.language != "" && lang != "" && lang != "en" && lang != c.language {
+			continue
+		}
+		for _, keyword := range words {
+			if strings.Contains(lowerPath, keyword) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// detectLanguage reads the site language from the first page fetched. Only
+// languages with keyword lists narrow matching; anything else keeps them all.
+func (c *Crawler) detectLanguage(doc *goquery.Document, resp *http.Response) {
+	c.languageDetected = true
+
+	lang := doc.Find("html").AttrOr("lang", "")
+	if lang == "" {
+		lang = resp.Header.Get("Content-Language")
+	}
+	lang = strings.ToLower(strings.TrimSpace(strings.Split(lang, ",")[0]))
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+
+	if _, ok := contactKeywords[lang]; ok && lang != "" {
+		c.language = lang
+		c.log.Info("detected site language, matching its and English keywords", "language", lang)
+	}
+}
+
+// followPagination reports whether a link is a pagination link that may still
+// be followed at the current depth, consuming one unit of the pagination budget.
+func (c *Crawler) followPagination(s *goquery.Selection, link *url.URL) bool {
+	if c.paginationFollowed >= c.opts.MaxPagination || c.visited[link.String()] || !c.inScope(link) {
+		return false
+	}
+	isNext := false
+	for _, rel := range strings.Fields(strings.ToLower(s.AttrOr("rel", ""))) {
+		if rel == "next" {
+			isNext = true
+		}
+	}
+	if !isNext && !paginationRegex.MatchString(link.RequestURI()) {
+		return false
+	}
+	c.paginationFollowed++
+	return true
+}
+
+// isAlternateLink reports whether a <link> points at another language version
+// (rel="alternate" with hreflang) or the AMP version of the page. Feeds and
+// other alternates without hreflang are ignored.
+func isAlternateLink(s *goquery.Selection) bool {
+	for _, rel := range strings.Fields(strings.ToLower(s.AttrOr("rel", ""))) {
+		switch rel {
+		case "amphtml":
 			return true
+		case "alternate":
+			if _, ok := s.Attr("hreflang"); ok {
+				return true
+			}
 		}
 	}
 	return false
 }
 
+// checkRedirect follows at most Options.MaxRedirects redirects and refuses
+// those that leave the crawl scope. The start URL may redirect to another host
+// (example.com to www.example.com), which then becomes part of the scope.
+func (c *Crawler) checkRedirect(req *http.Request, via []*http.Request) error {
+	if c.opts.MaxRedirects > 0 && len(via) > c.opts.MaxRedirects {
+		return fmt.Errorf("stopped after %d redirects", c.opts.MaxRedirects)
+	}
+	if err := c.checkTarget(req); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !DomainAllowed(req.URL.Hostname(), c.opts.AllowedDomains) {
+		return fmt.Errorf("redirect to %s leaves the allowed domains", req.URL)
+	}
+	if !c.inScope(req.URL) {
+		if via[0].URL.String() != c.baseURL.String() || c.redirectHost != "" {
+			return fmt.Errorf("redirect to %s leaves %s", req.URL, c.baseURL.Host)
+		}
+		c.redirectHost = req.URL.Host
+	}
+	c.log.Info("following redirect", "from", via[len(via)-1].URL.String(), "to", req.URL.String())
+	return nil
+}
+
+// inScope reports whether u may be crawled: it is on the start URL's host or,
+// with Options.IncludeSubdomains, anywhere under the same registrable domain,
+// and in Options.AllowedDomains.
+func (c *Crawler) inScope(u *url.URL) bool {
+	if !DomainAllowed(u.Hostname(), c.opts.AllowedDomains) {
+		return false
+	}
+	if u.Host == c.baseURL.Host || (c.redirectHost != "" && u.Host == c.redirectHost) {
+		return true
+	}
+	if c.baseDomain == "" {
+		return false
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(u.Hostname())
+	return err == nil && domain == c.baseDomain
+}
+
+// resolveURL resolves href against base. Links that aren't http(s), such as
+// mailto:, tel: and javascript:, resolve to nil since they can't be crawled.
 func (c *Crawler) resolveURL(base *url.URL, href string) *url.URL {
 	resolved, err := base.Parse(href)
-	if err != nil {
+	if err != nil || (resolved.Scheme != "http" && resolved.Scheme != "https") {
 		return nil
 	}
-	return resolved
+	return c.normalizeURL(resolved)
+}
+
+// normalizeURL returns the form of u used to deduplicate pages: no fragment, a
+// lowercase host, no trailing slash, and sorted query parameters without the
+// tracking ones listed in Options.StripQueryParams.
+func (c *Crawler) normalizeURL(u *url.URL) *url.URL {
+	normalized := *u
+	normalized.Fragment = ""
+	normalized.RawFragment = ""
+	normalized.Host = strings.ToLower(normalized.Host)
+
+	if normalized.Path == "" {
+		normalized.Path = "/"
+	} else if len(normalized.Path) > 1 {
+		normalized.Path = strings.TrimRight(normalized.Path, "/")
+		if normalized.Path == "" {
+			normalized.Path = "/"
+		}
+	}
+	normalized.RawPath = ""
+
+	if normalized.RawQuery != "" {
+		query := normalized.Query()
+		for name := range query {
+			if c.stripQueryParam(name) {
+				query.Del(name)
+			}
+		}
+		normalized.RawQuery = query.Encode()
+	}
+	return &normalized
+}
+
+// stripQueryParam reports whether name matches Options.StripQueryParams, where
+// a trailing "*" matches any suffix ("utm_*").
+func (c *Crawler) stripQueryParam(name string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range c.opts.StripQueryParams {
+		pattern = strings.ToLower(pattern)
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
 }
 
 func (c *Crawler) parseMetaRefresh(content string, base *url.URL) *url.URL {
@@ -156,7 +1087,7 @@ func (c *Crawler) parseMetaRefresh(content string, base *url.URL) *url.URL {
 		part = strings.TrimSpace(part)
 		if strings.HasPrefix(strings.ToLower(part), "url=") {
 			urlStr := strings.TrimSpace(part[4:])
-			if redirectURL, err := base.Parse(urlStr); err == nil {
+			if redirectURL := c.resolveURL(base, urlStr); redirectURL != nil {
 				return redirectURL
 			}
 		}
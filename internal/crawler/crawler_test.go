@@ -0,0 +1,741 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestSite serves each page of pages, keyed by path, as HTML and returns
+// the site's root URL. Other paths are not found.
+func newTestSite(t *testing.T, pages map[string]string) *url.URL {
+	t.Helper()
+	return newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, page)
+	}))
+}
+
+// newTestServer starts handler and returns its root URL.
+func newTestServer(t *testing.T, handler http.Handler) *url.URL {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	u, _ := url.Parse(server.URL)
+	return u
+}
+
+// testOptions returns options that let a crawl reach the loopback test
+// servers, with its logs discarded.
+func testOptions(maxDepth int) Options {
+	return Options{
+		MaxDepth:            maxDepth,
+		AllowPrivateTargets: true,
+		Logger:              slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+}
+
+func TestIfModifiedSinceSkipsUnchangedPages(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	lastModified := map[string]time.Time{
+		"/":      since.AddDate(-1, 0, 0),
+		"/older": since.AddDate(0, -1, 0),
+		"/newer": since.AddDate(0, 1, 0),
+	}
+	var conditional int
+	site := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		modified, ok := lastModified[r.URL.Path]
+		if !ok {
+			// Servers that honour If-Modified-Since answer 304 themselves
+			if r.Header.Get("If-Modified-Since") != "" {
+				conditional++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			modified = since.AddDate(1, 0, 0)
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		name := r.URL.Path[1:]
+		if name == "" {
+			name = "home"
+		}
+		fmt.Fprintf(w, `<html><body>%s@site.test
+			<a href="/older">Older</a> <a href="/newer">Newer</a> <a href="/conditional">Conditional</a>
+		</body></html>`, name)
+	}))
+
+	opts := testOptions(1)
+	opts.IfModifiedSince = since
+	emails := NewWithOptions(opts).Crawl(site)
+
+	for email, want := range map[string]bool{
+		"home@site.test":        false,
+		"older@site.test":       false,
+		"newer@site.test":       true,
+		"conditional@site.test": false,
+	} {
+		if emails[email] != want {
+			t.Errorf("found %s = %v, want %v", email, emails[email], want)
+		}
+	}
+	if conditional != 1 {
+		t.Errorf("%d conditional requests got 304, want 1", conditional)
+	}
+}
+
+func TestJoinSplitEmailsRecoversElementSplitAddress(t *testing.T) {
+	site := newTestSite(t, map[string]string{
+		"/": `<html><body>
+			<p>Write to
+				<span>john</span>
+				@
+				<span>example</span>
+				.<span>com</span>.
+			</p>
+		</body></html>`,
+	})
+
+	for _, join := range []bool{false, true} {
+		opts := testOptions(0)
+		opts.JoinSplitEmails = join
+		emails := NewWithOptions(opts).Crawl(site)
+		if emails["john@example.com"] != join {
+			t.Errorf("JoinSplitEmails=%v: found john@example.com = %v, want %v (all found: %v)", join, emails["john@example.com"], join, emails)
+		}
+	}
+}
+
+func TestLabelsAreCapturedFromContactPage(t *testing.T) {
+	site := newTestSite(t, map[string]string{
+		"/": `<html><body>
+			<ul>
+				<li>Sales: <a href="mailto:sales@site.test">sales@site.test</a></li>
+				<li><strong>Press inquiries:</strong> press@site.test</li>
+			</ul>
+			<p>Questions? Our whole team reads team@site.test every day.</p>
+			<p>Support: help@site.test | Billing: billing@site.test</p>
+		</body></html>`,
+	})
+
+	c := NewWithOptions(testOptions(0))
+	c.Crawl(site)
+
+	want := map[string]string{
+		"sales@site.test":   "Sales",
+		"press@site.test":   "Press inquiries",
+		"help@site.test":    "Support",
+		"billing@site.test": "Billing",
+	}
+	labels := c.Labels()
+	for email, label := range want {
+		if labels[email] != label {
+			t.Errorf("label of %s = %q, want %q", email, labels[email], label)
+		}
+	}
+	if label, ok := labels["team@site.test"]; ok {
+		t.Errorf("unlabelled team@site.test got label %q", label)
+	}
+}
+
+func TestDetectSoft404SkipsErrorPagesServedWith200(t *testing.T) {
+	pages := map[string]string{
+		"/": `<html><head><title>Acme</title></head><body>
+			<a href="/contact">Contact</a> <a href="/team">Team</a> <a href="/old-contact">Old contact</a>
+		</body></html>`,
+		"/contact": `<html><head><title>Contact | Acme</title></head><body>sales@site.test</body></html>`,
+		"/old-contact": `<html><head><title>Page not found</title></head><body>
+			webmaster@site.test <a href="/error-sitemap">Sitemap</a>
+		</body></html>`,
+		"/error-sitemap": `<html><body>sitemap@site.test</body></html>`,
+	}
+	site := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if page, ok := pages[r.URL.Path]; ok {
+			fmt.Fprint(w, page)
+			return
+		}
+		// Every other path gets the same error page, with a 200
+		fmt.Fprintf(w, `<html><head><title>Oops | Acme</title></head><body>
+			<nav>Home Products Solutions Pricing Customers About us Careers Blog</nav>
+			<p>Nothing lives at %s. Write to errors@site.test if you think this is a mistake.</p>
+			<footer>Acme Corporation, 1 Industrial Way, Springfield. All rights reserved.
+			Privacy policy, terms of service, cookie settings and accessibility statement.</footer>
+		</body></html>`, r.URL.Path)
+	}))
+
+	for _, detect := range []bool{false, true} {
+		opts := testOptions(2)
+		opts.DetectSoft404 = detect
+		emails := NewWithOptions(opts).Crawl(site)
+
+		if !emails["sales@site.test"] {
+			t.Errorf("DetectSoft404=%v: the real contact page's address was not found (found %v)", detect, emails)
+		}
+		for _, email := range []string{"errors@site.test", "webmaster@site.test", "sitemap@site.test"} {
+			if emails[email] == detect {
+				t.Errorf("DetectSoft404=%v: found %s = %v, want %v", detect, email, emails[email], !detect)
+			}
+		}
+	}
+}
+
+func TestSoft404WordingMatchesErrorPagesOnly(t *testing.T) {
+	tests := []struct {
+		title, heading string
+		want           bool
+	}{
+		{"Page not found | Acme", "", true},
+		{"Acme", "404 Not Found", true},
+		{"Error 404 - Acme", "", true},
+		{"Acme", "404 error", true},
+		{"Seite nicht gefunden", "", true},
+		{"Suite 404 | Acme Offices", "", false},
+		{"Contact", "Call 404-555-0100", false},
+		{"Model X404 brochure", "X404", false},
+	}
+	c := NewWithOptions(testOptions(0))
+	for _, tt := range tests {
+		page := fmt.Sprintf("<html><head><title>%s</title></head><body><h1>%s</h1></body></html>", tt.title, tt.heading)
+		if got := c.isSoft404(parseHTML(t, page)); got != tt.want {
+			t.Errorf("isSoft404(title %q, heading %q) = %v, want %v", tt.title, tt.heading, got, tt.want)
+		}
+	}
+}
+
+func TestSoft404ProbeHonoursMaxBodyBytes(t *testing.T) {
+	const streamed = 64 << 20
+	written := make(chan int, 1)
+	site := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<html><body>home@site.test</body></html>`)
+			return
+		}
+		// Any other path, the probe included, gets an endless 200 page
+		n, _ := fmt.Fprint(w, "<html><head><title>Oops</title></head><body><p>")
+		chunk := []byte(strings.Repeat("padding ", 4096))
+		for n < streamed {
+			m, err := w.Write(chunk)
+			n += m
+			if err != nil {
+				break
+			}
+		}
+		written <- n
+	}))
+
+	opts := testOptions(0)
+	opts.DetectSoft404 = true
+	opts.MaxBodyBytes = 64 << 10
+	if emails := NewWithOptions(opts).Crawl(site); !emails["home@site.test"] {
+		t.Errorf("emails = %v, want home@site.test", emails)
+	}
+	select {
+	case n := <-written:
+		if n >= streamed {
+			t.Errorf("server wrote the whole %d byte probe response, want the crawler to hang up", n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("the soft-404 probe was not sent or its response was not closed")
+	}
+}
+
+func TestMaxPaginationFollowsDirectoryPagesWithoutDepth(t *testing.T) {
+	const pageCount = 5
+	pageURL := func(page int) string {
+		if page == 1 {
+			return "/members"
+		}
+		return fmt.Sprintf("/members?page=%d", page)
+	}
+	site := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<html><body><a href="/members">People</a></body></html>`)
+			return
+		}
+		page := 1
+		fmt.Sscanf(r.URL.Query().Get("page"), "%d", &page)
+		next := ""
+		if page < pageCount {
+			next = fmt.Sprintf(`<a rel="next" href="%s">Next</a>`, pageURL(page+1))
+		}
+		fmt.Fprintf(w, `<html><body>person%d@site.test %s</body></html>`, page, next)
+	}))
+
+	for _, tt := range []struct {
+		maxPagination int
+		wantPages     int
+	}{
+		{0, 1},
+		{2, 3},
+		{10, pageCount},
+	} {
+		opts := testOptions(1)
+		opts.MaxPagination = tt.maxPagination
+		emails := NewWithOptions(opts).Crawl(site)
+		for page := 1; page <= pageCount; page++ {
+			email := fmt.Sprintf("person%d@site.test", page)
+			if want := page <= tt.wantPages; emails[email] != want {
+				t.Errorf("MaxPagination=%d: found %s = %v, want %v", tt.maxPagination, email, emails[email], want)
+			}
+		}
+	}
+}
+
+func TestSharedHostLimiterCapsConcurrentCrawls(t *testing.T) {
+	const rps = 10
+	var mu sync.Mutex
+	var requests []time.Time
+	site := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, time.Now())
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body><a href="/a">A</a> <a href="/b">B</a> <a href="/c">C</a> <a href="/d">D</a></body></html>`)
+	}))
+
+	limiter := NewHostLimiter(rps)
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			opts := testOptions(1)
+			opts.HostLimiter = limiter
+			NewWithOptions(opts).Crawl(site)
+		}()
+	}
+	wg.Wait()
+
+	if len(requests) != 10 {
+		t.Fatalf("site got %d requests, want 10 (five pages per crawl)", len(requests))
+	}
+	sort.Slice(requests, func(i, j int) bool { return requests[i].Before(requests[j]) })
+	// A burst of one lets the first request through at once; each later one
+	// waits its turn
+	want := time.Duration(len(requests)-1) * time.Second / rps
+	if span := requests[len(requests)-1].Sub(requests[0]); span < want*9/10 {
+		t.Errorf("%d requests spanned %s, want at least %s at %d requests per second", len(requests), span, want, rps)
+	}
+}
+
+func TestResumeSkipsPagesVisitedBeforeInterruption(t *testing.T) {
+	ctx, interrupt := context.WithCancel(context.Background())
+	defer interrupt()
+	var mu sync.Mutex
+	var fetched []string
+	site := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fetched = append(fetched, r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path == "/b" {
+			// The worker shuts down while this page is being fetched
+			interrupt()
+		}
+		name := strings.TrimPrefix(r.URL.Path, "/")
+		if name == "" {
+			name = "home"
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<html><body>%s@site.test <a href="/a">A</a> <a href="/b">B</a> <a href="/c">C</a></body></html>`, name)
+	}))
+
+	var saved *Frontier
+	opts := testOptions(1)
+	opts.Checkpoint = func(f *Frontier) { saved = f }
+	NewWithOptions(opts).CrawlWithContext(ctx, site)
+	if saved == nil {
+		t.Fatal("the interrupted crawl saved no frontier")
+	}
+
+	fetched = nil
+	opts = testOptions(1)
+	opts.Resume = saved
+	emails := NewWithOptions(opts).Crawl(site)
+
+	for _, path := range fetched {
+		if path == "/" || path == "/a" {
+			t.Errorf("resumed crawl refetched %s, visited before the interruption", path)
+		}
+	}
+	for _, email := range []string{"home@site.test", "a@site.test", "b@site.test", "c@site.test"} {
+		if !emails[email] {
+			t.Errorf("resumed crawl is missing %s (found %v)", email, emails)
+		}
+	}
+}
+
+func TestKeywordTiersCostDifferentDepth(t *testing.T) {
+	links := map[string][]string{
+		"/":         {"/contact", "/team", "/products"},
+		"/contact":  {"/c-next"},
+		"/team":     {"/t-next", "/staff-list"},
+		"/products": {"/help"},
+	}
+	site := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/")
+		if name == "" {
+			name = "home"
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<html><body>%s@site.test", name)
+		for _, link := range links[r.URL.Path] {
+			fmt.Fprintf(w, ` <a href="%s">link</a>`, link)
+		}
+		fmt.Fprint(w, "</body></html>")
+	}))
+
+	emails := NewWithOptions(testOptions(1)).Crawl(site)
+	for email, want := range map[string]bool{
+		// A strong keyword is free, so a regular link still fits after it
+		"contact@site.test": true,
+		"c-next@site.test":  true,
+		// A weak keyword costs half a level, leaving room for another weak one only
+		"team@site.test":       true,
+		"staff-list@site.test": true,
+		"t-next@site.test":     false,
+		// A regular link spends the whole level
+		"products@site.test": true,
+		"help@site.test":     false,
+	} {
+		if emails[email] != want {
+			t.Errorf("found %s = %v, want %v", email, emails[email], want)
+		}
+	}
+}
+
+func TestMaxLinksPerPageFollowsContactLinksFirst(t *testing.T) {
+	var index strings.Builder
+	index.WriteString("<html><body>")
+	for i := 0; i < 980; i++ {
+		fmt.Fprintf(&index, `<a href="/item/%d">Item</a>`, i)
+	}
+	// The contact links come last, so only prioritizing them keeps them
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(&index, `<a href="/contact/%d">Contact</a><a href="/team/%d">Team</a>`, i, i)
+	}
+	index.WriteString("</body></html>")
+
+	var mu sync.Mutex
+	fetched := make(map[string]bool)
+	site := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fetched[r.URL.Path] = true
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, index.String())
+			return
+		}
+		fmt.Fprint(w, "<html><body></body></html>")
+	}))
+
+	opts := testOptions(1)
+	opts.MaxLinksPerPage = 50
+	NewWithOptions(opts).Crawl(site)
+
+	if len(fetched) != 51 {
+		t.Errorf("fetched %d pages, want the start page and 50 links", len(fetched))
+	}
+	for i := 0; i < 10; i++ {
+		for _, path := range []string{fmt.Sprintf("/contact/%d", i), fmt.Sprintf("/team/%d", i)} {
+			if !fetched[path] {
+				t.Errorf("contact link %s was not followed", path)
+			}
+		}
+	}
+	for i := 0; i < 980; i++ {
+		if want := i < 30; fetched[fmt.Sprintf("/item/%d", i)] != want {
+			t.Errorf("followed /item/%d = %v, want %v (the first 30 regular links)", i, !want, want)
+		}
+	}
+}
+
+func TestMaxAlternatesFollowsLanguageAndAMPVariants(t *testing.T) {
+	site := newTestSite(t, map[string]string{
+		"/": `<html><head>
+			<link rel="alternate" hreflang="de" href="/de/">
+			<link rel="alternate" type="application/rss+xml" href="/feed">
+			<link rel="amphtml" href="/amp/">
+			<link rel="alternate" hreflang="fr" href="https://other.test/fr/">
+		</head><body>info@site.test</body></html>`,
+		"/de":   `<html><body>vertrieb@site.test</body></html>`,
+		"/amp":  `<html><body>amp@site.test</body></html>`,
+		"/feed": `<html><body>feed@site.test</body></html>`,
+	})
+
+	for _, tt := range []struct {
+		maxAlternates int
+		want          []string
+	}{
+		{0, []string{"info@site.test"}},
+		{1, []string{"info@site.test", "vertrieb@site.test"}},
+		{5, []string{"amp@site.test", "info@site.test", "vertrieb@site.test"}},
+	} {
+		// Variants share the page's depth, so even a depth-0 crawl reaches them
+		opts := testOptions(0)
+		opts.MaxAlternates = tt.maxAlternates
+		emails := NewWithOptions(opts).Crawl(site)
+		var got []string
+		for email := range emails {
+			got = append(got, email)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("MaxAlternates=%d: found %v, want %v", tt.maxAlternates, got, tt.want)
+		}
+	}
+}
+
+func TestLongContactChainDoesNotRecurse(t *testing.T) {
+	const chainLength = 1000
+	site := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var step int
+		fmt.Sscanf(r.URL.Path, "/contact/%d", &step)
+		w.Header().Set("Content-Type", "text/html")
+		if step < chainLength {
+			// Contact links cost no depth, so the whole chain is in reach
+			fmt.Fprintf(w, `<html><body><a href="/contact/%d">Next</a></body></html>`, step+1)
+			return
+		}
+		fmt.Fprint(w, `<html><body>end@site.test</body></html>`)
+	}))
+
+	maxFrames := 0
+	opts := testOptions(0)
+	opts.OnPage = func(string, int) {
+		if frames := runtime.Callers(0, make([]uintptr, 4096)); frames > maxFrames {
+			maxFrames = frames
+		}
+	}
+	c := NewWithOptions(opts)
+	emails := c.Crawl(site)
+
+	if !emails["end@site.test"] || c.PagesVisited() != chainLength+1 {
+		t.Fatalf("crawled %d pages and found %v, want the whole chain of %d", c.PagesVisited(), emails, chainLength+1)
+	}
+	// The call stack stays the same height however long the chain is
+	if maxFrames > 100 {
+		t.Errorf("stack reached %d frames while crawling a %d-page chain", maxFrames, chainLength)
+	}
+}
+
+func TestAutoLanguageMatchesOnlyTheSiteLanguageKeywords(t *testing.T) {
+	const links = `<a href="/kontakt">Kontakt</a> <a href="/contatti">Contatti</a> <a href="/contact">Contact</a>`
+	pages := map[string]string{
+		"/kontakt":  `<html><body>kontakt@site.test</body></html>`,
+		"/contatti": `<html><body>contatti@site.test</body></html>`,
+		"/contact":  `<html><body>contact@site.test</body></html>`,
+	}
+	for _, tt := range []struct {
+		name         string
+		html         string
+		language     string
+		autoLanguage bool
+		want         map[string]bool
+	}{
+		{
+			"html lang", `<html lang="de">`, "", true,
+			map[string]bool{"kontakt@site.test": true, "contatti@site.test": false, "contact@site.test": true},
+		},
+		{
+			"Content-Language", `<html>`, "de-DE, en", true,
+			map[string]bool{"kontakt@site.test": true, "contatti@site.test": false, "contact@site.test": true},
+		},
+		{
+			"disabled", `<html lang="de">`, "", false,
+			map[string]bool{"kontakt@site.test": true, "contatti@site.test": true, "contact@site.test": true},
+		},
+	} {
+		site := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			if page, ok := pages[r.URL.Path]; ok {
+				fmt.Fprint(w, page)
+				return
+			}
+			if tt.language != "" {
+				w.Header().Set("Content-Language", tt.language)
+			}
+			fmt.Fprintf(w, "%s<body>%s</body></html>", tt.html, links)
+		}))
+
+		// At depth 0 only keyword links, which cost no depth, are followed
+		opts := testOptions(0)
+		opts.AutoLanguage = tt.autoLanguage
+		emails := NewWithOptions(opts).Crawl(site)
+		for email, want := range tt.want {
+			if emails[email] != want {
+				t.Errorf("%s: found %s = %v, want %v", tt.name, email, emails[email], want)
+			}
+		}
+	}
+}
+
+func TestRequestsPerSecondThrottlesFetches(t *testing.T) {
+	if testing.Short() {
+		t.Skip("takes about 5 seconds")
+	}
+	var links strings.Builder
+	for i := 1; i < 10; i++ {
+		fmt.Fprintf(&links, `<a href="/page/%d">Page %d</a>`, i, i)
+	}
+	var mu sync.Mutex
+	var fetches int
+	site := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fetches++
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<html><body>%s</body></html>", links.String())
+	}))
+
+	opts := testOptions(1)
+	opts.RequestsPerSecond = 2
+	// Concurrent fetches wait on the same per-host bucket
+	opts.Concurrency = 4
+	start := time.Now()
+	NewWithOptions(opts).Crawl(site)
+	elapsed := time.Since(start)
+
+	if fetches != 10 {
+		t.Fatalf("crawl made %d fetches, want 10", fetches)
+	}
+	// The first fetch goes out at once, then one every half second
+	if elapsed < 4*time.Second || elapsed > 6*time.Second {
+		t.Errorf("10 fetches at 2 rps took %s, want about 5s", elapsed)
+	}
+}
+
+func TestMaxBodyBytesStopsReadingLargePages(t *testing.T) {
+	const streamed = 64 << 20
+	written := make(chan int, 1)
+	site := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		n, _ := fmt.Fprint(w, "<html><body><p>early@site.test</p>\n<p>")
+		chunk := []byte(strings.Repeat("padding ", 4096))
+		for n < streamed {
+			m, err := w.Write(chunk)
+			n += m
+			if err != nil {
+				break
+			}
+		}
+		fmt.Fprint(w, "</p>\n<p>late@site.test</p></body></html>")
+		written <- n
+	}))
+
+	opts := testOptions(0)
+	opts.MaxBodyBytes = 64 << 10
+	emails := NewWithOptions(opts).Crawl(site)
+
+	if !emails["early@site.test"] || emails["late@site.test"] {
+		t.Errorf("emails = %v, want only early@site.test", emails)
+	}
+	select {
+	case n := <-written:
+		// Socket buffers absorb some of the stream before the close shows
+		if n >= streamed {
+			t.Errorf("server wrote the whole %d byte page, want the crawler to hang up", n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("server still writing 5s after the crawl, want the crawler to close the body")
+	}
+}
+
+func TestContactKeywordsReplaceOrExtendTheBuiltInLists(t *testing.T) {
+	links := map[string][]string{
+		"/":        {"/kontakt", "/contact"},
+		"/kontakt": {"/k-next"},
+		"/contact": {"/c-next"},
+	}
+	site := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<html><body>%s@site.test", strings.TrimPrefix(r.URL.Path, "/"))
+		for _, link := range links[r.URL.Path] {
+			fmt.Fprintf(w, ` <a href="%s">link</a>`, link)
+		}
+		fmt.Fprint(w, "</body></html>")
+	}))
+
+	tests := []struct {
+		name    string
+		replace bool
+		// A keyword link is free at depth 1, so the page behind it is found
+		wantKNext, wantCNext bool
+	}{
+		{"append", false, true, true},
+		{"replace", true, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions(1)
+			opts.ContactKeywords = []string{"Kontakt"}
+			opts.ReplaceContactKeywords = tt.replace
+			emails := NewWithOptions(opts).Crawl(site)
+			if !emails["kontakt@site.test"] || !emails["contact@site.test"] {
+				t.Errorf("emails = %v, want both linked pages", emails)
+			}
+			if emails["k-next@site.test"] != tt.wantKNext || emails["c-next@site.test"] != tt.wantCNext {
+				t.Errorf("found k-next = %v, c-next = %v; want %v, %v",
+					emails["k-next@site.test"], emails["c-next@site.test"], tt.wantKNext, tt.wantCNext)
+			}
+		})
+	}
+
+	c := NewWithKeywords(1, []string{"Kontakt"})
+	if want := map[string][]string{"": {"kontakt"}}; !reflect.DeepEqual(c.strongKeywords, want) || c.weakKeywords != nil {
+		t.Errorf("NewWithKeywords keywords = %v, %v; want only %v", c.strongKeywords, c.weakKeywords, want)
+	}
+}
+
+func TestStrategyOrdersSequentialCrawl(t *testing.T) {
+	site := newTestSite(t, map[string]string{
+		"/":   `<html><body><a href="/a">A</a> <a href="/b">B</a></body></html>`,
+		"/a":  `<html><body><a href="/a1">A1</a></body></html>`,
+		"/b":  `<html><body><a href="/b1">B1</a></body></html>`,
+		"/a1": `<html><body></body></html>`,
+		"/b1": `<html><body></body></html>`,
+	})
+
+	tests := []struct {
+		strategy string
+		want     []string
+	}{
+		{StrategyDFS, []string{"/", "/a", "/a1", "/b", "/b1"}},
+		{StrategyBFS, []string{"/", "/a", "/b", "/a1", "/b1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			var visited []string
+			opts := testOptions(2)
+			opts.Strategy = tt.strategy
+			opts.Concurrency = 1
+			opts.OnPage = func(pageURL string, _ int) {
+				u, _ := url.Parse(pageURL)
+				visited = append(visited, u.Path)
+			}
+			NewWithOptions(opts).Crawl(site)
+			if !reflect.DeepEqual(visited, tt.want) {
+				t.Errorf("visit order = %v, want %v", visited, tt.want)
+			}
+		})
+	}
+}
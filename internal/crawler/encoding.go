@@ -0,0 +1,65 @@
+package crawler
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// acceptEncoding is sent with every fetch. Setting it ourselves turns off the
+// transport's transparent gzip handling, so decodeBody covers both encodings.
+const acceptEncoding = "gzip, deflate"
+
+// decodedBody reads the decompressed stream and closes the original body.
+type decodedBody struct {
+	io.Reader
+	body io.Closer
+}
+
+func (d decodedBody) Close() error {
+	return d.body.Close()
+}
+
+// decodeBody replaces a gzip or deflate encoded response body with its
+// decompressed stream, so size limits and parsing apply to the real HTML.
+func decodeBody(resp *http.Response) error {
+	var reader io.Reader
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "", "identity":
+		return nil
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return fmt.Errorf("invalid gzip body: %w", err)
+		}
+		reader = gz
+	case "deflate":
+		// "deflate" is meant to be zlib-wrapped, but some servers send raw
+		// DEFLATE; a zlib stream starts with a CMF byte whose low nibble is 8
+		buffered := bufio.NewReader(resp.Body)
+		header, _ := buffered.Peek(2)
+		if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+			zr, err := zlib.NewReader(buffered)
+			if err != nil {
+				return fmt.Errorf("invalid deflate body: %w", err)
+			}
+			reader = zr
+		} else {
+			reader = flate.NewReader(buffered)
+		}
+	default:
+		return fmt.Errorf("unsupported Content-Encoding %q", resp.Header.Get("Content-Encoding"))
+	}
+
+	resp.Body = decodedBody{Reader: reader, body: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
@@ -0,0 +1,52 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestCompressedPagesAreDecoded(t *testing.T) {
+	const page = `<html><body><p>sales@site.test</p></body></html>`
+	tests := []struct {
+		name     string
+		encoding string
+		compress func(io.Writer) io.WriteCloser
+	}{
+		{"gzip", "gzip", func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }},
+		{"zlib deflate", "deflate", func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }},
+		// Without the zlib wrapper, as some servers send it
+		{"raw deflate", "deflate", func(w io.Writer) io.WriteCloser {
+			fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+			return fw
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body bytes.Buffer
+			zw := tt.compress(&body)
+			io.WriteString(zw, page)
+			zw.Close()
+
+			var accepted string
+			site := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				accepted = r.Header.Get("Accept-Encoding")
+				w.Header().Set("Content-Type", "text/html")
+				w.Header().Set("Content-Encoding", tt.encoding)
+				w.Write(body.Bytes())
+			}))
+
+			emails := NewWithOptions(testOptions(0)).Crawl(site)
+			if !emails["sales@site.test"] {
+				t.Errorf("emails = %v, want sales@site.test from the %s page", emails, tt.encoding)
+			}
+			if accepted != acceptEncoding {
+				t.Errorf("Accept-Encoding = %q, want %q", accepted, acceptEncoding)
+			}
+		})
+	}
+}
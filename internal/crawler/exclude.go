@@ -0,0 +1,51 @@
+package crawler
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sync"
+)
+
+// CompileExcludePatterns compiles URL path exclusion patterns, naming the first
+// invalid one in the error.
+func CompileExcludePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+var (
+	sharedExcludePatterns     []*regexp.Regexp
+	sharedExcludePatternsOnce sync.Once
+)
+
+// SharedExcludePatterns returns the configured patterns compiled once per
+// process. Startup rejects invalid patterns with CompileExcludePatterns, so an
+// error here only leaves exclusion off.
+func SharedExcludePatterns(patterns []string) []*regexp.Regexp {
+	sharedExcludePatternsOnce.Do(func() {
+		sharedExcludePatterns, _ = CompileExcludePatterns(patterns)
+	})
+	return sharedExcludePatterns
+}
+
+// excluded reports whether u's path matches an exclude pattern. The start URL
+// is never excluded since it was explicitly requested.
+func (c *Crawler) excluded(u *url.URL) bool {
+	if len(c.opts.ExcludePatterns) == 0 || u.String() == c.baseURL.String() {
+		return false
+	}
+	for _, re := range c.opts.ExcludePatterns {
+		if re.MatchString(u.Path) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,305 @@
+package crawler
+
+import (
+	"encoding/json"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+var (
+	// splitAtRegex matches an "@" surrounded by whitespace between address characters,
+	// as produced by markup like <span>john</span> @ <span>example.com</span>
+	splitAtRegex = regexp.MustCompile(`([a-zA-Z0-9._%+-])\s*@\s*([a-zA-Z0-9-])`)
+	// splitDomainRegex matches a domain whose labels were separated by whitespace
+	// before a dot; a dot followed by whitespace is left alone since it usually ends a sentence
+	splitDomainRegex = regexp.MustCompile(`@[a-zA-Z0-9-]+(?:(?:\.|\s+\.\s*)[a-zA-Z0-9-]+)+`)
+	whitespaceRegex  = regexp.MustCompile(`\s+`)
+
+	// bracketedAtRegex matches addresses written with a bracketed "at", such as
+	// john [at] example [dot] com, john(at)example.com or john {at} example.com
+	bracketedAtRegex = regexp.MustCompile(`(?i)[a-z0-9._%+-]+\s*` + atSep + `\s*[a-z0-9-]+(?:(?:` + dotSep + `|\.)[a-z0-9-]+)+`)
+	// spelledAtRegex matches a bare " at " only when the domain also spells out
+	// its dots (john at example dot com), so prose like "visit us at example.com"
+	// or "chat" is left alone
+	spelledAtRegex = regexp.MustCompile(`(?i)[a-z0-9._%+-]+\s+at\s+[a-z0-9-]+(?:` + dotSep + `[a-z0-9-]+)+`)
+	atSepRegex     = regexp.MustCompile(`(?i)\s*` + atSep + `\s*|\s+at\s+`)
+	dotSepRegex    = regexp.MustCompile(`(?i)` + dotSep)
+
+	localPartRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+$`)
+	domainRegex    = regexp.MustCompile(`^[a-zA-Z0-9-]+(?:\.[a-zA-Z0-9-]+)*$`)
+	fullEmailRegex = regexp.MustCompile(`^` + emailRegex.String() + `$`)
+	// jsStringVarRegex matches string variables like: var user = "john";
+	jsStringVarRegex = regexp.MustCompile(`(?:var|let|const)\s+([A-Za-z_$][\w$]*)\s*=\s*(?:"([^"\n]*)"|'([^'\n]*)')`)
+	// jsConcatRegex matches an address assembled around a literal "@", e.g.
+	// user + "@" + domain or "john" + '@' + "example.com"
+	jsConcatRegex = regexp.MustCompile(`([A-Za-z_$][\w$]*|"[^"\n]*"|'[^'\n]*')\s*\+\s*(?:"@"|'@')\s*\+\s*([A-Za-z_$][\w$]*|"[^"\n]*"|'[^'\n]*')`)
+)
+
+// Obfuscated separators: [at], (at), {at} and [dot], (dot), {dot} or " dot ".
+const (
+	atSep  = `[\[({]\s*at\s*[\])}]`
+	dotSep = `\s*[\[({]\s*dot\s*[\])}]\s*|\s+dot\s+`
+)
+
+// Attribute names that hold the two halves of an address assembled client-side.
+var (
+	dataLocalAttrs  = []string{"data-email", "data-user", "data-username", "data-name", "data-local", "data-mailbox", "data-account"}
+	dataDomainAttrs = []string{"data-domain", "data-host"}
+)
+
+const (
+	// maxLabelLength bounds how long a label preceding an email may be
+	maxLabelLength = 40
+	// maxLabelContainerText skips large containers whose text is unlikely to
+	// pair a label with a single address
+	maxLabelContainerText = 500
+)
+
+// joinSplitEmails removes the inter-element whitespace that breaks addresses
+// rendered across several elements. It only operates on text used for email
+// scanning.
+func joinSplitEmails(text string) string {
+	text = splitAtRegex.ReplaceAllString(text, "$1@$2")
+	return splitDomainRegex.ReplaceAllStringFunc(text, func(match string) string {
+		return whitespaceRegex.ReplaceAllString(match, "")
+	})
+}
+
+// deobfuscateEmails rewrites addresses spelled out with "at" and "dot" words
+// back into regular addresses so emailRegex can find them.
+func deobfuscateEmails(text string) string {
+	restore := func(match string) string {
+		match = atSepRegex.ReplaceAllString(match, "@")
+		return dotSepRegex.ReplaceAllString(match, ".")
+	}
+	text = bracketedAtRegex.ReplaceAllStringFunc(text, restore)
+	return spelledAtRegex.ReplaceAllStringFunc(text, restore)
+}
+
+// extractEmails returns every address found in text.
+func (c *Crawler) extractEmails(text string) []string {
+	// The HTML parser decodes entities once; double-encoded ones such as
+	// &amp;#64; still reach the text as &#64;
+	if strings.Contains(text, "&") {
+		text = html.UnescapeString(text)
+	}
+	if c.opts.JoinSplitEmails {
+		text = joinSplitEmails(text)
+	}
+	text = deobfuscateEmails(text)
+	return emailRegex.FindAllString(strings.TrimSpace(text), -1)
+}
+
+// blocked reports whether the lowercased email is on the placeholder blocklist.
+func (c *Crawler) blocked(email string) bool {
+	_, domain, _ := strings.Cut(email, "@")
+	for _, entry := range c.opts.EmailBlocklist {
+		entry = strings.ToLower(entry)
+		if strings.Contains(entry, "@") {
+			if email == entry {
+				return true
+			}
+		} else if domain == entry || strings.HasSuffix(domain, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// extractMailtoEmails returns the addresses of mailto: links, which often sit
+// behind an icon with no visible text. Query parameters (?subject=...) are
+// dropped and several comma-separated recipients are all kept.
+func extractMailtoEmails(doc *goquery.Document) []string {
+	var found []string
+	doc.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
+		href := strings.TrimSpace(s.AttrOr("href", ""))
+		if len(href) < len("mailto:") || !strings.EqualFold(href[:len("mailto:")], "mailto:") {
+			return
+		}
+		recipients, _, _ := strings.Cut(href[len("mailto:"):], "?")
+		if unescaped, err := url.PathUnescape(recipients); err == nil {
+			recipients = unescaped
+		}
+		for _, recipient := range strings.Split(recipients, ",") {
+			if email := strings.TrimSpace(recipient); fullEmailRegex.MatchString(email) {
+				found = append(found, email)
+			}
+		}
+	})
+	return found
+}
+
+// extractJSONLDEmails returns the "email" values of schema.org structured data
+// (Organization, ContactPoint, Person, ...) in ld+json scripts, at any nesting
+// level including arrays and @graph. A malformed block is skipped on its own.
+func extractJSONLDEmails(doc *goquery.Document) []string {
+	var found []string
+	doc.Find(`script[type="application/ld+json"]`).Each(func(_ int, s *goquery.Selection) {
+		var data interface{}
+		if err := json.Unmarshal([]byte(s.Text()), &data); err != nil {
+			return
+		}
+		found = appendJSONLDEmails(found, data)
+	})
+	return found
+}
+
+// appendJSONLDEmails walks a decoded JSON value collecting email fields.
+func appendJSONLDEmails(found []string, value interface{}) []string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if key != "email" {
+				found = appendJSONLDEmails(found, field)
+				continue
+			}
+			emails, ok := field.([]interface{})
+			if !ok {
+				emails = []interface{}{field}
+			}
+			for _, e := range emails {
+				if email, ok := e.(string); ok {
+					email = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(email, "mailto:"), "MAILTO:"))
+					if fullEmailRegex.MatchString(email) {
+						found = append(found, email)
+					}
+				}
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			found = appendJSONLDEmails(found, item)
+		}
+	}
+	return found
+}
+
+// extractObfuscatedEmails reconstructs addresses that only exist once assembled
+// by JavaScript: split data-* attribute pairs (data-user="john"
+// data-domain="example.com") and string concatenation around a literal "@" in
+// inline scripts. Both halves must look like address parts, so arbitrary
+// attributes and expressions don't produce false positives.
+func (c *Crawler) extractObfuscatedEmails(doc *goquery.Document) []string {
+	var found []string
+
+	doc.Find("*").Each(func(_ int, s *goquery.Selection) {
+		local := firstAttr(s, dataLocalAttrs)
+		if local == "" {
+			return
+		}
+		if fullEmailRegex.MatchString(local) {
+			found = append(found, local)
+			return
+		}
+		domain := firstAttr(s, dataDomainAttrs)
+		if tld := strings.TrimPrefix(s.AttrOr("data-tld", ""), "."); domain != "" && tld != "" {
+			domain += "." + tld
+		}
+		if email, ok := assembleEmail(local, domain); ok {
+			found = append(found, email)
+		}
+	})
+
+	doc.Find("script").Each(func(_ int, s *goquery.Selection) {
+		script := s.Text()
+		if !strings.Contains(script, "@") {
+			return
+		}
+
+		vars := make(map[string]string)
+		for _, m := range jsStringVarRegex.FindAllStringSubmatch(script, -1) {
+			vars[m[1]] = m[2] + m[3]
+		}
+		for _, m := range jsConcatRegex.FindAllStringSubmatch(script, -1) {
+			if email, ok := assembleEmail(jsOperand(m[1], vars), jsOperand(m[2], vars)); ok {
+				found = append(found, email)
+			}
+		}
+	})
+
+	return found
+}
+
+// firstAttr returns the first non-empty value among names.
+func firstAttr(s *goquery.Selection, names []string) string {
+	for _, name := range names {
+		if value := strings.TrimSpace(s.AttrOr(name, "")); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// jsOperand resolves a concatenation operand that is either a quoted literal or
+// a variable assigned a string literal in the same script.
+func jsOperand(operand string, vars map[string]string) string {
+	if strings.HasPrefix(operand, `"`) || strings.HasPrefix(operand, "'") {
+		return operand[1 : len(operand)-1]
+	}
+	return vars[operand]
+}
+
+// assembleEmail joins a local part and domain if both are well-formed.
+func assembleEmail(local, domain string) (string, bool) {
+	if !localPartRegex.MatchString(local) || !domainRegex.MatchString(domain) {
+		return "", false
+	}
+	email := local + "@" + domain
+	return email, fullEmailRegex.MatchString(email)
+}
+
+// extractLabels records the short label ("Sales:", "Press:") that precedes an
+// email inside the same element. The innermost labelled element wins within a
+// page, and a label found on an earlier page is kept.
+func (c *Crawler) extractLabels(doc *goquery.Document) {
+	pageLabels := make(map[string]string)
+
+	doc.Find("body *").Each(func(_ int, s *goquery.Selection) {
+		text := s.Text()
+		if len(text) > maxLabelContainerText || !strings.Contains(text, "@") {
+			return
+		}
+
+		prevEnd := 0
+		for _, loc := range emailRegex.FindAllStringIndex(text, -1) {
+			if label := labelBefore(text[prevEnd:loc[0]]); label != "" {
+				pageLabels[strings.ToLower(text[loc[0]:loc[1]])] = label
+			}
+			prevEnd = loc[1]
+		}
+	})
+
+	for email, label := range pageLabels {
+		if _, exists := c.labels[email]; !exists {
+			c.labels[email] = label
+		}
+	}
+}
+
+// labelBefore returns the label ending in a colon on the last line of text,
+// or "" if the text doesn't end with a short label.
+func labelBefore(text string) string {
+	text = strings.TrimRightFunc(text, unicode.IsSpace)
+	if !strings.HasSuffix(text, ":") {
+		return ""
+	}
+	text = strings.TrimSuffix(text, ":")
+	if idx := strings.LastIndexAny(text, "\n.;|"); idx >= 0 {
+		text = text[idx+1:]
+	}
+	label := strings.TrimSpace(text)
+
+	if label == "" || utf8.RuneCountInString(label) > maxLabelLength || strings.Contains(label, "@") {
+		return ""
+	}
+	if len(strings.Fields(label)) > 4 || !strings.ContainsFunc(label, unicode.IsLetter) {
+		return ""
+	}
+	return label
+}
@@ -0,0 +1,89 @@
+package crawler
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// parseHTML parses page for the extraction helpers.
+func parseHTML(t *testing.T, page string) *goquery.Document {
+	t.Helper()
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		t.Fatalf("parsing %q: %v", page, err)
+	}
+	return doc
+}
+
+func TestExtractObfuscatedEmails(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		want []string
+	}{
+		{"split data attributes", `<a class="email" data-user="john" data-domain="example.com"></a>`, []string{"john@example.com"}},
+		{"separate tld", `<span data-name="sales" data-host="example" data-tld=".co.uk"></span>`, []string{"sales@example.co.uk"}},
+		{"whole address", `<span data-email="Info@Example.com"></span>`, []string{"Info@Example.com"}},
+		{"script variables", `<script>var user = "press"; var host = 'example.com'; el.href = "mailto:" + user + "@" + host;</script>`, []string{"press@example.com"}},
+		{"script literals", `<script>document.write("jobs" + '@' + "example.org")</script>`, []string{"jobs@example.org"}},
+		{"local part only", `<span data-user="john"></span>`, nil},
+		{"not an address part", `<div data-name="Main menu" data-domain="example.com"></div>`, nil},
+		{"unrelated attributes", `<div data-id="42" data-host="cdn"></div>`, nil},
+		{"unknown script operand", `<script>x = a + "@" + b;</script>`, nil},
+	}
+	c := NewWithOptions(Options{ExtractObfuscatedEmails: true})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := c.extractObfuscatedEmails(parseHTML(t, "<html><body>"+tt.page+"</body></html>"))
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extractObfuscatedEmails = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeobfuscateEmails(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"john [at] example [dot] com", []string{"john@example.com"}},
+		{"john(at)example(dot)com", []string{"john@example.com"}},
+		{"john {at} example {dot} co {dot} uk", []string{"john@example.co.uk"}},
+		{"john [AT] example.com", []string{"john@example.com"}},
+		{"write to john at example dot com today", []string{"john@example.com"}},
+		{"visit us at example.com", nil},
+		{"meet us at the office", nil},
+		{"the dot product at noon", nil},
+	}
+	c := New(0)
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := c.extractEmails(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extractEmails(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntityEncodedEmailsAreExtracted(t *testing.T) {
+	site := newTestSite(t, map[string]string{
+		"/": `<html><body>
+			<p>Sales: sales&#64;example.com</p>
+			<p>Press: press&#x40;example&#46;com</p>
+			<p>Jobs: jobs&commat;example.com</p>
+			<p>Legal: legal&amp;#64;example.com</p>
+		</body></html>`,
+	})
+
+	emails := NewWithOptions(testOptions(0)).Crawl(site)
+	want := map[string]bool{"jobs@example.com": true, "legal@example.com": true, "press@example.com": true, "sales@example.com": true}
+	if !reflect.DeepEqual(emails, want) {
+		t.Errorf("emails = %v, want %v", emails, want)
+	}
+}
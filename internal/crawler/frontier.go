@@ -0,0 +1,72 @@
+package crawler
+
+import (
+	"net/url"
+	"sort"
+)
+
+// FrontierEntry is a discovered URL waiting to be crawled at Depth, measured
+// in the crawler's internal half levels.
+type FrontierEntry struct {
+	URL   string `json:"url"`
+	Depth int    `json:"depth"`
+}
+
+// Frontier is a snapshot of an in-progress crawl: what is still pending, what
+// was already fetched and the emails found so far. Passing it back through
+// Options.Resume continues the crawl without refetching visited pages.
+type Frontier struct {
+	Pending []FrontierEntry `json:"pending"`
+	Visited []string        `json:"visited"`
+	Emails  []string        `json:"emails"`
+}
+
+// snapshot captures the crawler's current frontier.
+func (c *Crawler) snapshot() *Frontier {
+	f := &Frontier{
+		Pending: make([]FrontierEntry, 0, len(c.pending)),
+		Visited: make([]string, 0, len(c.visited)),
+		Emails:  make([]string, 0, len(c.emails)),
+	}
+	for u, depth := range c.pending {
+		f.Pending = append(f.Pending, FrontierEntry{URL: u, Depth: depth})
+	}
+	for u := range c.visited {
+		f.Visited = append(f.Visited, u)
+	}
+	for email := range c.emails {
+		f.Emails = append(f.Emails, email)
+	}
+	// Shallow pages first so a resumed crawl keeps the original priorities
+	sort.Slice(f.Pending, func(i, j int) bool { return f.Pending[i].Depth < f.Pending[j].Depth })
+	return f
+}
+
+// checkpoint hands the current frontier to Options.Checkpoint, if set.
+func (c *Crawler) checkpoint() {
+	if c.opts.Checkpoint != nil {
+		c.opts.Checkpoint(c.snapshot())
+	}
+}
+
+// resume restores a saved frontier and crawls its pending URLs.
+func (c *Crawler) resume(f *Frontier) {
+	for _, u := range f.Visited {
+		c.visited[u] = true
+	}
+	for _, email := range f.Emails {
+		c.emails[email] = true
+	}
+	c.log.Info("resuming crawl", "visited", len(f.Visited), "pending", len(f.Pending))
+
+	targets := make([]target, 0, len(f.Pending))
+	for _, entry := range f.Pending {
+		u, err := url.Parse(entry.URL)
+		if err != nil {
+			continue
+		}
+		c.pending[entry.URL] = entry.Depth
+		targets = append(targets, target{u: u, depth: entry.Depth})
+	}
+	c.crawlFrom(targets)
+}
@@ -0,0 +1,87 @@
+package crawler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// hostLimiterIdleTTL is how long a host may go without requests before its
+// limiter is dropped from the registry.
+const hostLimiterIdleTTL = 10 * time.Minute
+
+// HostLimiter caps the request rate to each host across every crawler sharing it.
+type HostLimiter struct {
+	mu        sync.Mutex
+	rps       rate.Limit
+	hosts     map[string]*hostEntry
+	lastSweep time.Time
+}
+
+type hostEntry struct {
+	limiter  *rate.Limiter
+	lastUsed time.Time
+}
+
+// NewHostLimiter returns a limiter allowing rps requests per second to each host.
+func NewHostLimiter(rps float64) *HostLimiter {
+	return &HostLimiter{
+		rps:       rate.Limit(rps),
+		hosts:     make(map[string]*hostEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+var (
+	sharedHostLimiter     *HostLimiter
+	sharedHostLimiterOnce sync.Once
+)
+
+// SharedHostLimiter returns the process-wide host limiter, created on first use
+// with the given rate. It returns nil when rps is not positive.
+func SharedHostLimiter(rps float64) *HostLimiter {
+	if rps <= 0 {
+		return nil
+	}
+	sharedHostLimiterOnce.Do(func() {
+		sharedHostLimiter = NewHostLimiter(rps)
+	})
+	return sharedHostLimiter
+}
+
+// Wait blocks until a request to host is allowed or ctx is done.
+func (l *HostLimiter) Wait(ctx context.Context, host string) error {
+	err := l.limiterFor(host).Wait(ctx)
+	if _, hasDeadline := ctx.Deadline(); err != nil && hasDeadline && ctx.Err() == nil {
+		// rate.Limiter gives up early when the wait would outlast ctx's
+		// deadline; wait it out so callers see the crawl ran out of time
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return err
+}
+
+func (l *HostLimiter) limiterFor(host string) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) > hostLimiterIdleTTL {
+		for h, entry := range l.hosts {
+			if now.Sub(entry.lastUsed) > hostLimiterIdleTTL {
+				delete(l.hosts, h)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	entry, ok := l.hosts[host]
+	if !ok {
+		entry = &hostEntry{limiter: rate.NewLimiter(l.rps, 1)}
+		l.hosts[host] = entry
+	}
+	entry.lastUsed = now
+	return entry.limiter
+}
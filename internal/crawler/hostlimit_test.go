@@ -0,0 +1,45 @@
+package crawler
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHostLimiterKeysBucketsByHost(t *testing.T) {
+	limiter := NewHostLimiter(2)
+	ctx := context.Background()
+
+	start := time.Now()
+	for _, host := range []string{"a.example.com", "b.example.com", "c.example.com"} {
+		if err := limiter.Wait(ctx, host); err != nil {
+			t.Fatalf("Wait(%s): %v", host, err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("first requests to three hosts took %s, want no wait", elapsed)
+	}
+
+	start = time.Now()
+	if err := limiter.Wait(ctx, "a.example.com"); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("second request to a host waited %s, want about 500ms at 2 rps", elapsed)
+	}
+}
+
+func TestHostLimiterWaitsOutADeadlineItCannotMeet(t *testing.T) {
+	limiter := NewHostLimiter(0.5)
+	limiter.Wait(context.Background(), "example.com")
+
+	// The next token is two seconds away, past the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx, "example.com"); err != context.DeadlineExceeded {
+		t.Errorf("Wait = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if ctx.Err() == nil {
+		t.Error("Wait returned before the deadline passed")
+	}
+}
@@ -0,0 +1,34 @@
+package crawler
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ParseLogLevel parses CRAWLER_LOG_LEVEL: debug, info, warn or error.
+func ParseLogLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.TrimSpace(level))); err != nil {
+		return 0, fmt.Errorf("invalid log level %q (use debug, info, warn or error)", level)
+	}
+	return l, nil
+}
+
+var (
+	sharedLogger     *slog.Logger
+	sharedLoggerOnce sync.Once
+)
+
+// SharedLogger returns the process-wide crawl logger, created on first use.
+// It writes key=value lines to stderr at the given level; startup rejects
+// invalid levels with ParseLogLevel, so an error here falls back to info.
+func SharedLogger(level string) *slog.Logger {
+	sharedLoggerOnce.Do(func() {
+		l, _ := ParseLogLevel(level)
+		sharedLogger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: l}))
+	})
+	return sharedLogger
+}
@@ -0,0 +1,82 @@
+package crawler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"email-crawler/internal/ssrf"
+)
+
+// ParseProxyURL validates a proxy URL. An empty string yields nil, meaning the
+// proxy comes from the environment (HTTP_PROXY, HTTPS_PROXY, NO_PROXY).
+func ParseProxyURL(raw string) (*url.URL, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	proxy, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL %q: %w", raw, err)
+	}
+	switch proxy.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q (use http, https or socks5)", proxy.Scheme)
+	}
+	if proxy.Host == "" {
+		return nil, fmt.Errorf("proxy URL %q has no host", raw)
+	}
+	return proxy, nil
+}
+
+// configuredProxy parses the configured proxy URL. Startup rejects invalid
+// values with ParseProxyURL, so an error here falls back to the environment.
+func configuredProxy(raw string) *url.URL {
+	proxy, _ := ParseProxyURL(raw)
+	return proxy
+}
+
+var proxyTransports sync.Map // proxy URL string -> *http.Transport
+
+// guardedTransport connects directly, refusing private addresses, for crawls
+// without Options.AllowPrivateTargets or a configured proxy.
+var guardedTransport = ssrf.NewTransport()
+
+// transportFor returns a transport sending requests through proxy, shared by
+// every crawler using the same proxy so connections are reused. A nil proxy
+// uses http.DefaultTransport, which honors the proxy environment variables,
+// only when allowPrivate; otherwise it connects directly through
+// ssrf.Control, which can only vet the address it dials. A configured proxy
+// may itself be private, so its targets are checked by checkTarget instead.
+func transportFor(proxy *url.URL, allowPrivate bool) http.RoundTripper {
+	if proxy == nil {
+		if !allowPrivate {
+			return guardedTransport
+		}
+		return http.DefaultTransport
+	}
+	if t, ok := proxyTransports.Load(proxy.String()); ok {
+		return t.(*http.Transport)
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = http.ProxyURL(proxy)
+	actual, _ := proxyTransports.LoadOrStore(proxy.String(), t)
+	return actual.(*http.Transport)
+}
+
+// checkTarget refuses req when it goes through a proxy to a host resolving to
+// a private address. Hosts that don't resolve here are left to the proxy.
+// Without Options.AllowPrivateTargets, only a configured proxy is ever used
+// and every other connection is checked as it is dialed, after DNS
+// resolution, so it needs no lookup here.
+func (c *Crawler) checkTarget(req *http.Request) error {
+	if c.opts.AllowPrivateTargets || c.opts.Proxy == nil {
+		return nil
+	}
+	if err := ssrf.CheckHost(req.Context(), req.URL.Hostname()); errors.Is(err, ssrf.ErrPrivateTarget) {
+		return err
+	}
+	return nil
+}
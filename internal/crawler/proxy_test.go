@@ -0,0 +1,56 @@
+package crawler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"email-crawler/internal/ssrf"
+)
+
+// newTestProxy returns a forward proxy that answers every request itself with
+// a page holding one email, counting the requests it gets.
+func newTestProxy(t *testing.T) (*url.URL, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body>info@proxied.test</body></html>`))
+	}))
+	t.Cleanup(proxy.Close)
+	proxyURL, _ := url.Parse(proxy.URL)
+	return proxyURL, &requests
+}
+
+func TestProxiedCrawlRefusesPrivateTargets(t *testing.T) {
+	proxyURL, requests := newTestProxy(t)
+
+	c := NewWithOptions(Options{MaxDepth: 0, Proxy: proxyURL})
+	target, _ := url.Parse("http://127.0.0.1:1/")
+	c.Crawl(target)
+	if err := c.StartPageError(); !errors.Is(err, ssrf.ErrPrivateTarget) {
+		t.Fatalf("StartPageError() = %v, want ErrPrivateTarget", err)
+	}
+	if requests.Load() != 0 {
+		t.Fatalf("proxy got %d requests for a private target, want 0", requests.Load())
+	}
+
+	// The proxy itself may be private; public targets go through it
+	c = NewWithOptions(Options{MaxDepth: 0, Proxy: proxyURL})
+	target, _ = url.Parse("http://93.184.216.34/")
+	emails := c.Crawl(target)
+	if !emails["info@proxied.test"] {
+		t.Fatalf("Crawl through proxy = %v, want info@proxied.test", emails)
+	}
+}
+
+func TestTransportForIgnoresProxyEnvironmentWhenGuarded(t *testing.T) {
+	transport, ok := transportFor(nil, false).(*http.Transport)
+	if !ok || transport.Proxy != nil {
+		t.Fatal("guarded transport honours HTTP(S)_PROXY, so ssrf.Control would vet the proxy instead of the target")
+	}
+}
@@ -0,0 +1,102 @@
+package crawler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// soft404Phrases are matched against a page's title and main heading.
+var soft404Phrases = []string{
+	"not found", "page not found", "doesn't exist", "does not exist",
+	"página no encontrada", "no encontrada", "seite nicht gefunden", "nicht gefunden",
+	"page introuvable", "pagina non trovata", "página não encontrada",
+}
+
+// soft404CodeRegex matches "404" only as an error code ("Error 404", "404
+// error"), so "Suite 404" or a 404 phone prefix don't mark a page as missing.
+// "404 Not Found" is already covered by soft404Phrases.
+var soft404CodeRegex = regexp.MustCompile(`(?i)\berror\s*:?\s*404\b|\b404\s*[-:|]?\s*error\b`)
+
+// soft404Fingerprint describes the page a site serves for a URL that can't exist.
+type soft404Fingerprint struct {
+	title   string
+	textLen int
+}
+
+// probeSoft404 fetches a random path that can't exist on the site. If the server
+// answers 200, the response is remembered as the site's soft-404 fingerprint.
+func (c *Crawler) probeSoft404() {
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return
+	}
+	probeURL := c.baseURL.ResolveReference(&url.URL{Path: "/gurl-soft404-" + hex.EncodeToString(token)})
+
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, probeURL.String(), nil)
+	if err != nil {
+		return
+	}
+	resp, err := c.fetch(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return
+	}
+
+	// Bounded like visit, so the probe can't be answered with an endless page
+	var body io.Reader = resp.Body
+	if c.opts.MaxBodyBytes > 0 {
+		body = &io.LimitedReader{R: resp.Body, N: c.opts.MaxBodyBytes}
+	}
+	doc, err := goquery.NewDocumentFromReader(body)
+	if err != nil {
+		return
+	}
+
+	c.soft404 = &soft404Fingerprint{
+		title:   pageTitle(doc),
+		textLen: len(strings.TrimSpace(doc.Find("body").Text())),
+	}
+	c.log.Info("site serves soft 404s, enabling fingerprint matching", "title", c.soft404.title)
+}
+
+// isSoft404 reports whether a 200 page is really an error page, either by its
+// title/heading wording or by matching the site's soft-404 fingerprint.
+func (c *Crawler) isSoft404(doc *goquery.Document) bool {
+	title := pageTitle(doc)
+	heading := strings.ToLower(strings.TrimSpace(doc.Find("h1").First().Text()))
+	for _, phrase := range soft404Phrases {
+		if strings.Contains(strings.ToLower(title), phrase) || strings.Contains(heading, phrase) {
+			return true
+		}
+	}
+	if soft404CodeRegex.MatchString(title) || soft404CodeRegex.MatchString(heading) {
+		return true
+	}
+
+	if c.soft404 == nil || c.soft404.title == "" || title != c.soft404.title {
+		return false
+	}
+
+	// Error pages often echo the requested path, so allow a small length drift
+	textLen := len(strings.TrimSpace(doc.Find("body").Text()))
+	diff := textLen - c.soft404.textLen
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff*10 <= c.soft404.textLen
+}
+
+func pageTitle(doc *goquery.Document) string {
+	return strings.TrimSpace(doc.Find("title").First().Text())
+}
@@ -0,0 +1,227 @@
+package emailcheck
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"email-crawler/internal/config"
+)
+
+// mxTimeout bounds each domain's MX lookup.
+const mxTimeout = 3 * time.Second
+
+// Tags annotates an email with quality heuristics.
+type Tags struct {
+	ValidSyntax  bool  `json:"valid_syntax"`
+	MXOK         *bool `json:"mx_ok,omitempty"` // nil when MX checks are disabled or syntax is invalid
+	IsRole       bool  `json:"is_role"`
+	IsDisposable bool  `json:"is_disposable"`
+}
+
+// Filter selects which tagged emails to drop.
+type Filter struct {
+	ExcludeInvalid    bool
+	ExcludeRole       bool
+	ExcludeDisposable bool
+}
+
+// Active reports whether the filter drops anything.
+func (f Filter) Active() bool {
+	return f.ExcludeInvalid || f.ExcludeRole || f.ExcludeDisposable
+}
+
+// Keep reports whether an email with tags t passes the filter.
+func (f Filter) Keep(t Tags) bool {
+	if f.ExcludeInvalid && (!t.ValidSyntax || (t.MXOK != nil && !*t.MXOK)) {
+		return false
+	}
+	if f.ExcludeRole && t.IsRole {
+		return false
+	}
+	if f.ExcludeDisposable && t.IsDisposable {
+		return false
+	}
+	return true
+}
+
+// defaultDisposableDomains are common throwaway mailbox providers.
+var defaultDisposableDomains = []string{
+	"mailinator.com", "guerrillamail.com", "guerrillamail.net", "sharklasers.com",
+	"10minutemail.com", "tempmail.com", "temp-mail.org", "yopmail.com",
+	"trashmail.com", "getnada.com", "dispostable.com", "maildrop.cc",
+	"throwawaymail.com", "fakeinbox.com", "mailnesia.com", "mintemail.com",
+}
+
+// roleLocalParts are mailboxes that belong to a function rather than a person.
+var roleLocalParts = map[string]bool{
+	"info": true, "contact": true, "hello": true, "office": true, "team": true,
+	"sales": true, "support": true, "help": true, "admin": true, "webmaster": true,
+	"postmaster": true, "hostmaster": true, "abuse": true, "noreply": true, "no-reply": true,
+	"marketing": true, "billing": true, "accounts": true, "hr": true, "jobs": true,
+	"careers": true, "press": true, "media": true, "enquiries": true, "inquiries": true,
+	"contacto": true, "ventas": true, "soporte": true, "administracion": true,
+	"kontakt": true, "vertrieb": true, "contatto": true, "contato": true,
+}
+
+// falsePositiveTLDs are file extensions the email regex mistakes for TLDs, as
+// in asset names like logo@2x.png.
+var falsePositiveTLDs = map[string]bool{
+	"png": true, "jpg": true, "jpeg": true, "gif": true, "svg": true,
+	"webp": true, "ico": true, "css": true, "js": true,
+}
+
+// Checker tags emails with syntax, MX, role and disposable-domain heuristics.
+type Checker struct {
+	disposable map[string]bool
+	checkMX    bool
+	lookupMX   func(ctx context.Context, domain string) ([]*net.MX, error)
+	lookupHost func(ctx context.Context, host string) ([]string, error)
+}
+
+// NewChecker builds a Checker from the configured disposable domains, which
+// extend the built-in list.
+func NewChecker(cfg *config.Config) *Checker {
+	disposable := make(map[string]bool)
+	for _, domain := range defaultDisposableDomains {
+		disposable[domain] = true
+	}
+	for _, domain := range cfg.EmailDisposableDomains {
+		disposable[strings.ToLower(strings.TrimSpace(domain))] = true
+	}
+	return &Checker{
+		disposable: disposable,
+		checkMX:    cfg.EmailCheckMX,
+		lookupMX:   net.DefaultResolver.LookupMX,
+		lookupHost: net.DefaultResolver.LookupHost,
+	}
+}
+
+// Tag annotates each email. MX lookups are made once per domain.
+func (c *Checker) Tag(ctx context.Context, emails []string) map[string]Tags {
+	tags := make(map[string]Tags, len(emails))
+	mxByDomain := make(map[string]bool)
+	for _, email := range emails {
+		local, domain := split(email)
+		t := Tags{
+			ValidSyntax:  validSyntax(local, domain),
+			IsRole:       roleLocalParts[local],
+			IsDisposable: c.isDisposable(domain),
+		}
+		if c.checkMX && t.ValidSyntax {
+			ok, seen := mxByDomain[domain]
+			if !seen {
+				ok = c.hasMX(ctx, domain)
+				mxByDomain[domain] = ok
+			}
+			t.MXOK = &ok
+		}
+		tags[email] = t
+	}
+	return tags
+}
+
+// DropUndeliverable removes emails whose domain can't receive mail, with one
+// lookup per domain. Domains whose lookup fails are kept.
+func (c *Checker) DropUndeliverable(ctx context.Context, emails []string) []string {
+	deliverable := make(map[string]bool)
+	kept := make([]string, 0, len(emails))
+	for _, email := range emails {
+		_, domain := split(email)
+		ok, seen := deliverable[domain]
+		if !seen {
+			ok = c.hasMX(ctx, domain)
+			deliverable[domain] = ok
+			if !ok {
+				log.Printf("Dropping emails at %s: no MX or address records", domain)
+			}
+		}
+		if ok {
+			kept = append(kept, email)
+		}
+	}
+	return kept
+}
+
+// isDisposable matches domain or any parent domain against the list.
+func (c *Checker) isDisposable(domain string) bool {
+	for domain != "" {
+		if c.disposable[domain] {
+			return true
+		}
+		_, parent, found := strings.Cut(domain, ".")
+		if !found {
+			break
+		}
+		domain = parent
+	}
+	return false
+}
+
+// hasMX reports whether domain can receive mail: it has MX records or, failing
+// that, an address record (the implicit MX of RFC 5321). Lookup failures other
+// than "not found" are not held against the domain.
+func (c *Checker) hasMX(ctx context.Context, domain string) bool {
+	ctx, cancel := context.WithTimeout(ctx, mxTimeout)
+	defer cancel()
+
+	records, err := c.lookupMX(ctx, domain)
+	if err == nil && len(records) > 0 {
+		return !(len(records) == 1 && records[0].Host == ".") // null MX (RFC 7505)
+	}
+	if err != nil && !isNotFound(err) {
+		return true
+	}
+	if _, err := c.lookupHost(ctx, domain); err != nil {
+		return !isNotFound(err)
+	}
+	return true
+}
+
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// split returns the lowercased local part and domain of email.
+func split(email string) (string, string) {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return strings.ToLower(email), ""
+	}
+	return strings.ToLower(email[:at]), strings.ToLower(email[at+1:])
+}
+
+// validSyntax applies the rules the extraction regex doesn't: length limits,
+// dot placement, hyphen placement in labels and file-extension pseudo-TLDs.
+func validSyntax(local, domain string) bool {
+	if local == "" || len(local) > 64 || domain == "" || len(domain) > 253 {
+		return false
+	}
+	if strings.HasPrefix(local, ".") || strings.HasSuffix(local, ".") || strings.Contains(local, "..") {
+		return false
+	}
+
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return false
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > 63 || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return false
+		}
+	}
+	tld := labels[len(labels)-1]
+	if len(tld) < 2 || falsePositiveTLDs[tld] {
+		return false
+	}
+	for _, r := range tld {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,111 @@
+package emailcheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"email-crawler/internal/config"
+)
+
+// newTestChecker returns a Checker whose DNS answers come from mx: domains
+// mapped to true have mail servers, every other domain doesn't exist.
+func newTestChecker(checkMX bool, disposable []string, mx map[string]bool) *Checker {
+	cfg := config.Load()
+	cfg.EmailCheckMX = checkMX
+	cfg.EmailDisposableDomains = disposable
+	c := NewChecker(cfg)
+	notFound := func(host string) error {
+		return &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	c.lookupMX = func(_ context.Context, domain string) ([]*net.MX, error) {
+		if mx[domain] {
+			return []*net.MX{{Host: "mail." + domain, Pref: 10}}, nil
+		}
+		return nil, notFound(domain)
+	}
+	c.lookupHost = func(_ context.Context, host string) ([]string, error) {
+		return nil, notFound(host)
+	}
+	return c
+}
+
+func TestTag(t *testing.T) {
+	c := newTestChecker(true, []string{" Burner.Test "}, map[string]bool{"acme.test": true, "mailinator.com": true})
+	yes, no := true, false
+
+	tests := []struct {
+		email string
+		want  Tags
+	}{
+		{"jane.doe@acme.test", Tags{ValidSyntax: true, MXOK: &yes}},
+		{"Sales@Acme.test", Tags{ValidSyntax: true, MXOK: &yes, IsRole: true}},
+		{"someone@mailinator.com", Tags{ValidSyntax: true, MXOK: &yes, IsDisposable: true}},
+		{"someone@eu.burner.test", Tags{ValidSyntax: true, MXOK: &no, IsDisposable: true}},
+		{"jane@gone.test", Tags{ValidSyntax: true, MXOK: &no}},
+		{"logo@2x.png", Tags{ValidSyntax: false}},
+		{"jane..doe@acme.test", Tags{ValidSyntax: false}},
+	}
+	emails := make([]string, 0, len(tests))
+	for _, tt := range tests {
+		emails = append(emails, tt.email)
+	}
+	tags := c.Tag(context.Background(), emails)
+	for _, tt := range tests {
+		got := tags[tt.email]
+		if got.ValidSyntax != tt.want.ValidSyntax || got.IsRole != tt.want.IsRole || got.IsDisposable != tt.want.IsDisposable ||
+			(got.MXOK == nil) != (tt.want.MXOK == nil) || (got.MXOK != nil && *got.MXOK != *tt.want.MXOK) {
+			t.Errorf("Tag(%s) = %s, want %s", tt.email, describe(got), describe(tt.want))
+		}
+	}
+
+	if tags := newTestChecker(false, nil, nil).Tag(context.Background(), []string{"jane.doe@acme.test"}); tags["jane.doe@acme.test"].MXOK != nil {
+		t.Error("mx_ok was set with MX checks disabled")
+	}
+}
+
+// describe formats t with mx_ok dereferenced.
+func describe(t Tags) string {
+	mx := "unchecked"
+	if t.MXOK != nil {
+		mx = fmt.Sprint(*t.MXOK)
+	}
+	return fmt.Sprintf("{valid_syntax %v, mx_ok %s, is_role %v, is_disposable %v}", t.ValidSyntax, mx, t.IsRole, t.IsDisposable)
+}
+
+func TestFilterKeep(t *testing.T) {
+	yes, no := true, false
+	personal := Tags{ValidSyntax: true, MXOK: &yes}
+	role := Tags{ValidSyntax: true, MXOK: &yes, IsRole: true}
+	disposable := Tags{ValidSyntax: true, IsDisposable: true}
+	noMX := Tags{ValidSyntax: true, MXOK: &no}
+	invalid := Tags{}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		keep   []Tags
+		drop   []Tags
+	}{
+		{"none", Filter{}, []Tags{personal, role, disposable, noMX, invalid}, nil},
+		{"invalid", Filter{ExcludeInvalid: true}, []Tags{personal, role, disposable}, []Tags{noMX, invalid}},
+		{"role", Filter{ExcludeRole: true}, []Tags{personal, disposable, noMX, invalid}, []Tags{role}},
+		{"disposable", Filter{ExcludeDisposable: true}, []Tags{personal, role, noMX, invalid}, []Tags{disposable}},
+	}
+	for _, tt := range tests {
+		if tt.filter.Active() != (tt.drop != nil) {
+			t.Errorf("%s: Active = %v", tt.name, tt.filter.Active())
+		}
+		for _, tags := range tt.keep {
+			if !tt.filter.Keep(tags) {
+				t.Errorf("%s: dropped %s", tt.name, describe(tags))
+			}
+		}
+		for _, tags := range tt.drop {
+			if tt.filter.Keep(tags) {
+				t.Errorf("%s: kept %s", tt.name, describe(tags))
+			}
+		}
+	}
+}
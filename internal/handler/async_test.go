@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"email-crawler/internal/cache"
+	"email-crawler/internal/config"
+)
+
+func TestAsyncEndpointsReportAsyncDisabled(t *testing.T) {
+	cfg := config.Load()
+	cfg.CacheBackend = "memory"
+	cfg.AsyncEnabled = false
+	cfg.AdminAPIKey = "secret"
+	// As in main.go, no job store or worker pool exists with async off
+	h := NewHandler(cfg, cache.New(cfg), nil)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		target  string
+		body    string
+	}{
+		{"async scan", h.AsyncScanHandler, http.MethodPost, "/scan/async", `{"url":"example.com"}`},
+		{"status", h.JobStatusHandler, http.MethodGet, "/scan/status/abc", ""},
+		{"cancel", h.CancelJobHandler, http.MethodPost, "/scan/cancel/abc", ""},
+		{"jobs", h.JobsListHandler, http.MethodGet, "/scan/jobs", ""},
+		{"dead letter", h.DeadLetterHandler, http.MethodGet, "/scan/dead-letter", ""},
+		{"retry dead letter", h.RetryDeadLetterHandler, http.MethodPost, "/scan/dead-letter/abc/retry", ""},
+		{"worker status", h.WorkerStatusHandler, http.MethodGet, "/workers", ""},
+		{"scale", h.ScaleWorkersHandler, http.MethodPost, "/workers/scale", `{"workers":2}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			req.SetPathValue("id", "abc")
+			req.Header.Set("X-Admin-Key", "secret")
+			rec := httptest.NewRecorder()
+			tt.handler(rec, req)
+
+			var body map[string]string
+			json.Unmarshal(rec.Body.Bytes(), &body)
+			if rec.Code != http.StatusServiceUnavailable || body["code"] != "ASYNC_DISABLED" || body["error"] == "" {
+				t.Errorf("response = %d %s, want %d with code ASYNC_DISABLED and an error message", rec.Code, rec.Body.String(), http.StatusServiceUnavailable)
+			}
+		})
+	}
+}
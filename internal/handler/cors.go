@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Methods and request headers browsers may use, advertised on every CORS
+// response. Retry-After and X-Cache-Age are exposed so clients can honour scan
+// cooldowns and judge cached results.
+const (
+	corsAllowMethods  = "GET, POST, DELETE, OPTIONS"
+	corsAllowHeaders  = "Content-Type, Idempotency-Key, X-Admin-Key, X-API-Key, X-Tenant-ID"
+	corsExposeHeaders = "Retry-After, X-Cache-Age"
+)
+
+// CORS wraps next so browsers on allowedOrigins can call the API. "*" allows
+// any origin. Preflight requests are answered with 204 without reaching next.
+func CORS(allowedOrigins []string, next http.Handler) http.Handler {
+	allowAll := false
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+		allowed[strings.TrimSuffix(origin, "/")] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		switch {
+		case allowAll:
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		case origin != "" && allowed[origin]:
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		if !allowAll {
+			// The response depends on the origin, so shared caches must key on it
+			w.Header().Add("Vary", "Origin")
+		}
+		if w.Header().Get("Access-Control-Allow-Origin") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", corsAllowMethods)
+			w.Header().Set("Access-Control-Allow-Headers", corsAllowHeaders)
+			w.Header().Set("Access-Control-Expose-Headers", corsExposeHeaders)
+		}
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -1,18 +1,30 @@
 package handler
 
 import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"mime"
 	"net/http"
 	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"email-crawler/internal/cache"
 	"email-crawler/internal/config"
 	"email-crawler/internal/crawler"
+	"email-crawler/internal/emailcheck"
 	"email-crawler/internal/jobs"
+	"email-crawler/internal/metrics"
+	"email-crawler/internal/ssrf"
 )
 
 type ScanResponse struct {
@@ -20,31 +32,174 @@ type ScanResponse struct {
 	Error      string   `json:"error,omitempty"`
 	FromCache  bool     `json:"from_cache"`
 	CrawlTime  string   `json:"crawl_time,omitempty"`
+	TimedOut   bool     `json:"timed_out,omitempty"`
+
+	// Truncated marks a crawl that stopped at CRAWLER_MAX_EMAILS emails
+	Truncated bool `json:"truncated,omitempty"`
+
+	// CachedAt and Age describe a cached result; both are omitted on fresh crawls
+	CachedAt *time.Time `json:"cached_at,omitempty"`
+	Age      string     `json:"age,omitempty"`
+
+	// Stale marks a cached result past its TTL that is being refreshed
+	Stale bool `json:"stale,omitempty"`
+
+	// Labels is only returned with ?include=labels
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Sources is only returned with ?include=sources
+	Sources map[string][]string `json:"sources,omitempty"`
+
+	// Tags is only returned with ?include=tags
+	Tags map[string]emailcheck.Tags `json:"tags,omitempty"`
+
+	// RawHTML is only returned to admins with ?debug=raw
+	RawHTML map[string]string `json:"raw_html,omitempty"`
+
+	// PagesVisited and DepthReached describe how thorough the crawl was
+	PagesVisited *int `json:"pages_visited,omitempty"`
+	DepthReached *int `json:"depth_reached,omitempty"`
+
+	// EmailCount replaces the emails with ?count_only=true
+	EmailCount *int `json:"email_count,omitempty"`
+
+	// retryAfter is sent as the Retry-After header of a 429
+	retryAfter time.Duration
+}
+
+// crawlInfo records how many pages the crawl visited and how deep it went.
+func (r *ScanResponse) crawlInfo(pagesVisited, depthReached int) {
+	r.PagesVisited = &pagesVisited
+	r.DepthReached = &depthReached
 }
 
+// countOnly strips everything that would reveal an address, leaving counts.
+func (r *ScanResponse) countOnly() {
+	emailCount := len(r.Emails)
+	r.EmailCount = &emailCount
+	r.Emails = nil
+	r.Labels = nil
+	r.Sources = nil
+	r.Tags = nil
+	r.RawHTML = nil
+}
+
+// rawHTMLLimit caps the bytes of HTML returned per page with ?debug=raw
+const rawHTMLLimit = 64 * 1024
+
 type Handler struct {
 	config       *config.Config
-	cacheManager *cache.CacheManager
-	jobQueue     *jobs.Queue
+	cacheManager cache.Cache
+	jobQueue     jobs.JobStore
+	workerPool   *jobs.WorkerPool
+	emailChecker *emailcheck.Checker
 }
 
-func NewHandler(cfg *config.Config, cacheManager *cache.CacheManager, jobQueue *jobs.Queue) *Handler {
+func NewHandler(cfg *config.Config, cacheManager cache.Cache, jobQueue jobs.JobStore) *Handler {
 	return &Handler{
 		config:       cfg,
 		cacheManager: cacheManager,
 		jobQueue:     jobQueue,
+		emailChecker: emailcheck.NewChecker(cfg),
+	}
+}
+
+// SetWorkerPool lets the handler report and scale the async worker pool.
+func (h *Handler) SetWorkerPool(wp *jobs.WorkerPool) {
+	h.workerPool = wp
+}
+
+// ScanRequest is the JSON body accepted by POST /scan. Fields mirror the GET
+// query parameters.
+type ScanRequest struct {
+	URL             string   `json:"url"`
+	IfModifiedSince string   `json:"if_modified_since,omitempty"`
+	Include         []string `json:"include,omitempty"`
+	MaxTime         string   `json:"max_time,omitempty"`
+	Debug           string   `json:"debug,omitempty"`
+	PreserveCase    bool     `json:"preserve_case,omitempty"`
+	CountOnly       bool     `json:"count_only,omitempty"`
+	Refresh         bool     `json:"refresh,omitempty"`
+	MaxDepth        *int     `json:"max_depth,omitempty"`
+	Exclude         []string `json:"exclude,omitempty"`
+
+	ExcludeInvalid    bool `json:"exclude_invalid,omitempty"`
+	ExcludeRole       bool `json:"exclude_role,omitempty"`
+	ExcludeDisposable bool `json:"exclude_disposable,omitempty"`
+}
+
+// values converts the body into the equivalent query parameters.
+func (req ScanRequest) values() url.Values {
+	params := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			params.Set(key, value)
+		}
 	}
+	set("url", req.URL)
+	set("if_modified_since", req.IfModifiedSince)
+	set("include", strings.Join(req.Include, ","))
+	set("max_time", req.MaxTime)
+	set("debug", req.Debug)
+	flag := func(key string, value bool) {
+		if value {
+			params.Set(key, "true")
+		}
+	}
+	flag("preserve_case", req.PreserveCase)
+	flag("count_only", req.CountOnly)
+	flag("refresh", req.Refresh)
+	flag("exclude_invalid", req.ExcludeInvalid)
+	flag("exclude_role", req.ExcludeRole)
+	flag("exclude_disposable", req.ExcludeDisposable)
+	if req.MaxDepth != nil {
+		params.Set("depth", strconv.Itoa(*req.MaxDepth))
+	}
+	// Patterns may contain commas, so each is its own value
+	for _, pattern := range req.Exclude {
+		params.Add("exclude", pattern)
+	}
+	return params
 }
 
 func (h *Handler) ScanHandler(w http.ResponseWriter, r *http.Request) {
-	startTime := time.Now()
 	w.Header().Set("Content-Type", "application/json")
-	queryURL := r.URL.Query().Get("url")
+
+	// POST takes the same options as a JSON body
+	params := r.URL.Query()
+	if r.Method == http.MethodPost {
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			json.NewEncoder(w).Encode(ScanResponse{Error: "Content-Type must be application/json"})
+			return
+		}
+		var req ScanRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ScanResponse{Error: "Invalid JSON format"})
+			return
+		}
+		params = req.values()
+	}
+	status, response := h.scan(r, params)
+	if response.retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(response.retryAfter.Round(time.Second).Seconds())))
+	}
+	if response.CachedAt != nil {
+		w.Header().Set("X-Cache-Age", strconv.Itoa(int(time.Since(*response.CachedAt).Seconds())))
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// scan runs one scan described by params, the query parameters of GET /scan,
+// and returns the HTTP status and response to send.
+func (h *Handler) scan(r *http.Request, params url.Values) (int, ScanResponse) {
+	startTime := time.Now()
+	queryURL := params.Get("url")
 
 	if queryURL == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ScanResponse{Error: "Missing 'url' parameter"})
-		return
+		return http.StatusBadRequest, ScanResponse{Error: "Missing 'url' parameter"}
 	}
 
 	if !strings.HasPrefix(queryURL, "http://") && !strings.HasPrefix(queryURL, "https://") {
@@ -53,67 +208,609 @@ func (h *Handler) ScanHandler(w http.ResponseWriter, r *http.Request) {
 
 	startURL, err := url.Parse(queryURL)
 	if err != nil || (startURL.Scheme != "http" && startURL.Scheme != "https") {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ScanResponse{Error: "Invalid URL provided"})
-		return
+		return http.StatusBadRequest, ScanResponse{Error: "Invalid URL provided"}
+	}
+	if !crawler.DomainAllowed(startURL.Hostname(), h.config.CrawlerAllowedDomains) {
+		return http.StatusForbidden, ScanResponse{Error: domainNotAllowed(startURL)}
+	}
+	if err := h.privateTarget(r.Context(), startURL); err != nil {
+		return http.StatusBadRequest, ScanResponse{Error: fmt.Sprintf("URL not allowed: %v", err)}
 	}
 
+	tenantID, tenant, err := h.tenantFor(r)
+	if err != nil {
+		return http.StatusBadRequest, ScanResponse{Error: err.Error()}
+	}
+	cacheManager := h.cacheFor(tenant)
+	opts := crawler.OptionsForTenant(h.config, tenant)
+
+	// Incremental scans only report recently modified pages, so they neither read
+	// nor populate the cache
+	var modifiedSince time.Time
+	if rawSince := params.Get("if_modified_since"); rawSince != "" {
+		modifiedSince, err = parseModifiedSince(rawSince)
+		if err != nil {
+			return http.StatusBadRequest, ScanResponse{Error: "Invalid 'if_modified_since' parameter (use RFC3339 or HTTP date)"}
+		}
+	}
+	incremental := !modifiedSince.IsZero()
+	includeLabels := hasInclude(params, "labels")
+	includeSources := hasInclude(params, "sources")
+	preserveCase := params.Get("preserve_case") == "true"
+	countOnly := params.Get("count_only") == "true"
+	// refresh skips the cache lookup but still caches the fresh result
+	refresh := params.Get("refresh") == "true"
+	includeTags := hasInclude(params, "tags")
+	filter := emailcheck.Filter{
+		ExcludeInvalid:    params.Get("exclude_invalid") == "true",
+		ExcludeRole:       params.Get("exclude_role") == "true",
+		ExcludeDisposable: params.Get("exclude_disposable") == "true",
+	}
+
+	// Best-effort scans stop after max_time and return whatever was found
+	var maxTime time.Duration
+	if rawMaxTime := params.Get("max_time"); rawMaxTime != "" {
+		maxTime, err = parseMaxTime(rawMaxTime)
+		if err != nil {
+			return http.StatusBadRequest, ScanResponse{Error: "Invalid 'max_time' parameter (use a duration like 10s)"}
+		}
+	}
+
+	// depth overrides the default crawl depth; results are cached per depth.
+	// max_depth is accepted as an alias.
+	cacheKey := queryURL
+	rawDepth := params.Get("depth")
+	if rawDepth == "" {
+		rawDepth = params.Get("max_depth")
+	}
+	if rawDepth != "" {
+		depth, err := strconv.Atoi(rawDepth)
+		if limit := h.depthLimit(opts.MaxDepth); err != nil || depth < 0 || depth > limit {
+			return http.StatusBadRequest, ScanResponse{Error: fmt.Sprintf("Invalid 'depth' parameter (use 0 to %d)", limit)}
+		}
+		cacheKey = cache.DepthKey(queryURL, depth, opts.MaxDepth)
+		opts.MaxDepth = depth
+	}
+
+	// exclude can only narrow the crawl; like incremental scans these neither
+	// read nor populate the cache
+	narrowed := false
+	if patterns := params["exclude"]; len(patterns) > 0 {
+		exclude, err := crawler.CompileExcludePatterns(patterns)
+		if err != nil {
+			return http.StatusBadRequest, ScanResponse{Error: fmt.Sprintf("Invalid 'exclude' parameter: %v", err)}
+		}
+		opts.ExcludePatterns = append(append([]*regexp.Regexp{}, opts.ExcludePatterns...), exclude...)
+		narrowed = true
+	}
+
+	// Raw HTML debugging is admin-only and always crawls so the pages can be shown
+	debugRaw := false
+	if debug := params.Get("debug"); debug != "" {
+		if debug != "raw" {
+			return http.StatusBadRequest, ScanResponse{Error: "Invalid 'debug' parameter (supported: raw)"}
+		}
+		if !h.isAdmin(r) {
+			return http.StatusForbidden, ScanResponse{Error: "debug=raw requires a valid X-Admin-Key"}
+		}
+		debugRaw = true
+	}
+
+	// A tenant's crawl timeout caps max_time
+	if tenant != nil && tenant.Timeout() > 0 && (maxTime == 0 || tenant.Timeout() < maxTime) {
+		maxTime = tenant.Timeout()
+	}
+	if tenantID != "" {
+		log.Printf("Scan for tenant %s: %s", tenantID, queryURL)
+	}
+
+	metrics.ObserveScan(metrics.ModeSync)
+
 	// Check cache first
-	if cachedResult, found := h.cacheManager.Get(queryURL); found {
+	var cachedResult *cache.CachedResult
+	found := false
+	if !incremental && !narrowed && !debugRaw && !refresh {
+		cachedResult, found = cacheManager.Get(cacheKey)
+		found = found && cachedResult.CoversDepth(opts.MaxDepth)
+		metrics.ObserveCacheLookup(found)
+	}
+	if found {
 		crawlTime := time.Since(startTime)
 		response := ScanResponse{
 			Emails:    cachedResult.Emails,
 			FromCache: true,
 			CrawlTime: crawlTime.String(),
+			CachedAt:  &cachedResult.Timestamp,
+			Age:       time.Since(cachedResult.Timestamp).Round(time.Second).String(),
+			Truncated: cachedResult.CrawlInfo.Truncated,
+		}
+		response.crawlInfo(cachedResult.CrawlInfo.PagesVisited, cachedResult.CrawlInfo.DepthReached)
+		if cachedResult.IsStale(h.config.CacheExpirationTime) {
+			response.Stale = true
+			if h.cooldownRemaining(r, cacheManager, queryURL) <= 0 {
+				h.revalidate(cacheManager, cacheKey, queryURL, startURL, opts)
+			}
+		}
+		if preserveCase {
+			response.Emails = withOriginalCase(response.Emails, cachedResult.OriginalCase)
 		}
-		if len(cachedResult.Emails) == 0 {
+		h.tagEmails(r.Context(), &response, includeTags, filter)
+		if includeLabels {
+			response.Labels = labelsFor(response.Emails, cachedResult.Labels)
+		}
+		if includeSources {
+			response.Sources = sourcesFor(response.Emails, cachedResult.Sources)
+		}
+		if len(response.Emails) == 0 {
 			response.Emails = []string{} // Ensure [] instead of null
 		}
-		json.NewEncoder(w).Encode(response)
-		return
+		if countOnly {
+			response.countOnly()
+		}
+		return http.StatusOK, response
+	}
+
+	// Enforce the per-URL cooldown before crawling again
+	if wait := h.cooldownRemaining(r, cacheManager, queryURL); wait > 0 {
+		return http.StatusTooManyRequests, ScanResponse{
+			Error:      fmt.Sprintf("URL was scanned recently, try again in %s", wait.Round(time.Second)),
+			retryAfter: wait,
+		}
 	}
 
 	// Not in cache, perform crawl
-	c := crawler.New(h.config.MaxDepth)
-	foundEmailsMap := c.Crawl(startURL)
+	ctx := r.Context()
+	if maxTime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, maxTime)
+		defer cancel()
+	}
+
+	opts.IfModifiedSince = modifiedSince
+	if debugRaw {
+		opts.RawHTMLLimit = rawHTMLLimit
+	}
+	c := crawler.NewWithOptions(opts)
+	foundEmailsMap := c.CrawlWithContext(ctx, startURL)
+	timedOut := errors.Is(ctx.Err(), context.DeadlineExceeded)
+	// A client that disconnected cut the crawl short too
+	interrupted := ctx.Err() != nil
+	outcome := metrics.OutcomeSuccess
+	if timedOut {
+		outcome = metrics.OutcomeTimeout
+	}
+	metrics.ObserveCrawl(queryURL, time.Since(startTime), len(foundEmailsMap), outcome)
 
 	emailList := make([]string, 0, len(foundEmailsMap))
 	for email := range foundEmailsMap {
 		emailList = append(emailList, email)
 	}
+	if h.config.CrawlerValidateMX {
+		emailList = h.emailChecker.DropUndeliverable(r.Context(), emailList)
+	}
+	cacheManager.MarkCrawled(queryURL, h.config.ScanMinInterval)
+
+	// Partial results are returned but never cached
+	if incremental || narrowed || interrupted {
+		response := ScanResponse{
+			Emails:    cacheManager.DeduplicateEmails(emailList),
+			FromCache: false,
+			CrawlTime: time.Since(startTime).String(),
+			TimedOut:  timedOut,
+			Truncated: c.Truncated(),
+		}
+		response.crawlInfo(c.PagesVisited(), c.MaxDepthReached())
+		if preserveCase {
+			response.Emails = withOriginalCase(response.Emails, c.OriginalCase())
+		}
+		h.tagEmails(r.Context(), &response, includeTags, filter)
+		if includeLabels {
+			response.Labels = labelsFor(response.Emails, c.Labels())
+		}
+		if includeSources {
+			response.Sources = sourcesFor(response.Emails, c.Sources())
+		}
+		if debugRaw {
+			response.RawHTML = c.RawPages()
+		}
+		if len(response.Emails) == 0 {
+			response.Emails = []string{} // Ensure [] instead of null
+		}
+		if countOnly {
+			response.countOnly()
+		}
+		return http.StatusOK, response
+	}
 
 	// Cache the result (includes deduplication)
-	h.cacheManager.Set(queryURL, emailList, h.config.MaxDepth, len(foundEmailsMap))
+	cacheManager.Set(cacheKey, cache.CachedResult{
+		Emails:    emailList,
+		CrawlInfo: cache.CrawlInfo{Depth: opts.MaxDepth, PagesVisited: c.PagesVisited(), DepthReached: c.MaxDepthReached(), Truncated: c.Truncated()},
+		Labels:    c.Labels(),
 
-	// Get deduplicated emails from cache (it was just cached)
-	var deduplicatedEmails []string
-	if cachedResult, found := h.cacheManager.Get(queryURL); found {
-		deduplicatedEmails = cachedResult.Emails
-	} else {
-		// Fallback - shouldn't happen but just in case
-		deduplicatedEmails = h.cacheManager.DeduplicateEmails(emailList)
-	}
+		OriginalCase: c.OriginalCase(),
+		Sources:      c.Sources(),
+	})
+
+	// Deduplicate the same way Set did rather than reading the entry back,
+	// which would count as a cache hit
+	deduplicatedEmails := cacheManager.DeduplicateEmails(emailList)
 
 	crawlTime := time.Since(startTime)
 	response := ScanResponse{
 		Emails:    deduplicatedEmails,
 		FromCache: false,
 		CrawlTime: crawlTime.String(),
+		Truncated: c.Truncated(),
+	}
+	response.crawlInfo(c.PagesVisited(), c.MaxDepthReached())
+	if preserveCase {
+		response.Emails = withOriginalCase(response.Emails, c.OriginalCase())
+	}
+	h.tagEmails(r.Context(), &response, includeTags, filter)
+	if includeLabels {
+		response.Labels = labelsFor(response.Emails, c.Labels())
 	}
-	if len(deduplicatedEmails) == 0 {
+	if includeSources {
+		response.Sources = sourcesFor(response.Emails, c.Sources())
+	}
+	if debugRaw {
+		response.RawHTML = c.RawPages()
+	}
+	if len(response.Emails) == 0 {
 		response.Emails = []string{} // Ensure [] instead of null
 	}
+	if countOnly {
+		response.countOnly()
+	}
 
-	json.NewEncoder(w).Encode(response)
+	return http.StatusOK, response
+}
+
+// BatchScanRequest is the JSON body accepted by POST /scan/batch. Options
+// besides URLs apply to every URL, as in POST /scan.
+type BatchScanRequest struct {
+	URLs []string `json:"urls"`
+	ScanRequest
+}
+
+// BatchScanResult is one URL's outcome in a batch, shaped like a /scan
+// response plus the URL it belongs to.
+type BatchScanResult struct {
+	URL string `json:"url"`
+	ScanResponse
+}
+
+// BatchScanHandler scans up to MAX_BATCH_SIZE URLs, BATCH_CONCURRENCY at a
+// time. Each URL is scanned, cached and validated like a single /scan, so one
+// URL failing only sets that result's error.
+func (h *Handler) BatchScanHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed. Use POST."})
+		return
+	}
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Content-Type must be application/json"})
+		return
+	}
+
+	var req BatchScanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid JSON format"})
+		return
+	}
+	if len(req.URLs) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Missing 'urls' field"})
+		return
+	}
+	if len(req.URLs) > h.config.MaxBatchSize {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Too many URLs: at most %d per batch", h.config.MaxBatchSize)})
+		return
+	}
+
+	concurrency := h.config.BatchConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	results := make([]BatchScanResult, len(req.URLs))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, rawURL := range req.URLs {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, rawURL string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			scanReq := req.ScanRequest
+			scanReq.URL = rawURL
+			_, response := h.scan(r, scanReq.values())
+			results[i] = BatchScanResult{URL: rawURL, ScanResponse: response}
+		}(i, rawURL)
+	}
+	wg.Wait()
+
+	json.NewEncoder(w).Encode(map[string][]BatchScanResult{"results": results})
+}
+
+// isAdmin reports whether the request carries the configured admin API key.
+func (h *Handler) isAdmin(r *http.Request) bool {
+	key := r.Header.Get("X-Admin-Key")
+	return h.config.AdminAPIKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(h.config.AdminAPIKey)) == 1
+}
+
+// privateTarget returns an error wrapping ssrf.ErrPrivateTarget if u's host
+// resolves to a private or internal address and ALLOW_PRIVATE_TARGETS is off.
+// It only gives callers a clear error up front: the crawler and webhook
+// deliveries check every connection again, redirects included. Hosts that
+// don't resolve are left to fail the crawl or delivery as before.
+func (h *Handler) privateTarget(ctx context.Context, u *url.URL) error {
+	if h.config.AllowPrivateTargets {
+		return nil
+	}
+	if err := ssrf.CheckHost(ctx, u.Hostname()); errors.Is(err, ssrf.ErrPrivateTarget) {
+		return err
+	}
+	return nil
+}
+
+// domainNotAllowed is the error for a scan of a site outside
+// CRAWLER_ALLOWED_DOMAINS.
+func domainNotAllowed(u *url.URL) string {
+	return fmt.Sprintf("Domain not allowed: %s is not in the allowed domains", u.Hostname())
+}
+
+// cooldownRemaining returns how long the caller must wait before rawURL may be
+// crawled again, or 0 if it may be crawled now. Admins bypass the cooldown.
+func (h *Handler) cooldownRemaining(r *http.Request, cacheManager cache.Cache, rawURL string) time.Duration {
+	if h.config.ScanMinInterval <= 0 || h.isAdmin(r) {
+		return 0
+	}
+
+	lastCrawl, found := cacheManager.LastCrawled(rawURL)
+	if !found {
+		return 0
+	}
+	return h.config.ScanMinInterval - time.Since(lastCrawl)
+}
+
+// tenantFor identifies the request's tenant from the X-Tenant-ID header or,
+// failing that, a tenant API key in X-API-Key. Requests without a tenant get
+// a nil config and use the global defaults.
+func (h *Handler) tenantFor(r *http.Request) (string, *config.TenantConfig, error) {
+	if id := r.Header.Get("X-Tenant-ID"); id != "" {
+		tenant, ok := h.config.Tenant(id)
+		if !ok {
+			return "", nil, fmt.Errorf("unknown tenant %q", id)
+		}
+		return id, tenant, nil
+	}
+	if id, tenant, ok := h.config.TenantByAPIKey(r.Header.Get("X-API-Key")); ok {
+		return id, tenant, nil
+	}
+	return "", nil, nil
+}
+
+// cacheFor returns the cache namespace for a tenant.
+func (h *Handler) cacheFor(tenant *config.TenantConfig) cache.Cache {
+	if tenant == nil || tenant.CachePrefix == "" {
+		return h.cacheManager
+	}
+	return h.cacheManager.WithPrefix(tenant.CachePrefix)
+}
+
+// depthLimit is the deepest crawl a request may ask for. Requests may go
+// deeper than the default up to CRAWLER_MAX_DEPTH_LIMIT, which never caps
+// below the default itself.
+func (h *Handler) depthLimit(defaultDepth int) int {
+	if h.config.CrawlerMaxDepthLimit < defaultDepth {
+		return defaultDepth
+	}
+	return h.config.CrawlerMaxDepthLimit
+}
+
+// hasInclude reports whether the comma-separated include parameter lists name.
+func hasInclude(params url.Values, name string) bool {
+	for _, item := range strings.Split(params.Get("include"), ",") {
+		if strings.TrimSpace(item) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// tagEmails runs the quality heuristics over the response's emails, dropping
+// those rejected by filter and attaching the tags when includeTags is set.
+func (h *Handler) tagEmails(ctx context.Context, response *ScanResponse, includeTags bool, filter emailcheck.Filter) {
+	if !includeTags && !filter.Active() {
+		return
+	}
+
+	tags := h.emailChecker.Tag(ctx, response.Emails)
+	kept := make([]string, 0, len(response.Emails))
+	for _, email := range response.Emails {
+		if filter.Keep(tags[email]) {
+			kept = append(kept, email)
+		} else {
+			delete(tags, email)
+		}
+	}
+	response.Emails = kept
+	if includeTags {
+		response.Tags = tags
+	}
+}
+
+// labelsFor returns the labels known for the given emails.
+func labelsFor(emails []string, labels map[string]string) map[string]string {
+	result := make(map[string]string)
+	for _, email := range emails {
+		if label, ok := labels[strings.ToLower(email)]; ok {
+			result[email] = label
+		}
+	}
+	return result
+}
+
+// sourcesFor returns the pages each of the given emails was found on.
+func sourcesFor(emails []string, sources map[string][]string) map[string][]string {
+	result := make(map[string][]string)
+	for _, email := range emails {
+		if pages, ok := sources[strings.ToLower(email)]; ok {
+			result[email] = pages
+		}
+	}
+	return result
+}
+
+// withOriginalCase replaces each (lowercased) email with the casing it was
+// first seen with, when known.
+func withOriginalCase(emails []string, original map[string]string) []string {
+	result := make([]string, 0, len(emails))
+	for _, email := range emails {
+		if cased, ok := original[strings.ToLower(email)]; ok {
+			email = cased
+		}
+		result = append(result, email)
+	}
+	return result
+}
+
+// parseModifiedSince accepts either an RFC3339 timestamp or an HTTP date.
+func parseModifiedSince(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return http.ParseTime(value)
+}
+
+// parseMaxTime accepts a Go duration ("10s", "1m30s") or a number of seconds.
+func parseMaxTime(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			return 0, err
+		}
+		d = time.Duration(seconds) * time.Second
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("max_time must be positive")
+	}
+	return d, nil
+}
+
+// NotFoundHandler answers requests that match no route.
+func (h *Handler) NotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("No endpoint at %s", r.URL.Path)})
+}
+
+// readyTimeout bounds the dependency checks behind /ready
+const readyTimeout = 2 * time.Second
+
+// HealthHandler is the liveness probe: it answers as long as the server runs.
+func (h *Handler) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// ReadyHandler is the readiness probe. It answers 503 while Redis, used by the
+// cache or the job queue, is unreachable.
+func (h *Handler) ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+
+	err := h.cacheManager.Ping(ctx)
+	if err == nil && h.jobQueue != nil {
+		err = h.jobQueue.Ping(ctx)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"status": "unavailable", "error": err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "ready"})
 }
 
 // Cache management endpoints
 func (h *Handler) CacheStatsHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
+
+	// DELETE zeroes the hit/miss counters without touching cached results
+	if r.Method == http.MethodDelete {
+		if err := h.cacheManager.ResetStats(); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to reset cache statistics"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"message": "Cache statistics reset"})
+		return
+	}
+
 	stats := h.cacheManager.Stats()
 	json.NewEncoder(w).Encode(stats)
 }
 
+// CacheEntryHandler returns the cached result for a URL exactly as stored,
+// without crawling on a miss. The URL and ?depth= are resolved to a cache key
+// the same way /scan does, including the tenant's prefix.
+func (h *Handler) CacheEntryHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed. Use GET."})
+		return
+	}
+
+	queryURL := r.URL.Query().Get("url")
+	if queryURL == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Missing 'url' parameter"})
+		return
+	}
+	if !strings.HasPrefix(queryURL, "http://") && !strings.HasPrefix(queryURL, "https://") {
+		queryURL = "https://" + queryURL
+	}
+
+	_, tenant, err := h.tenantFor(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	cacheKey := queryURL
+	if rawDepth := r.URL.Query().Get("depth"); rawDepth != "" {
+		defaultDepth := crawler.OptionsForTenant(h.config, tenant).MaxDepth
+		depth, err := strconv.Atoi(rawDepth)
+		if limit := h.depthLimit(defaultDepth); err != nil || depth < 0 || depth > limit {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Invalid 'depth' parameter (use 0 to %d)", limit)})
+			return
+		}
+		cacheKey = cache.DepthKey(queryURL, depth, defaultDepth)
+	}
+
+	cachedResult, found := h.cacheFor(tenant).Get(cacheKey)
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "No cached result for URL", "url": queryURL})
+		return
+	}
+	json.NewEncoder(w).Encode(cachedResult)
+}
+
 func (h *Handler) InvalidateCacheHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	
@@ -123,6 +820,18 @@ func (h *Handler) InvalidateCacheHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Clear every cached path of a domain
+	if domain := r.URL.Query().Get("domain"); domain != "" {
+		removed, err := h.cacheManager.InvalidateDomain(domain)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to invalidate cache"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"message": "Cache invalidated for domain", "domain": domain, "removed": removed})
+		return
+	}
+
 	queryURL := r.URL.Query().Get("url")
 	if queryURL == "" {
 		// Clear all cache
@@ -145,13 +854,126 @@ func (h *Handler) InvalidateCacheHandler(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Cache invalidated for URL", "url": queryURL})
 }
 
+// WebhookTestRequest is the body accepted by POST /scan/webhook/test.
+type WebhookTestRequest struct {
+	WebhookURL      string            `json:"webhook_url"`
+	WebhookHeaders  map[string]string `json:"webhook_headers,omitempty"`
+	WebhookCompress bool              `json:"webhook_compress,omitempty"`
+}
+
+// WebhookTestHandler delivers a sample payload to a webhook URL and reports the
+// receiver's status code and latency, without creating a job.
+func (h *Handler) WebhookTestHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed. Use POST."})
+		return
+	}
+
+	var req WebhookTestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid JSON format"})
+		return
+	}
+
+	if req.WebhookURL == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Missing 'webhook_url' field"})
+		return
+	}
+
+	webhookURL, err := url.Parse(req.WebhookURL)
+	if err != nil || (webhookURL.Scheme != "http" && webhookURL.Scheme != "https") || webhookURL.Host == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid webhook_url format"})
+		return
+	}
+
+	if err := jobs.ValidateWebhookHeaders(req.WebhookHeaders); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	if !h.config.AllowPrivateTargets {
+		if err := ssrf.CheckHost(r.Context(), webhookURL.Hostname()); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Webhook target not allowed: %v", err)})
+			return
+		}
+	}
+
+	result, err := jobs.ProbeWebhook(r.Context(), h.config, req.WebhookURL, req.WebhookHeaders, req.WebhookCompress)
+	if err != nil {
+		if errors.Is(err, ssrf.ErrPrivateTarget) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Webhook target not allowed: %v", err)})
+			return
+		}
+		if result == nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		// The receiver couldn't be reached; report the attempt as-is
+		w.WriteHeader(http.StatusBadGateway)
+	}
+
+	json.NewEncoder(w).Encode(result)
+}
+
+// NormalizeEmailsRequest is the body accepted by POST /emails/normalize.
+type NormalizeEmailsRequest struct {
+	Emails []string `json:"emails"`
+}
+
+// NormalizeEmailsHandler returns emails exactly as they would be stored in the
+// cache, applying the configured normalization and deduplication without a crawl.
+func (h *Handler) NormalizeEmailsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed. Use POST."})
+		return
+	}
+
+	var req NormalizeEmailsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid JSON format"})
+		return
+	}
+
+	emails := h.cacheManager.DeduplicateEmails(req.Emails)
+	if emails == nil {
+		emails = []string{} // Ensure [] instead of null
+	}
+	json.NewEncoder(w).Encode(map[string][]string{"emails": emails})
+}
+
+// asyncDisabled answers async endpoints when ASYNC_ENABLED=false, so clients can
+// tell a disabled feature apart from a mistyped route.
+func (h *Handler) asyncDisabled(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusServiceUnavailable)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error": h.config.AsyncDisabledMessage,
+		"code":  "ASYNC_DISABLED",
+	})
+}
+
+// maxIdempotencyKeyLength caps the Idempotency-Key header on POST /scan/async
+const maxIdempotencyKeyLength = 255
+
 // Async scan endpoints
 func (h *Handler) AsyncScanHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	
 	if !h.config.AsyncEnabled {
-		w.WriteHeader(http.StatusServiceUnavailable)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Async scanning is disabled"})
+		h.asyncDisabled(w)
 		return
 	}
 	
@@ -176,6 +998,32 @@ func (h *Handler) AsyncScanHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
+	tenantID, tenant, err := h.tenantFor(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	req.TenantID = tenantID
+
+	// A retried POST with the same Idempotency-Key gets the original job back
+	req.IdempotencyKey = r.Header.Get("Idempotency-Key")
+	if len(req.IdempotencyKey) > maxIdempotencyKeyLength {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength)})
+		return
+	}
+	req.Fingerprint = fmt.Sprintf("%x", sha256.Sum256(body))
+
+	if req.MaxDepth != nil {
+		limit := h.depthLimit(crawler.OptionsForTenant(h.config, tenant).MaxDepth)
+		if *req.MaxDepth < 0 || *req.MaxDepth > limit {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Invalid 'max_depth' field (use 0 to %d)", limit)})
+			return
+		}
+	}
+	
 	// Validate required fields
 	if req.URL == "" {
 		w.WriteHeader(http.StatusBadRequest)
@@ -183,70 +1031,159 @@ func (h *Handler) AsyncScanHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
+	// webhook_urls may stand in for webhook_url; its first entry becomes it
+	if req.WebhookURL == "" && len(req.WebhookURLs) > 0 {
+		req.WebhookURL, req.WebhookURLs = req.WebhookURLs[0], req.WebhookURLs[1:]
+	}
 	if req.WebhookURL == "" {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Missing 'webhook_url' field"})
 		return
 	}
+	if len(req.WebhookURLs) >= jobs.MaxWebhookURLs {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Too many webhook URLs: at most %d per job", jobs.MaxWebhookURLs)})
+		return
+	}
 	
 	// Validate URL format
 	if !strings.HasPrefix(req.URL, "http://") && !strings.HasPrefix(req.URL, "https://") {
 		req.URL = "https://" + req.URL
 	}
 	
-	if _, err := url.Parse(req.URL); err != nil {
+	startURL, err := url.Parse(req.URL)
+	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid URL format"})
 		return
 	}
+	if !crawler.DomainAllowed(startURL.Hostname(), h.config.CrawlerAllowedDomains) {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": domainNotAllowed(startURL)})
+		return
+	}
+	if err := h.privateTarget(r.Context(), startURL); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("URL not allowed: %v", err)})
+		return
+	}
 	
-	// Validate webhook URL format
-	if _, err := url.Parse(req.WebhookURL); err != nil {
+	// Validate webhook URLs; every receiver gets the same SSRF check as the
+	// scan target
+	for i, rawWebhookURL := range append([]string{req.WebhookURL}, req.WebhookURLs...) {
+		field := "webhook_url"
+		if i > 0 {
+			field = "webhook_urls"
+		}
+		webhookURL, err := url.Parse(rawWebhookURL)
+		if err != nil || (webhookURL.Scheme != "http" && webhookURL.Scheme != "https") || webhookURL.Host == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Invalid %s format", field)})
+			return
+		}
+		if err := h.privateTarget(r.Context(), webhookURL); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Webhook target not allowed: %v", err)})
+			return
+		}
+	}
+	if err := jobs.ValidateWebhookHeaders(req.WebhookHeaders); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid webhook_url format"})
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	// Scheduled jobs must run before their data expires after ASYNC_JOB_TTL_HOURS
+	if req.DelaySeconds != 0 {
+		if req.RunAt != nil || req.DelaySeconds < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid 'delay_seconds' field (use a positive number of seconds, without 'run_at')"})
+			return
+		}
+		runAt := time.Now().Add(time.Duration(req.DelaySeconds) * time.Second)
+		req.RunAt = &runAt
+	}
+	if req.RunAt != nil && req.RunAt.After(time.Now().Add(h.config.AsyncJobTTL)) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Jobs can be scheduled at most %s ahead", h.config.AsyncJobTTL)})
 		return
 	}
 	
 	// Enqueue job
 	job, err := h.jobQueue.Enqueue(req)
+	if errors.Is(err, jobs.ErrIdempotencyConflict) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Idempotency-Key was already used with a different request body"})
+		return
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Failed to queue job: %v", err)})
 		return
 	}
 	
+	if job.NotifyOnEnqueue && !job.Deduplicated && !job.Replayed {
+		jobs.NotifyEnqueued(h.config, job)
+	}
+	
 	// Return response
 	response := jobs.AsyncScanResponse{
 		JobID:          job.ID,
 		Status:         string(job.Status),
-		EstimatedTime:  "30-60s",
+		EstimatedTime:  h.estimatedTime(job),
 		WebhookURL:     job.WebhookURL,
+		WebhookURLs:    job.WebhookURLs,
 		CheckStatusURL: fmt.Sprintf("/scan/status/%s", job.ID),
+		Deduplicated:   job.Deduplicated,
 	}
 	
-	w.WriteHeader(http.StatusAccepted)
+	if job.Replayed {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusAccepted)
+	}
 	json.NewEncoder(w).Encode(response)
 }
 
+// defaultEstimatedTime is reported for new jobs until enough jobs have
+// completed to estimate from
+const defaultEstimatedTime = "30-60s"
+
+// estimatedTime estimates how long until a newly queued job is done from the
+// durations of recently completed jobs and its place in the queue.
+func (h *Handler) estimatedTime(job *jobs.ScanJob) string {
+	if job.Status != jobs.StatusQueued {
+		return defaultEstimatedTime
+	}
+	position, err := h.jobQueue.QueuePosition(job.ID)
+	if err != nil {
+		return defaultEstimatedTime
+	}
+	// A worker may already have taken it
+	if estimate, ok := h.workerPool.EstimateCompletion(max(position, 1)); ok {
+		return max(estimate.Round(time.Second), time.Second).String()
+	}
+	return defaultEstimatedTime
+}
+
+// JobStatusResponse is a job as returned by GET /scan/status/{id}. Queued jobs
+// also report their place in the queue and, once this instance has run some
+// jobs, a rough wait estimate.
+type JobStatusResponse struct {
+	jobs.ScanJob
+	QueuePosition int    `json:"queue_position,omitempty"`
+	EstimatedWait string `json:"estimated_wait,omitempty"`
+}
+
 func (h *Handler) JobStatusHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	
 	if !h.config.AsyncEnabled {
-		w.WriteHeader(http.StatusServiceUnavailable)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Async scanning is disabled"})
+		h.asyncDisabled(w)
 		return
 	}
 	
-	// Extract job ID from URL path
-	// Expected path: /scan/status/{job_id}
-	path := strings.TrimPrefix(r.URL.Path, "/scan/status/")
-	if path == "" || path == r.URL.Path {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Missing job ID in path"})
-		return
-	}
-	
-	jobID := path
+	jobID := r.PathValue("id")
 	
 	// Get job from queue
 	job, err := h.jobQueue.GetJob(jobID)
@@ -256,15 +1193,24 @@ func (h *Handler) JobStatusHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	json.NewEncoder(w).Encode(job)
+	response := JobStatusResponse{ScanJob: job.Redacted()}
+	if job.Status == jobs.StatusQueued {
+		// Recomputed on every poll since the queue keeps moving
+		if position, err := h.jobQueue.QueuePosition(jobID); err == nil && position > 0 {
+			response.QueuePosition = position
+			if wait, ok := h.workerPool.EstimateWait(position); ok {
+				response.EstimatedWait = max(wait.Round(time.Second), time.Second).String()
+			}
+		}
+	}
+	json.NewEncoder(w).Encode(response)
 }
 
 func (h *Handler) CancelJobHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	
 	if !h.config.AsyncEnabled {
-		w.WriteHeader(http.StatusServiceUnavailable)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Async scanning is disabled"})
+		h.asyncDisabled(w)
 		return
 	}
 	
@@ -274,16 +1220,7 @@ func (h *Handler) CancelJobHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	// Extract job ID from URL path
-	// Expected path: /scan/cancel/{job_id}
-	path := strings.TrimPrefix(r.URL.Path, "/scan/cancel/")
-	if path == "" || path == r.URL.Path {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Missing job ID in path"})
-		return
-	}
-	
-	jobID := path
+	jobID := r.PathValue("id")
 	
 	// Cancel job
 	err := h.jobQueue.CancelJob(jobID)
@@ -293,27 +1230,270 @@ func (h *Handler) CancelJobHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
+	// A processing job stops at its next cancellation check
+	if job, err := h.jobQueue.GetJob(jobID); err == nil && job.Status == jobs.StatusProcessing {
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Job cancellation requested", "job_id": jobID})
+		return
+	}
 	json.NewEncoder(w).Encode(map[string]string{"message": "Job cancelled", "job_id": jobID})
 }
 
+// Page sizes for GET /scan/jobs
+const (
+	defaultJobsLimit = 50
+	maxJobsLimit     = 500
+)
+
+// Pagination describes the page of a list returned by an endpoint: where it
+// starts, its maximum size, how many items match in all and whether more
+// follow.
+type Pagination struct {
+	Limit   int  `json:"limit"`
+	Offset  int  `json:"offset"`
+	Total   int  `json:"total"`
+	HasMore bool `json:"has_more"`
+}
+
+func newPagination(limit, offset, total int) Pagination {
+	return Pagination{
+		Limit:   limit,
+		Offset:  offset,
+		Total:   total,
+		HasMore: offset+limit < total,
+	}
+}
+
+// JobsListHandler reports queue stats and, to admins, lists stored jobs newest
+// first, optionally only those with ?status=, paginated with ?limit= and
+// ?offset=. Job IDs are what lets a caller poll or cancel a job, so other
+// callers only get the stats.
 func (h *Handler) JobsListHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	
 	if !h.config.AsyncEnabled {
-		w.WriteHeader(http.StatusServiceUnavailable)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Async scanning is disabled"})
+		h.asyncDisabled(w)
 		return
 	}
-	
+
+	query := r.URL.Query()
+	listJobs := h.isAdmin(r)
+	if !listJobs && (query.Has("status") || query.Has("limit") || query.Has("offset")) {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Listing jobs requires a valid X-Admin-Key"})
+		return
+	}
+	status := jobs.JobStatus(query.Get("status"))
+	switch status {
+	case "", jobs.StatusScheduled, jobs.StatusQueued, jobs.StatusProcessing, jobs.StatusCompleted, jobs.StatusFailed, jobs.StatusCancelled:
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid 'status' parameter (use scheduled, queued, processing, completed, failed or cancelled)"})
+		return
+	}
+	limit := defaultJobsLimit
+	if rawLimit := query.Get("limit"); rawLimit != "" {
+		var err error
+		if limit, err = strconv.Atoi(rawLimit); err != nil || limit < 1 || limit > maxJobsLimit {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Invalid 'limit' parameter (use 1 to %d)", maxJobsLimit)})
+			return
+		}
+	}
+	offset := 0
+	if rawOffset := query.Get("offset"); rawOffset != "" {
+		var err error
+		if offset, err = strconv.Atoi(rawOffset); err != nil || offset < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid 'offset' parameter"})
+			return
+		}
+	}
+
 	// Get queue stats
 	stats := h.jobQueue.Stats()
+	if !listJobs {
+		delete(stats, "active_job_ids")
+	}
 	
 	response := map[string]interface{}{
 		"async_enabled": h.config.AsyncEnabled,
 		"queue_stats":   stats,
-		"workers":       h.config.AsyncWorkers,
+		"workers":       h.workerPool.Size(),
 		"job_timeout":   h.config.AsyncJobTimeout.String(),
 	}
+
+	if listJobs {
+		jobList, total, err := h.jobQueue.ListJobs(status, limit, offset)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		for i := range jobList {
+			jobList[i] = jobList[i].Redacted()
+		}
+		response["jobs"] = jobList
+		response["pagination"] = newPagination(limit, offset, total)
+	}
 	
 	json.NewEncoder(w).Encode(response)
-}
\ No newline at end of file
+}
+
+// DeadLetterHandler lists jobs that failed for good, newest first, with the
+// error of each attempt. It spans every tenant's jobs, so it is admin-only.
+func (h *Handler) DeadLetterHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if !h.config.AsyncEnabled {
+		h.asyncDisabled(w)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed. Use GET."})
+		return
+	}
+	if !h.isAdmin(r) {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Listing failed jobs requires a valid X-Admin-Key"})
+		return
+	}
+
+	deadJobs, err := h.jobQueue.DeadLetters()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	for i := range deadJobs {
+		deadJobs[i] = deadJobs[i].Redacted()
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"count": len(deadJobs), "jobs": deadJobs})
+}
+
+// RetryDeadLetterHandler queues a dead-lettered job again under the same ID.
+// Like DeadLetterHandler it is admin-only.
+func (h *Handler) RetryDeadLetterHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if !h.config.AsyncEnabled {
+		h.asyncDisabled(w)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed. Use POST."})
+		return
+	}
+	if !h.isAdmin(r) {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Retrying failed jobs requires a valid X-Admin-Key"})
+		return
+	}
+
+	job, err := h.jobQueue.RetryDeadLetter(r.PathValue("id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, jobs.ErrNotDeadLettered) {
+			status = http.StatusNotFound
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(jobs.AsyncScanResponse{
+		JobID:          job.ID,
+		Status:         string(job.Status),
+		EstimatedTime:  h.estimatedTime(job),
+		WebhookURL:     job.WebhookURL,
+		WebhookURLs:    job.WebhookURLs,
+		CheckStatusURL: fmt.Sprintf("/scan/status/%s", job.ID),
+	})
+}
+
+// ScaleWorkersRequest is the JSON body accepted by POST /workers/scale.
+type ScaleWorkersRequest struct {
+	Workers int `json:"workers"`
+}
+
+// ScaleWorkersHandler is POST /workers/scale, the counterpart of GET /workers.
+// Admins can resize the pool to between 0 and ASYNC_MAX_WORKERS workers; a pool
+// started with ASYNC_WORKERS=0 is brought up this way, and 0 puts it back on
+// standby with jobs still accepted.
+func (h *Handler) ScaleWorkersHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if !h.config.AsyncEnabled {
+		h.asyncDisabled(w)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed. Use POST."})
+		return
+	}
+	if !h.isAdmin(r) {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Scaling workers requires a valid X-Admin-Key"})
+		return
+	}
+
+	var req ScaleWorkersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid JSON format"})
+		return
+	}
+	if req.Workers < 0 || req.Workers > h.config.AsyncMaxWorkers {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": fmt.Sprintf("'workers' must be between 0 and %d", h.config.AsyncMaxWorkers),
+		})
+		return
+	}
+
+	previous := h.workerPool.Resize(req.Workers)
+	log.Printf("Worker pool scaled from %d to %d", previous, req.Workers)
+
+	json.NewEncoder(w).Encode(map[string]int{"workers": h.workerPool.Size()})
+}
+
+// WorkerStatusResponse is the body of GET /workers.
+type WorkerStatusResponse struct {
+	Size    int                `json:"size"`
+	Busy    int                `json:"busy"`
+	Idle    int                `json:"idle"`
+	Workers []jobs.WorkerState `json:"workers"`
+}
+
+// WorkerStatusHandler reports what each worker is doing, to spot stuck
+// workers and uneven load. Size is the number of workers the pool is scaled
+// to; workers scaled away are listed until their current job finishes.
+func (h *Handler) WorkerStatusHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if !h.config.AsyncEnabled {
+		h.asyncDisabled(w)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed. Use GET."})
+		return
+	}
+
+	response := WorkerStatusResponse{Size: h.workerPool.Size(), Workers: h.workerPool.Status()}
+	for _, worker := range response.Workers {
+		if worker.State == jobs.WorkerProcessing {
+			response.Busy++
+		} else {
+			response.Idle++
+		}
+	}
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"email-crawler/internal/cache"
+	"email-crawler/internal/config"
+	"email-crawler/internal/jobs"
+)
+
+// newTestHandler returns a handler on the in-memory cache and job store, with
+// async enabled and a worker pool that is never started.
+func newTestHandler(t *testing.T) *Handler {
+	t.Helper()
+	cfg := config.Load()
+	cfg.CacheBackend = "memory"
+	cfg.JobStoreBackend = "memory"
+	cfg.AsyncEnabled = true
+	cfg.AllowPrivateTargets = false
+
+	queue := jobs.NewMemoryQueue(cfg)
+	h := NewHandler(cfg, cache.New(cfg), queue)
+	h.SetWorkerPool(jobs.NewWorkerPool(queue, h.cacheManager, cfg))
+	return h
+}
+
+// postJSON calls handler with a POST of body and returns the recorded response.
+func postJSON(handler http.HandlerFunc, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+func TestAsyncScanRejectsUnsafeWebhookTargets(t *testing.T) {
+	h := newTestHandler(t)
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"public", `{"url":"93.184.216.34","webhook_url":"https://93.184.216.34/hook"}`, http.StatusAccepted},
+		{"metadata", `{"url":"93.184.216.34","webhook_url":"http://169.254.169.254/latest"}`, http.StatusBadRequest},
+		{"loopback", `{"url":"93.184.216.34","webhook_url":"http://127.0.0.1:6379/"}`, http.StatusBadRequest},
+		{"private fan-out", `{"url":"93.184.216.34","webhook_url":"https://93.184.216.34/hook","webhook_urls":["http://10.0.0.5/hook"]}`, http.StatusBadRequest},
+		{"scheme", `{"url":"93.184.216.34","webhook_url":"ftp://93.184.216.34/hook"}`, http.StatusBadRequest},
+		{"no host", `{"url":"93.184.216.34","webhook_url":"https:///hook"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := postJSON(h.AsyncScanHandler, "/scan/async", tt.body)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
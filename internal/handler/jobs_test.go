@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"email-crawler/internal/jobs"
+)
+
+func TestJobsListPagination(t *testing.T) {
+	h := newTestHandler(t)
+	h.config.AdminAPIKey = "admin"
+	for i := 0; i < 5; i++ {
+		if _, err := h.jobQueue.Enqueue(jobs.AsyncScanRequest{URL: fmt.Sprintf("https://%d.example.com", i)}); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	tests := []struct {
+		name   string
+		offset int
+		jobs   int
+		want   Pagination
+	}{
+		{"first page", 0, 2, Pagination{Limit: 2, Offset: 0, Total: 5, HasMore: true}},
+		{"middle page", 2, 2, Pagination{Limit: 2, Offset: 2, Total: 5, HasMore: true}},
+		{"last page", 4, 1, Pagination{Limit: 2, Offset: 4, Total: 5, HasMore: false}},
+		{"past the end", 6, 0, Pagination{Limit: 2, Offset: 6, Total: 5, HasMore: false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := fmt.Sprintf("/scan/jobs?limit=2&offset=%d", tt.offset)
+			req := httptest.NewRequest(http.MethodGet, target, nil)
+			req.Header.Set("X-Admin-Key", "admin")
+			rec := httptest.NewRecorder()
+			h.JobsListHandler(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+			}
+
+			var body struct {
+				Jobs       []jobs.ScanJob `json:"jobs"`
+				Pagination Pagination     `json:"pagination"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if len(body.Jobs) != tt.jobs {
+				t.Errorf("got %d jobs, want %d", len(body.Jobs), tt.jobs)
+			}
+			if body.Pagination != tt.want {
+				t.Errorf("pagination = %+v, want %+v", body.Pagination, tt.want)
+			}
+		})
+	}
+}
+
+func TestJobsListShowsJobsOnlyToAdmins(t *testing.T) {
+	h := newTestHandler(t)
+	h.config.AdminAPIKey = "admin"
+	if _, err := h.jobQueue.Enqueue(jobs.AsyncScanRequest{URL: "https://example.com"}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.JobsListHandler(rec, httptest.NewRequest(http.MethodGet, "/scan/jobs", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	stats, _ := body["queue_stats"].(map[string]interface{})
+	if stats["active_jobs"] != 1.0 {
+		t.Errorf("queue_stats = %v, want 1 active job", stats)
+	}
+	if _, listed := body["jobs"]; listed || stats["active_job_ids"] != nil {
+		t.Errorf("response without X-Admin-Key reveals job IDs: %s", rec.Body.String())
+	}
+
+	for _, key := range []string{"", "wrong"} {
+		req := httptest.NewRequest(http.MethodGet, "/scan/jobs?status=queued", nil)
+		req.Header.Set("X-Admin-Key", key)
+		rec := httptest.NewRecorder()
+		h.JobsListHandler(rec, req)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("listing with X-Admin-Key %q: status = %d, want %d", key, rec.Code, http.StatusForbidden)
+		}
+	}
+}
+
+func TestDeadLetterRequiresAdmin(t *testing.T) {
+	h := newTestHandler(t)
+	h.config.AdminAPIKey = "admin"
+	if _, err := h.jobQueue.Enqueue(jobs.AsyncScanRequest{URL: "https://example.com"}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	job, err := h.jobQueue.Dequeue(time.Second)
+	if err != nil || job == nil {
+		t.Fatalf("Dequeue = %v, %v", job, err)
+	}
+	if _, err := h.jobQueue.FailJob(job, "404 Not Found", false); err != nil {
+		t.Fatalf("FailJob: %v", err)
+	}
+
+	call := func(handler http.HandlerFunc, method, target, key string) int {
+		req := httptest.NewRequest(method, target, nil)
+		req.SetPathValue("id", job.ID)
+		if key != "" {
+			req.Header.Set("X-Admin-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec.Code
+	}
+	retryTarget := "/scan/dead-letter/" + job.ID + "/retry"
+	for _, key := range []string{"", "wrong"} {
+		if status := call(h.DeadLetterHandler, http.MethodGet, "/scan/dead-letter", key); status != http.StatusForbidden {
+			t.Errorf("listing with X-Admin-Key %q: status = %d, want %d", key, status, http.StatusForbidden)
+		}
+		if status := call(h.RetryDeadLetterHandler, http.MethodPost, retryTarget, key); status != http.StatusForbidden {
+			t.Errorf("retrying with X-Admin-Key %q: status = %d, want %d", key, status, http.StatusForbidden)
+		}
+	}
+	if got, _ := h.jobQueue.GetJob(job.ID); got.Status != jobs.StatusFailed {
+		t.Errorf("job status = %s after refused retries, want %s", got.Status, jobs.StatusFailed)
+	}
+
+	if status := call(h.DeadLetterHandler, http.MethodGet, "/scan/dead-letter", "admin"); status != http.StatusOK {
+		t.Errorf("admin listing: status = %d, want %d", status, http.StatusOK)
+	}
+	if status := call(h.RetryDeadLetterHandler, http.MethodPost, retryTarget, "admin"); status != http.StatusAccepted {
+		t.Errorf("admin retry: status = %d, want %d", status, http.StatusAccepted)
+	}
+}
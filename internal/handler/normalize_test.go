@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeEmails(t *testing.T) {
+	h := newTestHandler(t)
+
+	tests := []struct {
+		name  string
+		dedup bool
+		body  string
+		want  []string
+	}{
+		{
+			"messy",
+			true,
+			`{"emails":["  Sales@Example.com", "info@example.com", "SALES@EXAMPLE.COM\n", "", "   ", "Zed@Example.org", "info@example.com"]}`,
+			[]string{"info@example.com", "sales@example.com", "zed@example.org"},
+		},
+		{"empty", true, `{"emails":[]}`, []string{}},
+		{"missing", true, `{}`, []string{}},
+		{"dedup off", false, `{"emails":["B@example.com", "a@example.com", "B@example.com"]}`, []string{"B@example.com", "a@example.com", "B@example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h.config.DeduplicateEmails = tt.dedup
+			rec := postJSON(h.NormalizeEmailsHandler, "/emails/normalize", tt.body)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, http.StatusOK, rec.Body.String())
+			}
+			var response struct {
+				Emails []string `json:"emails"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("decoding %s: %v", rec.Body.String(), err)
+			}
+			if !reflect.DeepEqual(response.Emails, tt.want) {
+				t.Errorf("emails = %q, want %q", response.Emails, tt.want)
+			}
+		})
+	}
+
+	if rec := postJSON(h.NormalizeEmailsHandler, "/emails/normalize", `{"emails":`); rec.Code != http.StatusBadRequest {
+		t.Errorf("malformed body: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	rec := httptest.NewRecorder()
+	h.NormalizeEmailsHandler(rec, httptest.NewRequest(http.MethodGet, "/emails/normalize", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET: status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"time"
+
+	"email-crawler/internal/cache"
+	"email-crawler/internal/crawler"
+	"email-crawler/internal/metrics"
+)
+
+// revalidate re-crawls queryURL in the background and caches the result under
+// cacheKey, replacing a stale entry. The cache's refresh lock keeps it to one
+// refresh per URL at a time, across instances when the cache is Redis. The
+// crawl is bounded by ASYNC_JOB_TIMEOUT_SECONDS, which is also the lock's TTL.
+func (h *Handler) revalidate(cacheManager cache.Cache, cacheKey, queryURL string, startURL *url.URL, opts crawler.Options) {
+	if !cacheManager.LockRefresh(cacheKey, h.config.AsyncJobTimeout) {
+		return
+	}
+
+	go func() {
+		defer cacheManager.UnlockRefresh(cacheKey)
+
+		startTime := time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), h.config.AsyncJobTimeout)
+		defer cancel()
+
+		c := crawler.NewWithOptions(opts)
+		foundEmailsMap := c.CrawlWithContext(ctx, startURL)
+		cacheManager.MarkCrawled(queryURL, h.config.ScanMinInterval)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			// Keep serving the stale result rather than caching a partial one
+			metrics.ObserveCrawl(queryURL, time.Since(startTime), len(foundEmailsMap), metrics.OutcomeTimeout)
+			log.Printf("Background refresh of %s timed out", queryURL)
+			return
+		}
+		// A site that is briefly down must not replace the stale result
+		if startErr := c.StartPageError(); startErr != nil && len(foundEmailsMap) == 0 {
+			metrics.ObserveCrawl(queryURL, time.Since(startTime), 0, metrics.OutcomeError)
+			log.Printf("Background refresh of %s could not fetch the start page: %v", queryURL, startErr)
+			return
+		}
+		metrics.ObserveCrawl(queryURL, time.Since(startTime), len(foundEmailsMap), metrics.OutcomeSuccess)
+
+		emailList := make([]string, 0, len(foundEmailsMap))
+		for email := range foundEmailsMap {
+			emailList = append(emailList, email)
+		}
+		if h.config.CrawlerValidateMX {
+			emailList = h.emailChecker.DropUndeliverable(ctx, emailList)
+		}
+
+		if err := cacheManager.Set(cacheKey, cache.CachedResult{
+			Emails:    emailList,
+			CrawlInfo: cache.CrawlInfo{Depth: opts.MaxDepth, PagesVisited: c.PagesVisited(), DepthReached: c.MaxDepthReached(), Truncated: c.Truncated()},
+			Labels:    c.Labels(),
+
+			OriginalCase: c.OriginalCase(),
+			Sources:      c.Sources(),
+		}); err != nil {
+			log.Printf("Background refresh of %s failed to cache: %v", queryURL, err)
+		}
+	}()
+}
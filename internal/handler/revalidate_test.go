@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"email-crawler/internal/cache"
+	"email-crawler/internal/crawler"
+)
+
+func TestRevalidateKeepsStaleResultWhenSiteIsDown(t *testing.T) {
+	h := newTestHandler(t)
+	h.config.AllowPrivateTargets = true
+
+	var down atomic.Bool
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			http.Error(w, "maintenance", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body>new@site.test</body></html>`)
+	}))
+	defer site.Close()
+	startURL, _ := url.Parse(site.URL)
+	opts := crawler.OptionsFromConfig(h.config)
+
+	// refresh runs revalidate and waits for the background crawl to finish
+	refresh := func() {
+		t.Helper()
+		h.revalidate(h.cacheManager, site.URL, site.URL, startURL, opts)
+		deadline := time.Now().Add(10 * time.Second)
+		for !h.cacheManager.LockRefresh(site.URL, time.Second) {
+			if time.Now().After(deadline) {
+				t.Fatal("background refresh did not finish")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		h.cacheManager.UnlockRefresh(site.URL)
+	}
+
+	stale := cache.CachedResult{Emails: []string{"old@site.test"}}
+	if err := h.cacheManager.Set(site.URL, stale); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	cachedEmails := func() []string {
+		cached, found := h.cacheManager.Get(site.URL)
+		if !found {
+			return nil
+		}
+		return cached.Emails
+	}
+
+	down.Store(true)
+	refresh()
+	if emails := cachedEmails(); !reflect.DeepEqual(emails, stale.Emails) {
+		t.Errorf("cached emails after a failed refresh = %v, want the stale %v", emails, stale.Emails)
+	}
+
+	down.Store(false)
+	refresh()
+	if emails := cachedEmails(); !reflect.DeepEqual(emails, []string{"new@site.test"}) {
+		t.Errorf("cached emails after a successful refresh = %v, want [new@site.test]", emails)
+	}
+}
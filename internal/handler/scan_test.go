@@ -0,0 +1,326 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"email-crawler/internal/cache"
+)
+
+// getScan calls handler with a GET of target and decodes the scan response.
+func getScan(t *testing.T, handler http.HandlerFunc, target string) (int, ScanResponse) {
+	t.Helper()
+	return serveScan(t, handler, httptest.NewRequest(http.MethodGet, target, nil))
+}
+
+// serveScan calls handler with req and decodes the scan response.
+func serveScan(t *testing.T, handler http.HandlerFunc, req *http.Request) (int, ScanResponse) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	var response ScanResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decoding %s: %v", rec.Body.String(), err)
+	}
+	return rec.Code, response
+}
+
+func TestScanDoesNotCacheCrawlCutShortByDisconnect(t *testing.T) {
+	h := newTestHandler(t)
+	h.config.AllowPrivateTargets = true
+	h.config.CrawlerConcurrency = 1
+
+	ctx, disconnect := context.WithCancel(context.Background())
+	defer disconnect()
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/contact" {
+			// The client goes away while the crawl is still running
+			disconnect()
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body>info@site.test <a href="/contact">Contact</a> <a href="/about">About</a></body></html>`)
+	}))
+	defer site.Close()
+
+	req := httptest.NewRequest(http.MethodGet, "/scan?url="+url.QueryEscape(site.URL), nil).WithContext(ctx)
+	h.ScanHandler(httptest.NewRecorder(), req)
+
+	if cached, found := h.cacheManager.Get(site.URL); found {
+		t.Fatalf("partial crawl was cached: %+v", cached)
+	}
+}
+
+func TestScanMaxTimeReturnsPartialResultsWithinBudget(t *testing.T) {
+	h := newTestHandler(t)
+	h.config.AllowPrivateTargets = true
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			// Answers long after the budget, unless the crawl gives up first
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(10 * time.Second):
+			}
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body>info@site.test <a href="/slow">Team</a></body></html>`)
+	}))
+	defer site.Close()
+
+	const budget = 500 * time.Millisecond
+	start := time.Now()
+	code, response := getScan(t, h.ScanHandler, "/scan?max_time=500ms&url="+url.QueryEscape(site.URL))
+	if elapsed := time.Since(start); elapsed > budget+time.Second {
+		t.Errorf("scan took %s with a %s budget", elapsed, budget)
+	}
+	if code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (error %q)", code, http.StatusOK, response.Error)
+	}
+	if !response.TimedOut {
+		t.Error("timed_out not set on a crawl that ran out of time")
+	}
+	if len(response.Emails) != 1 || response.Emails[0] != "info@site.test" {
+		t.Errorf("emails = %v, want the start page's info@site.test", response.Emails)
+	}
+}
+
+func TestScanMinIntervalServesCacheOrBlocksRecrawl(t *testing.T) {
+	h := newTestHandler(t)
+	h.config.AllowPrivateTargets = true
+	h.config.ScanMinInterval = time.Minute
+	h.config.AdminAPIKey = "admin"
+
+	var fetches int
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body>info@site.test</body></html>`)
+	}))
+	defer site.Close()
+	target := "/scan?depth=0&url=" + url.QueryEscape(site.URL)
+
+	if code, response := getScan(t, h.ScanHandler, target); code != http.StatusOK || response.FromCache {
+		t.Fatalf("first scan = %d, from_cache %v; want a fresh crawl", code, response.FromCache)
+	}
+	if code, response := getScan(t, h.ScanHandler, target); code != http.StatusOK || !response.FromCache {
+		t.Errorf("second scan = %d, from_cache %v; want the cached result", code, response.FromCache)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ScanHandler(rec, httptest.NewRequest(http.MethodGet, target+"&refresh=true", nil))
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("forced rescan = %d with Retry-After %q, want %d with a Retry-After", rec.Code, rec.Header().Get("Retry-After"), http.StatusTooManyRequests)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, target+"&refresh=true", nil)
+	req.Header.Set("X-Admin-Key", "admin")
+	if code, response := serveScan(t, h.ScanHandler, req); code != http.StatusOK || response.FromCache {
+		t.Errorf("admin rescan = %d, from_cache %v; want a fresh crawl", code, response.FromCache)
+	}
+	if fetches != 2 {
+		t.Errorf("site was fetched %d times, want 2 (first scan and admin rescan)", fetches)
+	}
+}
+
+func TestScanDebugRawReturnsContactPageHTMLToAdmins(t *testing.T) {
+	h := newTestHandler(t)
+	h.config.AllowPrivateTargets = true
+	h.config.AdminAPIKey = "admin"
+
+	const contactPage = `<html><body><p>Write to <span data-user="sales"></span>us.</p></body></html>`
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/contact" {
+			fmt.Fprint(w, contactPage)
+			return
+		}
+		fmt.Fprint(w, `<html><body>info@site.test <a href="/contact">Contact</a></body></html>`)
+	}))
+	defer site.Close()
+	target := "/scan?debug=raw&url=" + url.QueryEscape(site.URL)
+
+	if code, _ := getScan(t, h.ScanHandler, target); code != http.StatusForbidden {
+		t.Errorf("debug=raw without the admin key = %d, want %d", code, http.StatusForbidden)
+	}
+	// A cached result has no HTML to show, so debug scans always crawl
+	if code, _ := getScan(t, h.ScanHandler, "/scan?url="+url.QueryEscape(site.URL)); code != http.StatusOK {
+		t.Fatalf("plain scan = %d, want %d", code, http.StatusOK)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.Header.Set("X-Admin-Key", "admin")
+	code, response := serveScan(t, h.ScanHandler, req)
+	if code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (error %q)", code, http.StatusOK, response.Error)
+	}
+	if raw := response.RawHTML[site.URL+"/contact"]; raw != contactPage {
+		t.Errorf("raw HTML of the contact page = %q, want %q", raw, contactPage)
+	}
+	if response.FromCache {
+		t.Error("debug=raw was served from cache")
+	}
+}
+
+func TestScanPreserveCaseReturnsFirstSeenCasing(t *testing.T) {
+	h := newTestHandler(t)
+	h.config.AllowPrivateTargets = true
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body>John.Smith@Acme.test Sales@Site.test john.smith@acme.test</body></html>`)
+	}))
+	defer site.Close()
+	target := "/scan?depth=0&url=" + url.QueryEscape(site.URL)
+
+	tests := []struct {
+		name      string
+		query     string
+		fromCache bool
+		want      []string
+	}{
+		{"fresh crawl", "&preserve_case=true", false, []string{"John.Smith@Acme.test", "Sales@Site.test"}},
+		{"cached", "&preserve_case=true", true, []string{"John.Smith@Acme.test", "Sales@Site.test"}},
+		{"default", "", true, []string{"john.smith@acme.test", "sales@site.test"}},
+	}
+	for _, tt := range tests {
+		code, response := getScan(t, h.ScanHandler, target+tt.query)
+		if code != http.StatusOK || response.FromCache != tt.fromCache {
+			t.Fatalf("%s: status %d, from_cache %v; want %d, %v", tt.name, code, response.FromCache, http.StatusOK, tt.fromCache)
+		}
+		sort.Strings(response.Emails)
+		if !reflect.DeepEqual(response.Emails, tt.want) {
+			t.Errorf("%s: emails = %v, want %v", tt.name, response.Emails, tt.want)
+		}
+	}
+}
+
+func TestScanCountOnlyOmitsAddresses(t *testing.T) {
+	h := newTestHandler(t)
+	h.config.AllowPrivateTargets = true
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body>Sales: sales@site.test, press@site.test</body></html>`)
+	}))
+	defer site.Close()
+	target := "/scan?count_only=true&include=labels,sources&url=" + url.QueryEscape(site.URL)
+
+	for _, fromCache := range []bool{false, true} {
+		rec := httptest.NewRecorder()
+		h.ScanHandler(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d (body %s)", rec.Code, http.StatusOK, rec.Body.String())
+		}
+		var body map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decoding %s: %v", rec.Body.String(), err)
+		}
+		if body["email_count"] != 2.0 || body["pages_visited"] != 1.0 || body["from_cache"] != fromCache {
+			t.Errorf("response = %s, want email_count 2, pages_visited 1 and from_cache %v", rec.Body.String(), fromCache)
+		}
+		if strings.Contains(rec.Body.String(), "@") {
+			t.Errorf("count_only response reveals an address: %s", rec.Body.String())
+		}
+	}
+
+	// The full result is still cached for later requests
+	if cached, found := h.cacheManager.Get(site.URL); !found || len(cached.Emails) != 2 {
+		t.Errorf("cached result = %+v, %v; want both addresses", cached, found)
+	}
+}
+
+func TestScanPostBodyOptionsAreHonoured(t *testing.T) {
+	h := newTestHandler(t)
+	h.config.AllowPrivateTargets = true
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/products":
+			fmt.Fprint(w, `<html><body>products@site.test <a href="/products/deep">More</a></body></html>`)
+		case "/products/deep":
+			fmt.Fprint(w, `<html><body>deep@site.test</body></html>`)
+		case "/private":
+			fmt.Fprint(w, `<html><body>private@site.test</body></html>`)
+		default:
+			fmt.Fprint(w, `<html><body><p>Sales: Sales@Site.test</p> <a href="/products">Products</a> <a href="/private">Private</a></body></html>`)
+		}
+	}))
+	defer site.Close()
+
+	body := fmt.Sprintf(`{"url":%q,"max_depth":1,"exclude":["^/private"],"include":["labels"],"preserve_case":true}`, site.URL)
+	rec := postJSON(h.ScanHandler, "/scan", body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (body %s)", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var response ScanResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decoding %s: %v", rec.Body.String(), err)
+	}
+	sort.Strings(response.Emails)
+	if want := []string{"Sales@Site.test", "products@site.test"}; !reflect.DeepEqual(response.Emails, want) {
+		t.Errorf("emails = %v, want %v (depth 1, /private excluded, original case)", response.Emails, want)
+	}
+	if response.Labels["Sales@Site.test"] != "Sales" {
+		t.Errorf("labels = %v, want Sales@Site.test labelled Sales", response.Labels)
+	}
+
+	if rec := postJSON(h.ScanHandler, "/scan", `{"url":`); rec.Code != http.StatusBadRequest {
+		t.Errorf("malformed body: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestScanDepthCachesEachDepthSeparately(t *testing.T) {
+	h := newTestHandler(t)
+	h.config.AllowPrivateTargets = true
+	h.config.MaxDepth = 2
+
+	links := map[string]string{"/": "/a", "/a": "/b"}
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/")
+		if name == "" {
+			name = "home"
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<html><body><p>%s@site.test</p> <a href="%s">next</a></body></html>`, name, links[r.URL.Path])
+	}))
+	defer site.Close()
+	scanAt := func(depth int) ScanResponse {
+		t.Helper()
+		status, resp := getScan(t, h.ScanHandler, fmt.Sprintf("/scan?depth=%d&url=%s", depth, url.QueryEscape(site.URL)))
+		if status != http.StatusOK {
+			t.Fatalf("depth %d: status = %d, want %d (%s)", depth, status, http.StatusOK, resp.Error)
+		}
+		return resp
+	}
+
+	if resp := scanAt(1); resp.FromCache || len(resp.Emails) != 2 {
+		t.Errorf("depth 1 scan = %v (from cache %v), want 2 fresh emails", resp.Emails, resp.FromCache)
+	}
+	// The shallow result must not stand in for a deeper crawl
+	if resp := scanAt(3); resp.FromCache || len(resp.Emails) != 3 {
+		t.Errorf("depth 3 scan = %v (from cache %v), want 3 fresh emails", resp.Emails, resp.FromCache)
+	}
+	if resp := scanAt(1); !resp.FromCache || len(resp.Emails) != 2 {
+		t.Errorf("repeated depth 1 scan = %v (from cache %v), want its own 2 cached emails", resp.Emails, resp.FromCache)
+	}
+	for depth, want := range map[int]int{1: 2, 3: 3} {
+		key := cache.DepthKey(site.URL, depth, h.config.MaxDepth)
+		if cached, found := h.cacheManager.Get(key); !found || len(cached.Emails) != want || !cached.CoversDepth(depth) {
+			t.Errorf("cache entry for depth %d = %+v, %v; want %d emails", depth, cached, found, want)
+		}
+	}
+	if _, found := h.cacheManager.Get(site.URL); found {
+		t.Error("non-default depths were cached under the default-depth key")
+	}
+}
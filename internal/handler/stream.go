@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"email-crawler/internal/crawler"
+	"email-crawler/internal/metrics"
+)
+
+// streamDone is the final event of GET /scan/stream.
+type streamDone struct {
+	Emails       []string `json:"emails"`
+	PagesVisited int      `json:"pages_visited"`
+	DepthReached int      `json:"depth_reached"`
+	Truncated    bool     `json:"truncated,omitempty"`
+	CrawlTime    string   `json:"crawl_time"`
+}
+
+// streamBuffer is how many progress events can wait to be written while the
+// crawl goes on.
+const streamBuffer = 256
+
+// streamEvent is a server-sent event waiting to be written.
+type streamEvent struct {
+	name string
+	data interface{}
+}
+
+// StreamScanHandler crawls like GET /scan but reports progress as server-sent
+// events: "page" for each page fetched, "email" for each new email and a final
+// "done" with the full result. Streams always crawl and aren't cached.
+// Disconnecting cancels the crawl.
+func (h *Handler) StreamScanHandler(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+	w.Header().Set("Content-Type", "application/json")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ScanResponse{Error: "Streaming is not supported"})
+		return
+	}
+
+	queryURL := r.URL.Query().Get("url")
+	if queryURL == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ScanResponse{Error: "Missing 'url' parameter"})
+		return
+	}
+	if !strings.HasPrefix(queryURL, "http://") && !strings.HasPrefix(queryURL, "https://") {
+		queryURL = "https://" + queryURL
+	}
+	startURL, err := url.Parse(queryURL)
+	if err != nil || (startURL.Scheme != "http" && startURL.Scheme != "https") {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ScanResponse{Error: "Invalid URL provided"})
+		return
+	}
+	if !crawler.DomainAllowed(startURL.Hostname(), h.config.CrawlerAllowedDomains) {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(ScanResponse{Error: domainNotAllowed(startURL)})
+		return
+	}
+	if err := h.privateTarget(r.Context(), startURL); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ScanResponse{Error: fmt.Sprintf("URL not allowed: %v", err)})
+		return
+	}
+
+	_, tenant, err := h.tenantFor(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ScanResponse{Error: err.Error()})
+		return
+	}
+	cacheManager := h.cacheFor(tenant)
+	if wait := h.cooldownRemaining(r, cacheManager, queryURL); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait.Round(time.Second).Seconds())))
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(ScanResponse{Error: fmt.Sprintf("URL was scanned recently, try again in %s", wait.Round(time.Second))})
+		return
+	}
+	metrics.ObserveScan(metrics.ModeSync)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// The crawler calls OnPage and OnEmail with its lock held, so they only
+	// queue the event; this goroutine writes them while the crawl runs
+	events := make(chan streamEvent, streamBuffer)
+	queueEvent := func(event streamEvent) {
+		select {
+		case events <- event:
+		case <-r.Context().Done():
+		}
+	}
+	opts := crawler.OptionsForTenant(h.config, tenant)
+	opts.OnPage = func(pageURL string, depth int) {
+		queueEvent(streamEvent{"page", map[string]interface{}{"url": pageURL, "depth": depth}})
+	}
+	opts.OnEmail = func(email, pageURL string) {
+		queueEvent(streamEvent{"email", map[string]string{"email": email, "url": pageURL}})
+	}
+	c := crawler.NewWithOptions(opts)
+	var foundEmailsMap map[string]bool
+	go func() {
+		defer close(events)
+		foundEmailsMap = c.CrawlWithContext(r.Context(), startURL)
+	}()
+	for event := range events {
+		writeEvent(w, flusher, event.name, event.data)
+	}
+	cacheManager.MarkCrawled(queryURL, h.config.ScanMinInterval)
+	if r.Context().Err() != nil {
+		// The client went away; there is no one left to send "done" to
+		return
+	}
+	metrics.ObserveCrawl(queryURL, time.Since(startTime), len(foundEmailsMap), metrics.OutcomeSuccess)
+
+	emailList := make([]string, 0, len(foundEmailsMap))
+	for email := range foundEmailsMap {
+		emailList = append(emailList, email)
+	}
+	if h.config.CrawlerValidateMX {
+		emailList = h.emailChecker.DropUndeliverable(r.Context(), emailList)
+	}
+	emailList = cacheManager.DeduplicateEmails(emailList)
+	if emailList == nil {
+		emailList = []string{} // Ensure [] instead of null
+	}
+	writeEvent(w, flusher, "done", streamDone{
+		Emails:       emailList,
+		PagesVisited: c.PagesVisited(),
+		DepthReached: c.MaxDepthReached(),
+		Truncated:    c.Truncated(),
+		CrawlTime:    time.Since(startTime).String(),
+	})
+}
+
+// writeEvent sends one server-sent event with v as its JSON data.
+func writeEvent(w http.ResponseWriter, flusher http.Flusher, name string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+	flusher.Flush()
+}
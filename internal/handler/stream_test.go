@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestStreamScanReportsEveryEvent(t *testing.T) {
+	h := newTestHandler(t)
+	h.config.AllowPrivateTargets = true
+	h.config.CrawlerConcurrency = 4
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<html><body>
+				<a href="/contact">Contact</a> <a href="/team">Team</a> <a href="/about">About</a>
+			</body></html>`)
+			return
+		}
+		fmt.Fprintf(w, `<html><body>%s@site.test</body></html>`, strings.Trim(r.URL.Path, "/"))
+	}))
+	defer site.Close()
+
+	rec := httptest.NewRecorder()
+	h.StreamScanHandler(rec, httptest.NewRequest(http.MethodGet, "/scan/stream?url="+url.QueryEscape(site.URL), nil))
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream (body %s)", ct, rec.Body.String())
+	}
+
+	var pages int
+	var emails []string
+	var done streamDone
+	var name string
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data := []byte(strings.TrimPrefix(line, "data: "))
+			switch name {
+			case "page":
+				pages++
+			case "email":
+				var event map[string]string
+				json.Unmarshal(data, &event)
+				emails = append(emails, event["email"])
+			case "done":
+				if err := json.Unmarshal(data, &done); err != nil {
+					t.Fatalf("decoding done event: %v", err)
+				}
+			}
+		}
+	}
+
+	if name != "done" {
+		t.Fatalf("last event = %q, want done", name)
+	}
+	if pages != 4 || pages != done.PagesVisited {
+		t.Errorf("got %d page events, want 4 matching pages_visited %d", pages, done.PagesVisited)
+	}
+	sort.Strings(emails)
+	if want := []string{"about@site.test", "contact@site.test", "team@site.test"}; strings.Join(emails, ",") != strings.Join(want, ",") ||
+		strings.Join(done.Emails, ",") != strings.Join(want, ",") {
+		t.Errorf("email events = %v, done emails = %v; want %v", emails, done.Emails, want)
+	}
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"email-crawler/internal/config"
+)
+
+func TestTenantsGetTheirOwnDepthAndCache(t *testing.T) {
+	h := newTestHandler(t)
+	h.config.AllowPrivateTargets = true
+	h.config.MaxDepth = 1
+	shallow, deep := 0, 1
+	h.config.Tenants = map[string]*config.TenantConfig{
+		"shallow": {MaxDepth: &shallow, CachePrefix: "shallow"},
+		"deep":    {MaxDepth: &deep, CachePrefix: "deep", APIKeys: []string{"deep-key"}},
+	}
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/products" {
+			fmt.Fprint(w, `<html><body>products@site.test</body></html>`)
+			return
+		}
+		fmt.Fprint(w, `<html><body>info@site.test <a href="/products">Products</a></body></html>`)
+	}))
+	defer site.Close()
+	target := "/scan?url=" + url.QueryEscape(site.URL)
+
+	scan := func(header, value string) ScanResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		code, response := serveScan(t, h.ScanHandler, req)
+		if code != http.StatusOK {
+			t.Fatalf("%s %s: status = %d, want %d (error %q)", header, value, code, http.StatusOK, response.Error)
+		}
+		return response
+	}
+
+	if response := scan("X-Tenant-ID", "shallow"); len(response.Emails) != 1 || *response.PagesVisited != 1 {
+		t.Errorf("shallow tenant found %v on %d pages, want only the start page", response.Emails, *response.PagesVisited)
+	}
+	if response := scan("X-API-Key", "deep-key"); response.FromCache || len(response.Emails) != 2 {
+		t.Errorf("deep tenant got %v (from_cache %v), want a fresh crawl finding both addresses", response.Emails, response.FromCache)
+	}
+	if response := scan("X-Tenant-ID", "shallow"); !response.FromCache || len(response.Emails) != 1 {
+		t.Errorf("shallow tenant got %v (from_cache %v), want its own cached result", response.Emails, response.FromCache)
+	}
+	if response := scan("", ""); response.FromCache {
+		t.Error("a request without a tenant was served a tenant's cached result")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.Header.Set("X-Tenant-ID", "unknown")
+	if code, _ := serveScan(t, h.ScanHandler, req); code != http.StatusBadRequest {
+		t.Errorf("unknown tenant: status = %d, want %d", code, http.StatusBadRequest)
+	}
+}
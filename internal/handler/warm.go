@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"email-crawler/internal/crawler"
+	"email-crawler/internal/jobs"
+)
+
+// CacheWarmRequest is the JSON body accepted by POST /cache/warm.
+type CacheWarmRequest struct {
+	URLs []string `json:"urls"`
+}
+
+// CacheWarmResponse summarizes a warm request. Mode is "async" when crawls
+// were queued as jobs and "sync" when they run in the background of this
+// instance. Invalid lists URLs that don't parse or may not be crawled.
+type CacheWarmResponse struct {
+	Mode    string   `json:"mode"`
+	Queued  int      `json:"queued"`
+	Skipped int      `json:"skipped"`
+	Invalid []string `json:"invalid,omitempty"`
+}
+
+// WarmCacheHandler pre-populates the cache for up to CACHE_WARM_MAX_URLS URLs.
+// URLs with a fresh cached result are skipped. The rest are queued as async
+// jobs without a webhook or, with async disabled, crawled in the background
+// BATCH_CONCURRENCY at a time. It answers 202 without waiting for any crawl.
+func (h *Handler) WarmCacheHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed. Use POST."})
+		return
+	}
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Content-Type must be application/json"})
+		return
+	}
+
+	var req CacheWarmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid JSON format"})
+		return
+	}
+	if len(req.URLs) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Missing 'urls' field"})
+		return
+	}
+	if len(req.URLs) > h.config.CacheWarmMaxURLs {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Too many URLs: at most %d per request", h.config.CacheWarmMaxURLs)})
+		return
+	}
+
+	tenantID, tenant, err := h.tenantFor(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	cacheManager := h.cacheFor(tenant)
+	depth := crawler.OptionsForTenant(h.config, tenant).MaxDepth
+
+	response := CacheWarmResponse{Mode: "sync"}
+	if h.config.AsyncEnabled {
+		response.Mode = "async"
+	}
+	var pending []string
+	for _, rawURL := range req.URLs {
+		if !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "https://") {
+			rawURL = "https://" + rawURL
+		}
+		parsedURL, err := url.Parse(rawURL)
+		if err != nil || !crawler.DomainAllowed(parsedURL.Hostname(), h.config.CrawlerAllowedDomains) || h.privateTarget(r.Context(), parsedURL) != nil {
+			response.Invalid = append(response.Invalid, rawURL)
+			continue
+		}
+
+		if cached, found := cacheManager.Get(rawURL); found && cached.CoversDepth(depth) && !cached.IsStale(h.config.CacheExpirationTime) {
+			response.Skipped++
+			continue
+		}
+
+		if h.config.AsyncEnabled {
+			if _, err := h.jobQueue.Enqueue(jobs.AsyncScanRequest{URL: rawURL, TenantID: tenantID}); err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Failed to queue job: %v", err)})
+				return
+			}
+		} else {
+			pending = append(pending, rawURL)
+		}
+		response.Queued++
+	}
+
+	if len(pending) > 0 {
+		// The crawls outlive this request, so they get a copy of it that
+		// keeps the tenant and admin headers but is never cancelled
+		go h.warm(r.Clone(context.Background()), pending)
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(response)
+}
+
+// warm scans urls through the sync scan path, BATCH_CONCURRENCY at a time, so
+// their results are cached.
+func (h *Handler) warm(r *http.Request, urls []string) {
+	concurrency := h.config.BatchConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, rawURL := range urls {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(rawURL string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			if status, response := h.scan(r, url.Values{"url": {rawURL}}); status != http.StatusOK {
+				log.Printf("Cache warm of %s failed: %s", rawURL, response.Error)
+			}
+		}(rawURL)
+	}
+	wg.Wait()
+	log.Printf("Cache warm finished for %d URLs", len(urls))
+}
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"email-crawler/internal/jobs"
+)
+
+func TestWebhookTestReportsReceiverStatus(t *testing.T) {
+	h := newTestHandler(t)
+	h.config.AllowPrivateTargets = true
+
+	var received []jobs.WebhookPayload
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Webhook-Test") != "true" || r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var payload jobs.WebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received = append(received, payload)
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		delivered  bool
+	}{
+		{"/hook", http.StatusNoContent, true},
+		{"/broken", http.StatusInternalServerError, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			body := fmt.Sprintf(`{"webhook_url":%q,"webhook_headers":{"X-Token":"secret"}}`, receiver.URL+tt.path)
+			rec := postJSON(h.WebhookTestHandler, "/scan/webhook/test", body)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, http.StatusOK, rec.Body.String())
+			}
+			var result jobs.WebhookProbeResult
+			if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+				t.Fatalf("decoding %s: %v", rec.Body.String(), err)
+			}
+			if result.StatusCode != tt.wantStatus || result.Delivered != tt.delivered || result.Latency == "" {
+				t.Errorf("result = %+v, want status %d, delivered %v and a latency", result, tt.wantStatus, tt.delivered)
+			}
+		})
+	}
+
+	if len(received) != len(tests) || received[0].Status != jobs.StatusCompleted {
+		t.Errorf("receiver got %+v, want one sample completed payload per probe", received)
+	}
+	if _, total, _ := h.jobQueue.ListJobs("", 10, 0); total != 0 {
+		t.Errorf("probing created %d jobs, want none", total)
+	}
+}
+
+func TestWebhookTestRefusesPrivateReceivers(t *testing.T) {
+	h := newTestHandler(t)
+	for _, webhookURL := range []string{"http://127.0.0.1:8080/hook", "http://169.254.169.254/latest"} {
+		rec := postJSON(h.WebhookTestHandler, "/scan/webhook/test", fmt.Sprintf(`{"webhook_url":%q}`, webhookURL))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("probing %s: status = %d, want %d", webhookURL, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestNotifyOnEnqueueSendsQueuedThenTerminalCallback(t *testing.T) {
+	h := newTestHandler(t)
+	h.config.AllowPrivateTargets = true
+	t.Cleanup(func() { h.workerPool.Resize(0) })
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body>info@site.test</body></html>`)
+	}))
+	defer site.Close()
+	callbacks := make(chan jobs.WebhookPayload, 4)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload jobs.WebhookPayload
+		json.NewDecoder(r.Body).Decode(&payload)
+		callbacks <- payload
+	}))
+	defer receiver.Close()
+
+	body := fmt.Sprintf(`{"url":%q,"webhook_url":%q,"notify_on_enqueue":true,"max_depth":0}`, site.URL, receiver.URL)
+	if rec := postJSON(h.AsyncScanHandler, "/scan/async", body); rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d (body %s)", rec.Code, http.StatusAccepted, rec.Body.String())
+	}
+
+	next := func() jobs.WebhookPayload {
+		t.Helper()
+		select {
+		case payload := <-callbacks:
+			return payload
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for a callback")
+			return jobs.WebhookPayload{}
+		}
+	}
+	if queued := next(); queued.Status != jobs.StatusQueued || len(queued.Emails) != 0 {
+		t.Errorf("first callback = %+v, want a queued one without results", queued)
+	}
+
+	// Only process the job once the queued callback is in
+	h.workerPool.Resize(1)
+	if done := next(); done.Status != jobs.StatusCompleted || len(done.Emails) != 1 {
+		t.Errorf("second callback = %+v, want the completed job with its email", done)
+	}
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestScaleWorkersBounds(t *testing.T) {
+	h := newTestHandler(t)
+	h.config.AdminAPIKey = "secret"
+	h.config.AsyncMaxWorkers = 4
+	t.Cleanup(func() { h.workerPool.Resize(0) })
+
+	scale := func(body, adminKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/workers/scale", strings.NewReader(body))
+		req.Header.Set("X-Admin-Key", adminKey)
+		rec := httptest.NewRecorder()
+		h.ScaleWorkersHandler(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		adminKey string
+		want     int
+	}{
+		{"not admin", `{"workers":2}`, "wrong", http.StatusForbidden},
+		{"negative", `{"workers":-1}`, "secret", http.StatusBadRequest},
+		{"over ceiling", `{"workers":5}`, "secret", http.StatusBadRequest},
+		{"one", `{"workers":1}`, "secret", http.StatusOK},
+		{"standby", `{"workers":0}`, "secret", http.StatusOK},
+		{"ceiling", `{"workers":4}`, "secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := h.workerPool.Size()
+			rec := scale(tt.body, tt.adminKey)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.want, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK && h.workerPool.Size() != before {
+				t.Errorf("rejected request resized the pool from %d to %d", before, h.workerPool.Size())
+			}
+		})
+	}
+	if size := h.workerPool.Size(); size != 4 {
+		t.Errorf("pool size = %d, want 4", size)
+	}
+}
@@ -0,0 +1,50 @@
+package jobs
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// dedupID identifies the crawl a job performs for ASYNC_DEDUPLICATE_JOBS: its
+// tenant, depth and URL (lowercase host and path, like cache keys), and
+// whether it bypasses the cache. It is "" for scheduled and incremental
+// jobs, which are never deduplicated.
+func dedupID(job *ScanJob) string {
+	if job.Status == StatusScheduled || job.IfModifiedSince != nil {
+		return ""
+	}
+
+	normalizedURL := job.URL
+	if parsedURL, err := url.Parse(job.URL); err == nil {
+		normalizedURL = strings.TrimSuffix(strings.ToLower(parsedURL.Host)+parsedURL.Path, "/")
+	}
+	depth := "default"
+	if job.MaxDepth != nil {
+		depth = fmt.Sprint(*job.MaxDepth)
+	}
+	key := fmt.Sprintf("%s|%s|%t|%s", job.TenantID, depth, job.ForceRefresh, normalizedURL)
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(key)))
+}
+
+// idempotencyRecord is what an idempotency key maps to: the job it created and
+// the fingerprint of the request that created it.
+type idempotencyRecord struct {
+	JobID       string `json:"job_id"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// idempotencyID scopes the request's idempotency key to its tenant. It is ""
+// when the request has no key.
+func idempotencyID(req AsyncScanRequest) string {
+	if req.IdempotencyKey == "" {
+		return ""
+	}
+	return req.TenantID + ":" + req.IdempotencyKey
+}
+
+// finished reports whether a job in status will never run again.
+func finished(status JobStatus) bool {
+	return status == StatusCompleted || status == StatusFailed || status == StatusCancelled
+}
@@ -0,0 +1,78 @@
+package jobs
+
+import (
+	"sync"
+	"time"
+)
+
+// durationSamples is how many recently completed jobs estimates average over,
+// and minDurationSamples how many there must be before estimates are made.
+const (
+	durationSamples    = 20
+	minDurationSamples = 5
+)
+
+// jobDurations keeps how long the most recently completed jobs took, for
+// MemoryQueue.
+type jobDurations struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+func (d *jobDurations) add(duration time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.samples) < durationSamples {
+		d.samples = append(d.samples, duration)
+		return
+	}
+	d.samples[d.next] = duration
+	d.next = (d.next + 1) % durationSamples
+}
+
+// average returns the mean of the samples and how many there are.
+func (d *jobDurations) average() (time.Duration, int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return averageDuration(d.samples), len(d.samples)
+}
+
+// averageDuration returns the mean of samples, or 0 if there are none.
+func averageDuration(samples []time.Duration) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, sample := range samples {
+		total += sample
+	}
+	return total / time.Duration(len(samples))
+}
+
+// EstimateWait roughly estimates how long until a job at position in the
+// queue (1 is next) has been picked up, assuming every worker takes the
+// average of recently completed jobs. It reports false while there are fewer
+// than minDurationSamples of them or no workers are running.
+func (wp *WorkerPool) EstimateWait(position int) (time.Duration, bool) {
+	average, samples := wp.queue.AverageDuration()
+	workers := wp.Size()
+	if samples < minDurationSamples || workers == 0 || position < 1 {
+		return 0, false
+	}
+	rounds := (position + workers - 1) / workers
+	return time.Duration(rounds) * average, true
+}
+
+// EstimateCompletion is EstimateWait plus the average job itself: roughly
+// how long until a job at position has finished.
+func (wp *WorkerPool) EstimateCompletion(position int) (time.Duration, bool) {
+	wait, ok := wp.EstimateWait(position)
+	if !ok {
+		return 0, false
+	}
+	average, _ := wp.queue.AverageDuration()
+	return wait + average, true
+}
@@ -0,0 +1,637 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"email-crawler/internal/config"
+	"email-crawler/internal/crawler"
+)
+
+type memoryJob struct {
+	job       ScanJob
+	expiresAt time.Time
+}
+
+// MemoryQueue is an in-process JobStore. Jobs are lost on restart, so it is
+// only suitable for single-node deployments.
+type MemoryQueue struct {
+	mu     sync.Mutex
+	config *config.Config
+	jobs   map[string]*memoryJob
+	queue  []string
+	active map[string]bool
+	ready  chan struct{}
+
+	// delayed maps scheduled jobs and retries to when they are due
+	delayed map[string]time.Time
+	// cancelling holds processing jobs whose cancellation was requested
+	cancelling map[string]bool
+	// deadLetters holds jobs that failed for good, newest first
+	deadLetters []ScanJob
+	// dedup maps a crawl, identified by dedupID, to the job performing it
+	dedup map[string]string
+	// idempotency maps idempotency keys, scoped by idempotencyID, to the job
+	// they created
+	idempotency map[string]memoryIdempotency
+
+	frontiers map[string]*crawler.Frontier
+	durations jobDurations
+	// leases maps processing jobs to when their lease lapses
+	leases map[string]time.Time
+}
+
+func NewMemoryQueue(config *config.Config) *MemoryQueue {
+	return &MemoryQueue{
+		config: config,
+		jobs:   make(map[string]*memoryJob),
+		active: make(map[string]bool),
+		ready:  make(chan struct{}, 1),
+
+		delayed:    make(map[string]time.Time),
+		cancelling: make(map[string]bool),
+		dedup:      make(map[string]string),
+
+		idempotency: make(map[string]memoryIdempotency),
+
+		frontiers: make(map[string]*crawler.Frontier),
+		leases:    make(map[string]time.Time),
+	}
+}
+
+func (q *MemoryQueue) Enqueue(req AsyncScanRequest) (*ScanJob, error) {
+	jobID := uuid.New().String()
+
+	job := &ScanJob{
+		ID:         jobID,
+		URL:        req.URL,
+		WebhookURL: req.WebhookURL,
+		CallbackID: req.CallbackID,
+		Status:     StatusQueued,
+		CreatedAt:  time.Now(),
+
+		IfModifiedSince: req.IfModifiedSince,
+		TenantID:        req.TenantID,
+		MaxDepth:        req.MaxDepth,
+		ForceRefresh:    req.ForceRefresh,
+		WebhookCompress: req.WebhookCompress,
+		NotifyOnEnqueue: req.NotifyOnEnqueue,
+		WebhookHeaders:  req.WebhookHeaders,
+		WebhookURLs:     req.WebhookURLs,
+	}
+
+	schedule(job, req.RunAt)
+
+	q.mu.Lock()
+	if existing, err := q.claimIdempotencyKey(req, job.ID); existing != nil || err != nil {
+		q.mu.Unlock()
+		return existing, err
+	}
+	if existing := q.claimDedup(job); existing != nil {
+		q.setIdempotencyKey(req, existing.ID)
+		q.mu.Unlock()
+		log.Printf("Job %s already pending for URL: %s", existing.ID, req.URL)
+		return existing, nil
+	}
+	q.store(job)
+	if job.Status == StatusScheduled {
+		q.delayed[jobID] = *job.RunAt
+	} else {
+		q.queue = append(q.queue, jobID)
+	}
+	q.active[jobID] = true
+	q.mu.Unlock()
+
+	q.signal()
+
+	log.Printf("Job %s %s for URL: %s", jobID, job.Status, req.URL)
+	return job, nil
+}
+
+func (q *MemoryQueue) Dequeue(timeout time.Duration) (*ScanJob, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		q.mu.Lock()
+		for len(q.queue) > 0 {
+			jobID := q.queue[0]
+			q.queue = q.queue[1:]
+
+			entry, ok := q.lookup(jobID)
+			if !ok {
+				continue
+			}
+
+			now := time.Now()
+			entry.job.Status = StatusProcessing
+			entry.job.StartedAt = &now
+			q.store(&entry.job)
+
+			remaining := len(q.queue)
+			job := entry.job
+			q.mu.Unlock()
+
+			// Wake another waiter if there is still work queued
+			if remaining > 0 {
+				q.signal()
+			}
+			return &job, nil
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-q.ready:
+		case <-deadline.C:
+			return nil, nil // No jobs available
+		}
+	}
+}
+
+func (q *MemoryQueue) GetJob(jobID string) (*ScanJob, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	entry, ok := q.lookup(jobID)
+	if !ok {
+		return nil, fmt.Errorf("job not found")
+	}
+	job := entry.job
+	return &job, nil
+}
+
+func (q *MemoryQueue) RequeueJob(job *ScanJob) error {
+	job.Status = StatusQueued
+	job.StartedAt = nil
+
+	q.mu.Lock()
+	q.store(job)
+	q.queue = append([]string{job.ID}, q.queue...)
+	q.mu.Unlock()
+
+	q.signal()
+	return nil
+}
+
+func (q *MemoryQueue) QueuePosition(jobID string) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, id := range q.queue {
+		if id == jobID {
+			return i + 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (q *MemoryQueue) UpdateJob(job *ScanJob) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.store(job)
+	return nil
+}
+
+func (q *MemoryQueue) CompleteJob(job *ScanJob, emails []string, pagesVisited int, crawlTime string) error {
+	now := time.Now()
+	job.Status = StatusCompleted
+	job.CompletedAt = &now
+	job.Error = "" // Left over from an attempt that was retried
+	job.Emails = emails
+	job.PagesVisited = pagesVisited
+	job.CrawlTime = crawlTime
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.store(job)
+	delete(q.active, job.ID)
+	q.releaseDedup(job)
+	return nil
+}
+
+func (q *MemoryQueue) FailJob(job *ScanJob, errorMsg string, retryable bool) (bool, error) {
+	if delay, ok := retryJob(q.config, job, errorMsg, retryable); ok {
+		// The job stays active; PromoteDueJobs queues it once it is due
+		q.mu.Lock()
+		q.store(job)
+		q.delayed[job.ID] = time.Now().Add(delay)
+		q.mu.Unlock()
+		log.Printf("Job %s failed (%s), retry %d/%d in %s", job.ID, errorMsg, job.RetryCount, q.config.AsyncMaxRetries, delay)
+		return true, nil
+	}
+
+	now := time.Now()
+	job.Status = StatusFailed
+	job.CompletedAt = &now
+	job.Error = errorMsg
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.store(job)
+	delete(q.active, job.ID)
+	q.releaseDedup(job)
+	if size := q.config.AsyncDeadLetterSize; size > 0 {
+		q.deadLetters = append([]ScanJob{*job}, q.deadLetters...)
+		if len(q.deadLetters) > size {
+			q.deadLetters = q.deadLetters[:size]
+		}
+	}
+	return false, nil
+}
+
+func (q *MemoryQueue) ListJobs(status JobStatus, limit, offset int) ([]ScanJob, int, error) {
+	q.mu.Lock()
+	matches := []ScanJob{}
+	for jobID := range q.jobs {
+		entry, ok := q.lookup(jobID)
+		if !ok || (status != "" && entry.job.Status != status) {
+			continue
+		}
+		matches = append(matches, entry.job)
+	}
+	q.mu.Unlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].CreatedAt.After(matches[j].CreatedAt)
+	})
+	total := len(matches)
+	if offset >= total {
+		return []ScanJob{}, total, nil
+	}
+	matches = matches[offset:]
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, total, nil
+}
+
+func (q *MemoryQueue) DeadLetters() ([]ScanJob, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return append([]ScanJob{}, q.deadLetters...), nil
+}
+
+func (q *MemoryQueue) RetryDeadLetter(jobID string) (*ScanJob, error) {
+	q.mu.Lock()
+	for i, job := range q.deadLetters {
+		if job.ID != jobID {
+			continue
+		}
+		q.deadLetters = append(q.deadLetters[:i:i], q.deadLetters[i+1:]...)
+
+		reviveJob(&job)
+		q.store(&job)
+		q.queue = append(q.queue, jobID)
+		q.active[jobID] = true
+		q.mu.Unlock()
+
+		q.signal()
+		log.Printf("Job %s requeued from the dead-letter queue", jobID)
+		return &job, nil
+	}
+	q.mu.Unlock()
+	return nil, ErrNotDeadLettered
+}
+
+func (q *MemoryQueue) CancelJob(jobID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	entry, ok := q.lookup(jobID)
+	if !ok {
+		return fmt.Errorf("job not found")
+	}
+
+	if entry.job.Status == StatusProcessing {
+		q.cancelling[jobID] = true
+		return nil
+	}
+
+	now := time.Now()
+	entry.job.Status = StatusCancelled
+	entry.job.CompletedAt = &now
+	q.store(&entry.job)
+
+	q.removeFromQueue(jobID)
+	delete(q.delayed, jobID)
+	delete(q.active, jobID)
+	q.releaseDedup(&entry.job)
+	return nil
+}
+
+func (q *MemoryQueue) CancelRequested(jobID string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.cancelling[jobID]
+}
+
+func (q *MemoryQueue) MarkCancelled(job *ScanJob) error {
+	now := time.Now()
+	job.Status = StatusCancelled
+	job.CompletedAt = &now
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.store(job)
+	delete(q.cancelling, job.ID)
+	delete(q.active, job.ID)
+	q.releaseDedup(job)
+	return nil
+}
+
+func (q *MemoryQueue) PromoteDueJobs() (int, error) {
+	now := time.Now()
+
+	q.mu.Lock()
+	promoted := 0
+	for jobID, due := range q.delayed {
+		if due.After(now) {
+			continue
+		}
+		delete(q.delayed, jobID)
+		entry, ok := q.lookup(jobID)
+		if !ok {
+			continue
+		}
+		if entry.job.Status == StatusScheduled {
+			entry.job.Status = StatusQueued
+			q.store(&entry.job)
+		}
+		q.queue = append(q.queue, jobID)
+		promoted++
+	}
+	q.mu.Unlock()
+
+	if promoted > 0 {
+		q.signal()
+	}
+	return promoted, nil
+}
+
+func (q *MemoryQueue) CleanupStaleJobs() (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// lookup evicts expired jobs, so sweep them all first; the maps below
+	// are then pruned of anything pointing at a job that's gone
+	for jobID := range q.jobs {
+		q.lookup(jobID)
+	}
+
+	removed := 0
+	for jobID := range q.active {
+		if _, ok := q.lookup(jobID); !ok {
+			delete(q.active, jobID)
+			removed++
+		}
+	}
+
+	kept := q.queue[:0]
+	for _, jobID := range q.queue {
+		if _, ok := q.lookup(jobID); ok {
+			kept = append(kept, jobID)
+		} else {
+			removed++
+		}
+	}
+	q.queue = kept
+
+	for jobID := range q.frontiers {
+		if _, ok := q.lookup(jobID); !ok {
+			delete(q.frontiers, jobID)
+		}
+	}
+	for jobID := range q.delayed {
+		if _, ok := q.lookup(jobID); !ok {
+			delete(q.delayed, jobID)
+		}
+	}
+	for jobID := range q.cancelling {
+		if _, ok := q.lookup(jobID); !ok {
+			delete(q.cancelling, jobID)
+		}
+	}
+	for id, jobID := range q.dedup {
+		if entry, ok := q.lookup(jobID); !ok || finished(entry.job.Status) {
+			delete(q.dedup, id)
+		}
+	}
+	for jobID, lease := range q.leases {
+		if time.Now().After(lease) {
+			delete(q.leases, jobID)
+		}
+	}
+
+	now := time.Now()
+	for id, stored := range q.idempotency {
+		if now.After(stored.expiresAt) {
+			delete(q.idempotency, id)
+		}
+	}
+
+	return removed, nil
+}
+
+func (q *MemoryQueue) Heartbeat(jobID string, ttl time.Duration) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.leases[jobID] = time.Now().Add(ttl)
+	return nil
+}
+
+func (q *MemoryQueue) ClaimOrphanedJobs(olderThan, ttl time.Duration) ([]*ScanJob, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var orphans []*ScanJob
+	now := time.Now()
+	cutoff := now.Add(-olderThan)
+	for jobID := range q.active {
+		entry, ok := q.lookup(jobID)
+		if !ok || entry.job.Status != StatusProcessing || entry.job.StartedAt == nil || entry.job.StartedAt.After(cutoff) {
+			continue
+		}
+		if lease, ok := q.leases[jobID]; ok && now.Before(lease) {
+			continue
+		}
+		q.leases[jobID] = now.Add(ttl)
+		job := entry.job
+		orphans = append(orphans, &job)
+	}
+	return orphans, nil
+}
+
+func (q *MemoryQueue) SaveFrontier(jobID string, frontier *crawler.Frontier) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.frontiers[jobID] = frontier
+	return nil
+}
+
+func (q *MemoryQueue) LoadFrontier(jobID string) (*crawler.Frontier, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.frontiers[jobID], nil
+}
+
+func (q *MemoryQueue) DeleteFrontier(jobID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.frontiers, jobID)
+	return nil
+}
+
+// Ping always succeeds since the queue lives in memory.
+func (q *MemoryQueue) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (q *MemoryQueue) RecordDuration(duration time.Duration) {
+	q.durations.add(duration)
+}
+
+func (q *MemoryQueue) AverageDuration() (time.Duration, int) {
+	return q.durations.average()
+}
+
+func (q *MemoryQueue) Stats() map[string]interface{} {
+	average, samples := q.AverageDuration()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	activeJobs := make([]string, 0, len(q.active))
+	for jobID := range q.active {
+		activeJobs = append(activeJobs, jobID)
+	}
+
+	return map[string]interface{}{
+		"queue_size":     int64(len(q.queue)),
+		"active_jobs":    len(activeJobs),
+		"active_job_ids": activeJobs,
+		"delayed_jobs":   int64(len(q.delayed)),
+		"stored_jobs":    int64(len(q.jobs)),
+
+		"average_job_duration": average.Round(time.Millisecond).String(),
+		"duration_samples":     samples,
+	}
+}
+
+// memoryIdempotency is an idempotencyRecord that expires with ASYNC_JOB_TTL_HOURS.
+type memoryIdempotency struct {
+	record    idempotencyRecord
+	expiresAt time.Time
+}
+
+// claimIdempotencyKey records that the request's idempotency key created
+// jobID, or returns the job it created before (ErrIdempotencyConflict for a
+// different fingerprint). q.mu must be held.
+func (q *MemoryQueue) claimIdempotencyKey(req AsyncScanRequest, jobID string) (*ScanJob, error) {
+	id := idempotencyID(req)
+	if id == "" {
+		return nil, nil
+	}
+
+	if stored, ok := q.idempotency[id]; ok && time.Now().Before(stored.expiresAt) {
+		if stored.record.Fingerprint != req.Fingerprint {
+			return nil, ErrIdempotencyConflict
+		}
+		if entry, ok := q.lookup(stored.record.JobID); ok {
+			existing := entry.job
+			existing.Replayed = true
+			return &existing, nil
+		}
+	}
+	q.setIdempotencyKey(req, jobID)
+	return nil, nil
+}
+
+// setIdempotencyKey points the request's idempotency key at jobID. q.mu must
+// be held.
+func (q *MemoryQueue) setIdempotencyKey(req AsyncScanRequest, jobID string) {
+	if id := idempotencyID(req); id != "" {
+		q.idempotency[id] = memoryIdempotency{
+			record:    idempotencyRecord{JobID: jobID, Fingerprint: req.Fingerprint},
+			expiresAt: time.Now().Add(q.config.AsyncJobTTL),
+		}
+	}
+}
+
+// claimDedup registers job as the one crawling its URL when
+// ASYNC_DEDUPLICATE_JOBS is on, or returns the unfinished job that already is.
+// q.mu must be held.
+func (q *MemoryQueue) claimDedup(job *ScanJob) *ScanJob {
+	id := dedupID(job)
+	if !q.config.AsyncDeduplicateJobs || id == "" {
+		return nil
+	}
+
+	if entry, ok := q.lookup(q.dedup[id]); ok && !finished(entry.job.Status) {
+		existing := entry.job
+		existing.Deduplicated = true
+		return &existing
+	}
+	q.dedup[id] = job.ID
+	return nil
+}
+
+// releaseDedup removes the job's dedup mapping unless a newer job has taken it
+// over. q.mu must be held.
+func (q *MemoryQueue) releaseDedup(job *ScanJob) {
+	if id := dedupID(job); id != "" && q.dedup[id] == job.ID {
+		delete(q.dedup, id)
+	}
+}
+
+// store saves a copy of job and refreshes its TTL. Callers must hold q.mu.
+func (q *MemoryQueue) store(job *ScanJob) {
+	q.jobs[job.ID] = &memoryJob{
+		job:       *job,
+		expiresAt: time.Now().Add(q.config.AsyncJobTTL),
+	}
+}
+
+// lookup returns a live job entry, evicting it if expired. Callers must hold q.mu.
+func (q *MemoryQueue) lookup(jobID string) (*memoryJob, bool) {
+	entry, ok := q.jobs[jobID]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(q.jobs, jobID)
+		return nil, false
+	}
+	return entry, true
+}
+
+// removeFromQueue drops jobID from the pending queue. Callers must hold q.mu.
+func (q *MemoryQueue) removeFromQueue(jobID string) {
+	for i, id := range q.queue {
+		if id == jobID {
+			q.queue = append(q.queue[:i], q.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+func (q *MemoryQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
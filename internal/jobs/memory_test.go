@@ -0,0 +1,50 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryCleanupSweepsExpiredJobs(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.AsyncJobTTL = 50 * time.Millisecond
+	cfg.AsyncDeduplicateJobs = true
+	q := NewMemoryQueue(cfg)
+
+	// A finished job is in neither active nor the queue
+	mustEnqueue(t, q, "https://finished.example.com")
+	if err := q.CompleteJob(mustDequeue(t, q), nil, 1, "1s"); err != nil {
+		t.Fatalf("CompleteJob: %v", err)
+	}
+	if _, err := q.Enqueue(AsyncScanRequest{URL: "https://queued.example.com", IdempotencyKey: "key", Fingerprint: "a"}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	runAt := time.Now().Add(time.Hour)
+	if _, err := q.Enqueue(AsyncScanRequest{URL: "https://scheduled.example.com", RunAt: &runAt}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	time.Sleep(2 * cfg.AsyncJobTTL)
+	removed, err := q.CleanupStaleJobs()
+	if err != nil {
+		t.Fatalf("CleanupStaleJobs: %v", err)
+	}
+	if removed != 3 {
+		t.Errorf("CleanupStaleJobs removed %d entries, want 3 (two active jobs, one queued)", removed)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for name, size := range map[string]int{
+		"jobs":        len(q.jobs),
+		"queue":       len(q.queue),
+		"active":      len(q.active),
+		"delayed":     len(q.delayed),
+		"dedup":       len(q.dedup),
+		"idempotency": len(q.idempotency),
+	} {
+		if size != 0 {
+			t.Errorf("%s still holds %d entries after cleanup", name, size)
+		}
+	}
+}
@@ -5,20 +5,37 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strconv"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
 
 	"email-crawler/internal/config"
+	"email-crawler/internal/crawler"
 )
 
+// Redis key names, each prefixed with JOB_KEY_PREFIX so several instances can
+// share one Redis.
 const (
-	QueueKey      = "crawler:job_queue"
-	JobKeyPrefix  = "crawler:job:"
-	ActiveJobsKey = "crawler:active_jobs"
+	queueKeyName       = "job_queue"
+	jobKeyName         = "job:"
+	activeJobsKeyName  = "active_jobs"
+	delayedKeyName     = "job_delayed"
+	cancelKeyName      = "cancel:"
+	deadLetterKeyName  = "dead_letter"
+	frontierKeyName    = "frontier:"
+	dedupKeyName       = "dedup:"
+	idempotencyKeyName = "idempotency:"
+	jobIndexKeyName    = "job_index"
+	statusIndexKeyName = "job_index:"
+	durationsKeyName   = "job_durations"
+	leaseKeyName       = "lease:"
 )
 
+// jobStatuses lists every job status, each with its own index
+var jobStatuses = []JobStatus{StatusScheduled, StatusQueued, StatusProcessing, StatusCompleted, StatusFailed, StatusCancelled}
+
 type Queue struct {
 	client *redis.Client
 	config *config.Config
@@ -33,6 +50,71 @@ func NewQueue(client *redis.Client, config *config.Config) *Queue {
 	}
 }
 
+func (q *Queue) queueKey() string {
+	return q.config.JobKeyPrefix + queueKeyName
+}
+
+func (q *Queue) jobKey(jobID string) string {
+	return q.config.JobKeyPrefix + jobKeyName + jobID
+}
+
+func (q *Queue) activeJobsKey() string {
+	return q.config.JobKeyPrefix + activeJobsKeyName
+}
+
+// delayedKey is a sorted set of scheduled jobs and jobs waiting to be retried,
+// scored by the Unix time they are due
+func (q *Queue) delayedKey() string {
+	return q.config.JobKeyPrefix + delayedKeyName
+}
+
+// cancelKey marks a processing job whose cancellation was requested
+func (q *Queue) cancelKey(jobID string) string {
+	return q.config.JobKeyPrefix + cancelKeyName + jobID
+}
+
+// deadLetterKey lists jobs that failed for good as JSON, newest first
+func (q *Queue) deadLetterKey() string {
+	return q.config.JobKeyPrefix + deadLetterKeyName
+}
+
+func (q *Queue) frontierKey(jobID string) string {
+	return q.config.JobKeyPrefix + frontierKeyName + jobID
+}
+
+// jobIndexKey is a sorted set of job IDs scored by creation time in
+// milliseconds, so jobs can be listed without scanning keys
+func (q *Queue) jobIndexKey() string {
+	return q.config.JobKeyPrefix + jobIndexKeyName
+}
+
+// statusIndexKey is like jobIndexKey but only holds the jobs currently in
+// status, so ListJobs can count and page them without reading every job
+func (q *Queue) statusIndexKey(status JobStatus) string {
+	return q.config.JobKeyPrefix + statusIndexKeyName + string(status)
+}
+
+// leaseKey exists while a worker is alive and processing the job
+func (q *Queue) leaseKey(jobID string) string {
+	return q.config.JobKeyPrefix + leaseKeyName + jobID
+}
+
+// durationsKey lists the durations of the latest completed jobs in
+// milliseconds, newest first
+func (q *Queue) durationsKey() string {
+	return q.config.JobKeyPrefix + durationsKeyName
+}
+
+// idempotencyKey maps a client's idempotency key to an idempotencyRecord
+func (q *Queue) idempotencyKey(id string) string {
+	return q.config.JobKeyPrefix + idempotencyKeyName + id
+}
+
+// dedupKey maps a crawl, identified by dedupID, to the job performing it
+func (q *Queue) dedupKey(id string) string {
+	return q.config.JobKeyPrefix + dedupKeyName + id
+}
+
 func (q *Queue) Enqueue(req AsyncScanRequest) (*ScanJob, error) {
 	jobID := uuid.New().String()
 	
@@ -43,40 +125,73 @@ func (q *Queue) Enqueue(req AsyncScanRequest) (*ScanJob, error) {
 		CallbackID: req.CallbackID,
 		Status:     StatusQueued,
 		CreatedAt:  time.Now(),
+
+		IfModifiedSince: req.IfModifiedSince,
+		TenantID:        req.TenantID,
+		MaxDepth:        req.MaxDepth,
+		ForceRefresh:    req.ForceRefresh,
+		WebhookCompress: req.WebhookCompress,
+		NotifyOnEnqueue: req.NotifyOnEnqueue,
+		WebhookHeaders:  req.WebhookHeaders,
+		WebhookURLs:     req.WebhookURLs,
+	}
+
+	schedule(job, req.RunAt)
+
+	if existing, err := q.claimIdempotencyKey(req, job.ID); existing != nil || err != nil {
+		return existing, err
+	}
+	if existing := q.claimDedup(job); existing != nil {
+		log.Printf("Job %s already pending for URL: %s", existing.ID, req.URL)
+		q.setIdempotencyKey(req, existing.ID)
+		return existing, nil
 	}
 
 	// Store job details
-	jobKey := JobKeyPrefix + jobID
+	jobKey := q.jobKey(jobID)
 	jobData, err := json.Marshal(job)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal job: %v", err)
 	}
 
-	// Set job with TTL (24 hours)
-	err = q.client.Set(q.ctx, jobKey, jobData, 24*time.Hour).Err()
+	// Set job with TTL
+	err = q.client.Set(q.ctx, jobKey, jobData, q.config.AsyncJobTTL).Err()
 	if err != nil {
 		return nil, fmt.Errorf("failed to store job: %v", err)
 	}
 
-	// Add to queue
-	err = q.client.LPush(q.ctx, QueueKey, jobID).Err()
+	// Add to queue, or hold scheduled jobs until PromoteDueJobs queues them
+	if job.Status == StatusScheduled {
+		err = q.delay(jobID, *job.RunAt)
+	} else {
+		err = q.client.LPush(q.ctx, q.queueKey(), jobID).Err()
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to enqueue job: %v", err)
 	}
 
 	// Add to active jobs set
-	err = q.client.SAdd(q.ctx, ActiveJobsKey, jobID).Err()
+	err = q.client.SAdd(q.ctx, q.activeJobsKey(), jobID).Err()
 	if err != nil {
 		log.Printf("Warning: failed to add job to active set: %v", err)
 	}
 
-	log.Printf("Job %s queued for URL: %s", jobID, req.URL)
+	// Index the job for ListJobs
+	pipe := q.client.TxPipeline()
+	q.indexJob(pipe, job)
+	if _, err := pipe.Exec(q.ctx); err != nil {
+		log.Printf("Warning: failed to index job: %v", err)
+	}
+
+	q.extendSharedKeys()
+
+	log.Printf("Job %s %s for URL: %s", jobID, job.Status, req.URL)
 	return job, nil
 }
 
 func (q *Queue) Dequeue(timeout time.Duration) (*ScanJob, error) {
 	// Blocking pop from queue
-	result, err := q.client.BRPop(q.ctx, timeout, QueueKey).Result()
+	result, err := q.client.BRPop(q.ctx, timeout, q.queueKey()).Result()
 	if err != nil {
 		if err == redis.Nil {
 			return nil, nil // No jobs available
@@ -108,7 +223,7 @@ func (q *Queue) Dequeue(timeout time.Duration) (*ScanJob, error) {
 }
 
 func (q *Queue) GetJob(jobID string) (*ScanJob, error) {
-	jobKey := JobKeyPrefix + jobID
+	jobKey := q.jobKey(jobID)
 	data, err := q.client.Get(q.ctx, jobKey).Result()
 	if err != nil {
 		if err == redis.Nil {
@@ -126,26 +241,97 @@ func (q *Queue) GetJob(jobID string) (*ScanJob, error) {
 	return &job, nil
 }
 
+func (q *Queue) RequeueJob(job *ScanJob) error {
+	job.Status = StatusQueued
+	job.StartedAt = nil
+	if err := q.UpdateJob(job); err != nil {
+		return err
+	}
+	// Workers pop from the right, so this makes it the next job
+	if err := q.client.RPush(q.ctx, q.queueKey(), job.ID).Err(); err != nil {
+		return fmt.Errorf("failed to requeue job: %v", err)
+	}
+	q.extendSharedKeys()
+	return nil
+}
+
+// extendSharedKeys renews the TTL of the queue, the active set and the job
+// index after a write to them. They expire once nothing has been written for a
+// full job TTL, at which point every job they could reference is gone as well.
+func (q *Queue) extendSharedKeys() {
+	pipe := q.client.TxPipeline()
+	pipe.Expire(q.ctx, q.queueKey(), q.config.AsyncJobTTL)
+	pipe.Expire(q.ctx, q.activeJobsKey(), q.config.AsyncJobTTL)
+	pipe.Expire(q.ctx, q.jobIndexKey(), q.config.AsyncJobTTL)
+	for _, status := range jobStatuses {
+		pipe.Expire(q.ctx, q.statusIndexKey(status), q.config.AsyncJobTTL)
+	}
+	if _, err := pipe.Exec(q.ctx); err != nil {
+		log.Printf("Warning: failed to extend job key TTLs: %v", err)
+	}
+}
+
+func (q *Queue) QueuePosition(jobID string) (int, error) {
+	pipe := q.client.TxPipeline()
+	index := pipe.LPos(q.ctx, q.queueKey(), jobID, redis.LPosArgs{})
+	size := pipe.LLen(q.ctx, q.queueKey())
+	if _, err := pipe.Exec(q.ctx); err != nil && err != redis.Nil {
+		return 0, fmt.Errorf("failed to find job in queue: %v", err)
+	}
+	if index.Err() != nil {
+		return 0, nil
+	}
+	// Workers pop from the right, so the last element runs next
+	return int(size.Val() - index.Val()), nil
+}
+
 func (q *Queue) UpdateJob(job *ScanJob) error {
-	jobKey := JobKeyPrefix + job.ID
+	jobKey := q.jobKey(job.ID)
 	jobData, err := json.Marshal(job)
 	if err != nil {
 		return fmt.Errorf("failed to marshal job: %v", err)
 	}
 
-	// Update with TTL (24 hours)
-	err = q.client.Set(q.ctx, jobKey, jobData, 24*time.Hour).Err()
-	if err != nil {
+	// Update with TTL, moving the job to the index of its new status
+	pipe := q.client.TxPipeline()
+	pipe.Set(q.ctx, jobKey, jobData, q.config.AsyncJobTTL)
+	q.indexJob(pipe, job)
+	if _, err := pipe.Exec(q.ctx); err != nil {
 		return fmt.Errorf("failed to update job: %v", err)
 	}
 
 	return nil
 }
 
+// indexJob adds job to the job index and to the index of its status, removing
+// it from the other status indexes. Entries whose job has expired are dropped.
+func (q *Queue) indexJob(pipe redis.Pipeliner, job *ScanJob) {
+	member := &redis.Z{Score: float64(job.CreatedAt.UnixMilli()), Member: job.ID}
+	pipe.ZAdd(q.ctx, q.jobIndexKey(), member)
+	pipe.ZRemRangeByScore(q.ctx, q.jobIndexKey(), "-inf", q.expiredIndexScore())
+	for _, status := range jobStatuses {
+		key := q.statusIndexKey(status)
+		if status != job.Status {
+			pipe.ZRem(q.ctx, key, job.ID)
+			continue
+		}
+		pipe.ZAdd(q.ctx, key, member)
+		pipe.ZRemRangeByScore(q.ctx, key, "-inf", q.expiredIndexScore())
+		pipe.Expire(q.ctx, key, q.config.AsyncJobTTL)
+	}
+}
+
+// expiredIndexScore is the index score below which jobs were created more
+// than a job TTL ago and have expired
+func (q *Queue) expiredIndexScore() string {
+	return strconv.FormatInt(time.Now().Add(-q.config.AsyncJobTTL).UnixMilli(), 10)
+}
+
 func (q *Queue) CompleteJob(job *ScanJob, emails []string, pagesVisited int, crawlTime string) error {
 	now := time.Now()
 	job.Status = StatusCompleted
 	job.CompletedAt = &now
+	job.Error = "" // Left over from an attempt that was retried
 	job.Emails = emails
 	job.PagesVisited = pagesVisited
 	job.CrawlTime = crawlTime
@@ -156,12 +342,25 @@ func (q *Queue) CompleteJob(job *ScanJob, emails []string, pagesVisited int, cra
 	}
 
 	// Remove from active jobs
-	q.client.SRem(q.ctx, ActiveJobsKey, job.ID)
+	q.client.SRem(q.ctx, q.activeJobsKey(), job.ID)
+	q.releaseDedup(job)
 
 	return nil
 }
 
-func (q *Queue) FailJob(job *ScanJob, errorMsg string) error {
+func (q *Queue) FailJob(job *ScanJob, errorMsg string, retryable bool) (bool, error) {
+	if delay, ok := retryJob(q.config, job, errorMsg, retryable); ok {
+		if err := q.UpdateJob(job); err != nil {
+			return false, err
+		}
+		// The job stays active; PromoteDueJobs queues it once it is due
+		if err := q.delay(job.ID, time.Now().Add(delay)); err != nil {
+			return false, fmt.Errorf("failed to schedule retry: %v", err)
+		}
+		log.Printf("Job %s failed (%s), retry %d/%d in %s", job.ID, errorMsg, job.RetryCount, q.config.AsyncMaxRetries, delay)
+		return true, nil
+	}
+
 	now := time.Now()
 	job.Status = StatusFailed
 	job.CompletedAt = &now
@@ -169,15 +368,269 @@ func (q *Queue) FailJob(job *ScanJob, errorMsg string) error {
 
 	err := q.UpdateJob(job)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	// Remove from active jobs
-	q.client.SRem(q.ctx, ActiveJobsKey, job.ID)
+	q.client.SRem(q.ctx, q.activeJobsKey(), job.ID)
+	q.releaseDedup(job)
+
+	q.deadLetter(job)
+	return false, nil
+}
+
+// deadLetter keeps a copy of a failed job on the dead-letter list, dropping
+// the oldest entries past ASYNC_DEAD_LETTER_SIZE. The copy outlives the job's
+// own TTL.
+func (q *Queue) deadLetter(job *ScanJob) {
+	if q.config.AsyncDeadLetterSize <= 0 {
+		return
+	}
+
+	data, err := json.Marshal(job)
+	if err != nil {
+		log.Printf("Warning: failed to marshal dead-letter job %s: %v", job.ID, err)
+		return
+	}
+	pipe := q.client.TxPipeline()
+	pipe.LPush(q.ctx, q.deadLetterKey(), data)
+	pipe.LTrim(q.ctx, q.deadLetterKey(), 0, int64(q.config.AsyncDeadLetterSize-1))
+	if _, err := pipe.Exec(q.ctx); err != nil {
+		log.Printf("Warning: failed to dead-letter job %s: %v", job.ID, err)
+	}
+}
+
+// ListJobs reads one page of the job index, or of the status index, so only
+// the jobs on that page are fetched and the total is the index size.
+func (q *Queue) ListJobs(status JobStatus, limit, offset int) ([]ScanJob, int, error) {
+	indexKey := q.jobIndexKey()
+	if status != "" {
+		indexKey = q.statusIndexKey(status)
+	}
+
+	pipe := q.client.TxPipeline()
+	pipe.ZRemRangeByScore(q.ctx, indexKey, "-inf", q.expiredIndexScore())
+	total := pipe.ZCard(q.ctx, indexKey)
+	page := pipe.ZRevRange(q.ctx, indexKey, int64(offset), int64(offset+limit-1))
+	if _, err := pipe.Exec(q.ctx); err != nil {
+		return nil, 0, fmt.Errorf("failed to read job index: %v", err)
+	}
+
+	jobs := []ScanJob{}
+	jobIDs := page.Val()
+	if len(jobIDs) == 0 {
+		return jobs, int(total.Val()), nil
+	}
+	keys := make([]string, len(jobIDs))
+	for i, jobID := range jobIDs {
+		keys[i] = q.jobKey(jobID)
+	}
+	values, err := q.client.MGet(q.ctx, keys...).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read jobs: %v", err)
+	}
+
+	for _, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue // Lost
+		}
+		var job ScanJob
+		if err := json.Unmarshal([]byte(data), &job); err != nil {
+			continue
+		}
+		// The job may have moved on since the index was read
+		if status != "" && job.Status != status {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, int(total.Val()), nil
+}
+
+func (q *Queue) DeadLetters() ([]ScanJob, error) {
+	entries, err := q.client.LRange(q.ctx, q.deadLetterKey(), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead-letter queue: %v", err)
+	}
+
+	jobs := make([]ScanJob, 0, len(entries))
+	for _, entry := range entries {
+		var job ScanJob
+		if err := json.Unmarshal([]byte(entry), &job); err != nil {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+func (q *Queue) RetryDeadLetter(jobID string) (*ScanJob, error) {
+	entries, err := q.client.LRange(q.ctx, q.deadLetterKey(), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead-letter queue: %v", err)
+	}
+
+	for _, entry := range entries {
+		var job ScanJob
+		if err := json.Unmarshal([]byte(entry), &job); err != nil || job.ID != jobID {
+			continue
+		}
+		// LREM decides who retries the job if two requests race
+		if removed, err := q.client.LRem(q.ctx, q.deadLetterKey(), 1, entry).Result(); err != nil {
+			return nil, fmt.Errorf("failed to remove dead-letter job: %v", err)
+		} else if removed == 0 {
+			break
+		}
+
+		reviveJob(&job)
+		if err := q.UpdateJob(&job); err != nil {
+			return nil, err
+		}
+		if err := q.client.LPush(q.ctx, q.queueKey(), jobID).Err(); err != nil {
+			return nil, fmt.Errorf("failed to enqueue job: %v", err)
+		}
+		q.client.SAdd(q.ctx, q.activeJobsKey(), jobID)
+		q.extendSharedKeys()
+		log.Printf("Job %s requeued from the dead-letter queue", jobID)
+		return &job, nil
+	}
+	return nil, ErrNotDeadLettered
+}
+
+// claimIdempotencyKey records that the request's idempotency key created
+// jobID. If the key was already used, it returns the job created then, or
+// ErrIdempotencyConflict if that request had a different fingerprint.
+func (q *Queue) claimIdempotencyKey(req AsyncScanRequest, jobID string) (*ScanJob, error) {
+	id := idempotencyID(req)
+	if id == "" {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(idempotencyRecord{JobID: jobID, Fingerprint: req.Fingerprint})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal idempotency record: %v", err)
+	}
+	key := q.idempotencyKey(id)
+	claimed, err := q.client.SetNX(q.ctx, key, data, q.config.AsyncJobTTL).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to store idempotency key: %v", err)
+	}
+	if claimed {
+		return nil, nil
+	}
+
+	var record idempotencyRecord
+	if stored, err := q.client.Get(q.ctx, key).Bytes(); err == nil && json.Unmarshal(stored, &record) == nil {
+		if record.Fingerprint != req.Fingerprint {
+			return nil, ErrIdempotencyConflict
+		}
+		if existing, err := q.GetJob(record.JobID); err == nil {
+			existing.Replayed = true
+			return existing, nil
+		}
+	}
+	// The job the key created has expired
+	q.client.Set(q.ctx, key, data, q.config.AsyncJobTTL)
+	return nil, nil
+}
+
+// setIdempotencyKey points the request's idempotency key at jobID.
+func (q *Queue) setIdempotencyKey(req AsyncScanRequest, jobID string) {
+	id := idempotencyID(req)
+	if id == "" {
+		return
+	}
+	if data, err := json.Marshal(idempotencyRecord{JobID: jobID, Fingerprint: req.Fingerprint}); err == nil {
+		q.client.Set(q.ctx, q.idempotencyKey(id), data, q.config.AsyncJobTTL)
+	}
+}
 
+// claimDedup registers job as the one crawling its URL when
+// ASYNC_DEDUPLICATE_JOBS is on. If another job that hasn't finished already
+// holds the URL, it returns that job instead.
+func (q *Queue) claimDedup(job *ScanJob) *ScanJob {
+	id := dedupID(job)
+	if !q.config.AsyncDeduplicateJobs || id == "" {
+		return nil
+	}
+
+	key := q.dedupKey(id)
+	claimed, err := q.client.SetNX(q.ctx, key, job.ID, q.config.AsyncJobTTL).Result()
+	if err != nil {
+		log.Printf("Warning: failed to check for duplicate jobs: %v", err)
+		return nil
+	}
+	if claimed {
+		return nil
+	}
+
+	if existingID, err := q.client.Get(q.ctx, key).Result(); err == nil {
+		if existing, err := q.GetJob(existingID); err == nil && !finished(existing.Status) {
+			existing.Deduplicated = true
+			return existing
+		}
+	}
+	// The mapping outlived its job
+	q.client.Set(q.ctx, key, job.ID, q.config.AsyncJobTTL)
 	return nil
 }
 
+// releaseDedup removes the job's dedup mapping once it has finished, unless a
+// newer job has taken it over.
+func (q *Queue) releaseDedup(job *ScanJob) {
+	id := dedupID(job)
+	if !q.config.AsyncDeduplicateJobs || id == "" {
+		return
+	}
+
+	key := q.dedupKey(id)
+	if owner, err := q.client.Get(q.ctx, key).Result(); err == nil && owner == job.ID {
+		q.client.Del(q.ctx, key)
+	}
+}
+
+// delay holds jobID in the delayed set until at.
+func (q *Queue) delay(jobID string, at time.Time) error {
+	err := q.client.ZAdd(q.ctx, q.delayedKey(), &redis.Z{Score: float64(at.Unix()), Member: jobID}).Err()
+	if err != nil {
+		return err
+	}
+	q.client.Expire(q.ctx, q.delayedKey(), q.config.AsyncJobTTL)
+	return nil
+}
+
+// PromoteDueJobs moves scheduled jobs and retries that are due onto the queue.
+// ZREM decides which instance moves each job, so none is queued twice.
+func (q *Queue) PromoteDueJobs() (int, error) {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	due, err := q.client.ZRangeByScore(q.ctx, q.delayedKey(), &redis.ZRangeBy{Min: "-inf", Max: now}).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read delayed jobs: %v", err)
+	}
+
+	promoted := 0
+	for _, jobID := range due {
+		if removed, err := q.client.ZRem(q.ctx, q.delayedKey(), jobID).Result(); err != nil || removed == 0 {
+			continue
+		}
+		if job, err := q.GetJob(jobID); err == nil && job.Status == StatusScheduled {
+			job.Status = StatusQueued
+			if err := q.UpdateJob(job); err != nil {
+				log.Printf("Warning: failed to update job status: %v", err)
+			}
+		}
+		if err := q.client.LPush(q.ctx, q.queueKey(), jobID).Err(); err != nil {
+			return promoted, fmt.Errorf("failed to queue job %s: %v", jobID, err)
+		}
+		promoted++
+	}
+	if promoted > 0 {
+		q.extendSharedKeys()
+	}
+	return promoted, nil
+}
+
 func (q *Queue) CancelJob(jobID string) error {
 	job, err := q.GetJob(jobID)
 	if err != nil {
@@ -185,7 +638,11 @@ func (q *Queue) CancelJob(jobID string) error {
 	}
 
 	if job.Status == StatusProcessing {
-		return fmt.Errorf("cannot cancel job that is currently processing")
+		// The worker polls for this key and stops the crawl
+		if err := q.client.Set(q.ctx, q.cancelKey(jobID), 1, q.config.AsyncJobTTL).Err(); err != nil {
+			return fmt.Errorf("failed to request cancellation: %v", err)
+		}
+		return nil
 	}
 
 	now := time.Now()
@@ -198,16 +655,38 @@ func (q *Queue) CancelJob(jobID string) error {
 	}
 
 	// Remove from queue if it's still queued
-	q.client.LRem(q.ctx, QueueKey, 0, jobID)
+	q.client.LRem(q.ctx, q.queueKey(), 0, jobID)
+	q.client.ZRem(q.ctx, q.delayedKey(), jobID)
 
 	// Remove from active jobs
-	q.client.SRem(q.ctx, ActiveJobsKey, jobID)
+	q.client.SRem(q.ctx, q.activeJobsKey(), jobID)
+	q.releaseDedup(job)
 
 	return nil
 }
 
+func (q *Queue) CancelRequested(jobID string) bool {
+	exists, err := q.client.Exists(q.ctx, q.cancelKey(jobID)).Result()
+	return err == nil && exists > 0
+}
+
+func (q *Queue) MarkCancelled(job *ScanJob) error {
+	now := time.Now()
+	job.Status = StatusCancelled
+	job.CompletedAt = &now
+
+	if err := q.UpdateJob(job); err != nil {
+		return err
+	}
+
+	q.client.SRem(q.ctx, q.activeJobsKey(), job.ID)
+	q.client.Del(q.ctx, q.cancelKey(job.ID))
+	q.releaseDedup(job)
+	return nil
+}
+
 func (q *Queue) GetActiveJobs() ([]string, error) {
-	jobs, err := q.client.SMembers(q.ctx, ActiveJobsKey).Result()
+	jobs, err := q.client.SMembers(q.ctx, q.activeJobsKey()).Result()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get active jobs: %v", err)
 	}
@@ -215,13 +694,152 @@ func (q *Queue) GetActiveJobs() ([]string, error) {
 }
 
 func (q *Queue) GetQueueSize() (int64, error) {
-	size, err := q.client.LLen(q.ctx, QueueKey).Result()
+	size, err := q.client.LLen(q.ctx, q.queueKey()).Result()
 	if err != nil {
 		return 0, fmt.Errorf("failed to get queue size: %v", err)
 	}
 	return size, nil
 }
 
+// CleanupStaleJobs removes active-set and queue entries whose job key no longer
+// exists (e.g. the job data expired or was lost), returning how many were pruned.
+func (q *Queue) CleanupStaleJobs() (int, error) {
+	removed := 0
+
+	activeJobs, err := q.GetActiveJobs()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, jobID := range activeJobs {
+		exists, err := q.client.Exists(q.ctx, q.jobKey(jobID)).Result()
+		if err != nil {
+			return removed, fmt.Errorf("failed to check job %s: %v", jobID, err)
+		}
+		if exists == 0 {
+			q.client.SRem(q.ctx, q.activeJobsKey(), jobID)
+			removed++
+		}
+	}
+
+	queued, err := q.client.LRange(q.ctx, q.queueKey(), 0, -1).Result()
+	if err != nil {
+		return removed, fmt.Errorf("failed to read queue: %v", err)
+	}
+
+	for _, jobID := range queued {
+		exists, err := q.client.Exists(q.ctx, q.jobKey(jobID)).Result()
+		if err != nil {
+			return removed, fmt.Errorf("failed to check job %s: %v", jobID, err)
+		}
+		if exists == 0 {
+			q.client.LRem(q.ctx, q.queueKey(), 0, jobID)
+			removed++
+		}
+	}
+
+	return removed, nil
+}
+
+func (q *Queue) Heartbeat(jobID string, ttl time.Duration) error {
+	if err := q.client.Set(q.ctx, q.leaseKey(jobID), 1, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to renew job lease: %v", err)
+	}
+	return nil
+}
+
+func (q *Queue) ClaimOrphanedJobs(olderThan, ttl time.Duration) ([]*ScanJob, error) {
+	activeJobs, err := q.GetActiveJobs()
+	if err != nil {
+		return nil, err
+	}
+
+	var orphans []*ScanJob
+	cutoff := time.Now().Add(-olderThan)
+	for _, jobID := range activeJobs {
+		job, err := q.GetJob(jobID)
+		if err != nil {
+			continue
+		}
+		if job.Status != StatusProcessing || job.StartedAt == nil || job.StartedAt.After(cutoff) {
+			continue
+		}
+		// SETNX only succeeds once the lease has lapsed, and only for one caller
+		claimed, err := q.client.SetNX(q.ctx, q.leaseKey(jobID), 1, ttl).Result()
+		if err != nil {
+			return orphans, fmt.Errorf("failed to claim job %s: %v", jobID, err)
+		}
+		if claimed {
+			orphans = append(orphans, job)
+		}
+	}
+
+	return orphans, nil
+}
+
+func (q *Queue) SaveFrontier(jobID string, frontier *crawler.Frontier) error {
+	data, err := json.Marshal(frontier)
+	if err != nil {
+		return fmt.Errorf("failed to marshal frontier: %v", err)
+	}
+
+	err = q.client.Set(q.ctx, q.frontierKey(jobID), data, q.config.AsyncJobTTL).Err()
+	if err != nil {
+		return fmt.Errorf("failed to store frontier: %v", err)
+	}
+	return nil
+}
+
+// LoadFrontier returns the saved frontier for jobID, or nil if there is none.
+func (q *Queue) LoadFrontier(jobID string) (*crawler.Frontier, error) {
+	data, err := q.client.Get(q.ctx, q.frontierKey(jobID)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get frontier: %v", err)
+	}
+
+	var frontier crawler.Frontier
+	if err := json.Unmarshal([]byte(data), &frontier); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal frontier: %v", err)
+	}
+	return &frontier, nil
+}
+
+func (q *Queue) DeleteFrontier(jobID string) error {
+	return q.client.Del(q.ctx, q.frontierKey(jobID)).Err()
+}
+
+// Ping checks that Redis is reachable.
+func (q *Queue) Ping(ctx context.Context) error {
+	return q.client.Ping(ctx).Err()
+}
+
+func (q *Queue) RecordDuration(duration time.Duration) {
+	pipe := q.client.TxPipeline()
+	pipe.LPush(q.ctx, q.durationsKey(), duration.Milliseconds())
+	pipe.LTrim(q.ctx, q.durationsKey(), 0, durationSamples-1)
+	pipe.Expire(q.ctx, q.durationsKey(), q.config.AsyncJobTTL)
+	if _, err := pipe.Exec(q.ctx); err != nil {
+		log.Printf("Warning: failed to record job duration: %v", err)
+	}
+}
+
+func (q *Queue) AverageDuration() (time.Duration, int) {
+	values, err := q.client.LRange(q.ctx, q.durationsKey(), 0, -1).Result()
+	if err != nil {
+		return 0, 0
+	}
+	samples := make([]time.Duration, 0, len(values))
+	for _, value := range values {
+		if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+			samples = append(samples, time.Duration(ms)*time.Millisecond)
+		}
+	}
+	return averageDuration(samples), len(samples)
+}
+
 func (q *Queue) Stats() map[string]interface{} {
 	stats := make(map[string]interface{})
 
@@ -234,5 +852,17 @@ func (q *Queue) Stats() map[string]interface{} {
 		stats["active_job_ids"] = activeJobs
 	}
 
+	if delayed, err := q.client.ZCard(q.ctx, q.delayedKey()).Result(); err == nil {
+		stats["delayed_jobs"] = delayed
+	}
+
+	if stored, err := q.client.ZCard(q.ctx, q.jobIndexKey()).Result(); err == nil {
+		stats["stored_jobs"] = stored
+	}
+
+	average, samples := q.AverageDuration()
+	stats["average_job_duration"] = average.Round(time.Millisecond).String()
+	stats["duration_samples"] = samples
+
 	return stats
 }
\ No newline at end of file
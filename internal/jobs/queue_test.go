@@ -0,0 +1,80 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCleanupStaleJobsPrunesLostJobs(t *testing.T) {
+	q := newTestQueue(t, newTestConfig())
+	kept := mustEnqueue(t, q, "https://kept.example.com")
+	lost := mustEnqueue(t, q, "https://lost.example.com")
+	q.client.Del(q.ctx, q.jobKey(lost.ID))
+
+	removed, err := q.CleanupStaleJobs()
+	if err != nil {
+		t.Fatalf("CleanupStaleJobs: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("CleanupStaleJobs removed %d entries, want 2 (active set and queue)", removed)
+	}
+	active, _ := q.GetActiveJobs()
+	if len(active) != 1 || active[0] != kept.ID {
+		t.Errorf("active set = %v, want only %s", active, kept.ID)
+	}
+	if size, _ := q.GetQueueSize(); size != 1 {
+		t.Errorf("queue size = %d, want 1", size)
+	}
+}
+
+func TestQueueWritesRenewSharedKeyTTLs(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.AsyncMaxRetries = 0
+	q := newTestQueue(t, cfg)
+	sharedKeys := []string{q.queueKey(), q.activeJobsKey(), q.jobIndexKey()}
+
+	// expireSoon makes the shared keys about to expire, then checks that
+	// write renewed their TTL
+	expireSoon := func(name string, write func()) {
+		t.Helper()
+		for _, key := range sharedKeys {
+			q.client.Expire(q.ctx, key, time.Minute)
+		}
+		write()
+		for _, key := range sharedKeys {
+			if ttl := q.client.TTL(q.ctx, key).Val(); ttl < cfg.AsyncJobTTL-time.Minute {
+				t.Errorf("after %s, %s expires in %s, want about %s", name, key, ttl, cfg.AsyncJobTTL)
+			}
+		}
+	}
+
+	// Keep a job queued so the queue key exists throughout
+	mustEnqueue(t, q, "https://example.com")
+	mustEnqueue(t, q, "https://waiting.example.com")
+	job := mustDequeue(t, q)
+
+	expireSoon("RequeueJob", func() {
+		if err := q.RequeueJob(job); err != nil {
+			t.Fatalf("RequeueJob: %v", err)
+		}
+	})
+
+	job = mustDequeue(t, q)
+	if _, err := q.FailJob(job, "404 Not Found", false); err != nil {
+		t.Fatalf("FailJob: %v", err)
+	}
+	expireSoon("RetryDeadLetter", func() {
+		if _, err := q.RetryDeadLetter(job.ID); err != nil {
+			t.Fatalf("RetryDeadLetter: %v", err)
+		}
+	})
+
+	if err := q.delay(job.ID, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("delay: %v", err)
+	}
+	expireSoon("PromoteDueJobs", func() {
+		if promoted, err := q.PromoteDueJobs(); err != nil || promoted != 1 {
+			t.Fatalf("PromoteDueJobs = %d, %v; want 1", promoted, err)
+		}
+	})
+}
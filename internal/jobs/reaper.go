@@ -0,0 +1,92 @@
+package jobs
+
+import (
+	"log"
+	"time"
+)
+
+// holdLease takes the lease on a processing job and renews it every third of
+// ASYNC_LEASE_SECONDS until the returned function is called, so the reaper
+// knows a live worker is on it. With ASYNC_LEASE_SECONDS=0 leases, and with
+// them the reaper, are off.
+func (wp *WorkerPool) holdLease(jobID string) func() {
+	ttl := wp.config.AsyncLeaseTTL
+	if ttl <= 0 {
+		return func() {}
+	}
+	if err := wp.queue.Heartbeat(jobID, ttl); err != nil {
+		log.Printf("Job %s: %v", jobID, err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := wp.queue.Heartbeat(jobID, ttl); err != nil {
+					log.Printf("Job %s: %v", jobID, err)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// reapLoop handles abandoned jobs every ASYNC_REAPER_INTERVAL_SECONDS.
+func (wp *WorkerPool) reapLoop() {
+	ticker := time.NewTicker(wp.config.AsyncReaperInterval)
+	defer ticker.Stop()
+
+	wp.reapOrphans()
+	for {
+		select {
+		case <-wp.ctx.Done():
+			return
+		case <-ticker.C:
+			wp.reapOrphans()
+		}
+	}
+}
+
+// reapOrphans finds processing jobs whose lease has lapsed, meaning the worker
+// running them died, and retries them like a transient failure. Once retries
+// run out they fail and their webhook is sent.
+//
+// The lease, not ASYNC_JOB_TIMEOUT_SECONDS, decides whether a job was abandoned: a
+// live worker keeps renewing it however long the crawl runs, so a dead one is
+// noticed within ASYNC_LEASE_SECONDS instead of after the whole job timeout.
+// Jobs younger than one lease are skipped so a worker that just started has
+// time to take its first one.
+func (wp *WorkerPool) reapOrphans() {
+	orphans, err := wp.queue.ClaimOrphanedJobs(wp.config.AsyncLeaseTTL, wp.config.AsyncLeaseTTL)
+	if err != nil {
+		log.Printf("Job reaper error: %v", err)
+	}
+
+	for _, job := range orphans {
+		// No worker is left to honour a pending cancellation
+		if wp.queue.CancelRequested(job.ID) {
+			wp.queue.DeleteFrontier(job.ID)
+			if err := wp.queue.MarkCancelled(job); err != nil {
+				log.Printf("Job reaper: failed to cancel job %s: %v", job.ID, err)
+			}
+			continue
+		}
+
+		log.Printf("Job reaper: job %s was abandoned by its worker", job.ID)
+		// With resumable crawls, a retry picks up from the saved frontier
+		retried, err := wp.queue.FailJob(job, "Worker stopped while processing the job", true)
+		if err != nil {
+			log.Printf("Job reaper: failed to fail job %s: %v", job.ID, err)
+			continue
+		}
+		if !retried {
+			wp.queue.DeleteFrontier(job.ID)
+			wp.notify("Job reaper", job)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package jobs
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"email-crawler/internal/config"
+	"email-crawler/internal/crawler"
+	"email-crawler/internal/ssrf"
+)
+
+// retryJob records the failed attempt and prepares job for another if
+// retryable and ASYNC_MAX_RETRIES allows it, returning how long to wait before
+// queueing it again. The delay grows with each retry: ASYNC_RETRY_DELAY_SECONDS,
+// then twice that, and so on.
+func retryJob(cfg *config.Config, job *ScanJob, errorMsg string, retryable bool) (time.Duration, bool) {
+	job.Attempts = append(job.Attempts, JobAttempt{FailedAt: time.Now(), Error: errorMsg})
+	if !retryable || job.RetryCount >= cfg.AsyncMaxRetries {
+		return 0, false
+	}
+
+	job.RetryCount++
+	job.Status = StatusQueued
+	job.StartedAt = nil
+	job.Error = errorMsg
+	return time.Duration(job.RetryCount) * cfg.AsyncRetryDelay, true
+}
+
+// reviveJob resets a dead-lettered job so it runs again from the start,
+// callback included. Its attempt history is kept.
+func reviveJob(job *ScanJob) {
+	job.Status = StatusQueued
+	job.RetryCount = 0
+	job.StartedAt = nil
+	job.CompletedAt = nil
+	job.Error = ""
+	job.WebhookDeliveries = nil
+	job.WebhookDelivered = nil
+	job.WebhookAttempts = 0
+	job.WebhookLastStatus = 0
+}
+
+// retryableCrawlError reports whether a crawl that failed with err, as
+// returned by Crawler.StartPageError, may succeed if tried again. Server
+// errors, rate limiting, timeouts and dropped connections are retried; other
+// statuses, unknown hosts and refused targets are not.
+func retryableCrawlError(err error) bool {
+	var statusErr *crawler.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+
+	if errors.Is(err, ssrf.ErrPrivateTarget) {
+		return false
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return !dnsErr.IsNotFound
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
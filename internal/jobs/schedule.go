@@ -0,0 +1,13 @@
+package jobs
+
+import "time"
+
+// schedule marks job to run at runAt instead of right away. Times that have
+// already passed leave the job queued.
+func schedule(job *ScanJob, runAt *time.Time) {
+	if runAt == nil || !runAt.After(time.Now()) {
+		return
+	}
+	job.Status = StatusScheduled
+	job.RunAt = runAt
+}
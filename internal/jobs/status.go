@@ -0,0 +1,75 @@
+package jobs
+
+import (
+	"sort"
+	"time"
+)
+
+// Worker states reported by WorkerPool.Status.
+const (
+	WorkerIdle       = "idle"
+	WorkerProcessing = "processing"
+)
+
+// WorkerState is what one worker is doing. Workers scaled away stay listed
+// until they have finished their current job.
+type WorkerState struct {
+	ID            int       `json:"id"`
+	State         string    `json:"state"`
+	CurrentJobID  string    `json:"current_job_id,omitempty"`
+	JobsProcessed int       `json:"jobs_processed"`
+	LastActivity  time.Time `json:"last_activity"`
+}
+
+// Status returns the state of every running worker, ordered by ID.
+func (wp *WorkerPool) Status() []WorkerState {
+	wp.statesMu.Lock()
+	defer wp.statesMu.Unlock()
+
+	states := make([]WorkerState, 0, len(wp.states))
+	for _, state := range wp.states {
+		states = append(states, *state)
+	}
+	sort.SliceStable(states, func(i, j int) bool { return states[i].ID < states[j].ID })
+	return states
+}
+
+// track registers a starting worker and returns its state, which the worker
+// updates through setState until it calls untrack.
+func (wp *WorkerPool) track(id int) *WorkerState {
+	wp.statesMu.Lock()
+	defer wp.statesMu.Unlock()
+
+	state := &WorkerState{ID: id, State: WorkerIdle, LastActivity: time.Now()}
+	wp.states = append(wp.states, state)
+	return state
+}
+
+func (wp *WorkerPool) untrack(state *WorkerState) {
+	wp.statesMu.Lock()
+	defer wp.statesMu.Unlock()
+
+	for i, s := range wp.states {
+		if s == state {
+			wp.states = append(wp.states[:i], wp.states[i+1:]...)
+			return
+		}
+	}
+}
+
+// setState records that the worker is processing jobID, or idle when jobID is
+// "". Going idle counts a processed job.
+func (wp *WorkerPool) setState(state *WorkerState, jobID string) {
+	wp.statesMu.Lock()
+	defer wp.statesMu.Unlock()
+
+	if jobID == "" && state.State == WorkerProcessing {
+		state.JobsProcessed++
+	}
+	state.State = WorkerProcessing
+	if jobID == "" {
+		state.State = WorkerIdle
+	}
+	state.CurrentJobID = jobID
+	state.LastActivity = time.Now()
+}
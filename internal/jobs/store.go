@@ -0,0 +1,102 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"email-crawler/internal/config"
+	"email-crawler/internal/crawler"
+)
+
+// JobStore persists scan jobs and hands them out to workers. Queue is the
+// Redis-backed implementation; MemoryQueue keeps everything in-process for
+// single-node deployments without Redis.
+type JobStore interface {
+	Enqueue(req AsyncScanRequest) (*ScanJob, error)
+	Dequeue(timeout time.Duration) (*ScanJob, error)
+	GetJob(jobID string) (*ScanJob, error)
+	// RequeueJob puts a dequeued job that never started back at the front of
+	// the queue
+	RequeueJob(job *ScanJob) error
+	// QueuePosition returns where a queued job stands, 1 being the next to
+	// run, or 0 if it isn't waiting on the queue.
+	QueuePosition(jobID string) (int, error)
+	UpdateJob(job *ScanJob) error
+	CompleteJob(job *ScanJob, emails []string, pagesVisited int, crawlTime string) error
+	// FailJob re-queues a retryable failure after a growing delay while
+	// retries remain, and otherwise marks the job failed. It reports whether
+	// the job was re-queued, in which case no webhook should be sent yet.
+	FailJob(job *ScanJob, errorMsg string, retryable bool) (bool, error)
+	// ListJobs returns stored jobs newest first, only those in status unless
+	// it is empty, skipping offset matches and returning at most limit. It
+	// also returns the total number of matches.
+	ListJobs(status JobStatus, limit, offset int) ([]ScanJob, int, error)
+	// DeadLetters lists jobs that failed for good, newest first. Only the
+	// latest ASYNC_DEAD_LETTER_SIZE are kept.
+	DeadLetters() ([]ScanJob, error)
+	// RetryDeadLetter takes a job off the dead-letter list and queues it again
+	RetryDeadLetter(jobID string) (*ScanJob, error)
+	// CancelJob cancels a queued or scheduled job outright. For a processing
+	// job it only requests cancellation; the worker running it stops the crawl
+	// and calls MarkCancelled.
+	CancelJob(jobID string) error
+	CancelRequested(jobID string) bool
+	MarkCancelled(job *ScanJob) error
+	CleanupStaleJobs() (int, error)
+	// PromoteDueJobs queues scheduled jobs and retries whose time has come
+	PromoteDueJobs() (int, error)
+	// Heartbeat renews the lease a worker holds on a processing job for ttl
+	Heartbeat(jobID string, ttl time.Duration) error
+	// ClaimOrphanedJobs returns processing jobs started more than olderThan
+	// ago whose lease has lapsed, taking the lease for ttl so that a single
+	// caller handles each.
+	ClaimOrphanedJobs(olderThan, ttl time.Duration) ([]*ScanJob, error)
+	Stats() map[string]interface{}
+	// RecordDuration and AverageDuration keep a rolling sample of how long
+	// the latest completed jobs took, shared by every instance using Redis.
+	// AverageDuration also returns the sample size.
+	RecordDuration(duration time.Duration)
+	AverageDuration() (time.Duration, int)
+	Ping(ctx context.Context) error
+
+	// Crawl frontiers let an interrupted job resume where it stopped
+	SaveFrontier(jobID string, frontier *crawler.Frontier) error
+	LoadFrontier(jobID string) (*crawler.Frontier, error)
+	DeleteFrontier(jobID string) error
+}
+
+// ErrNotDeadLettered is returned by RetryDeadLetter for jobs not on the
+// dead-letter list.
+var ErrNotDeadLettered = errors.New("job is not in the dead-letter queue")
+
+// ErrIdempotencyConflict is returned by Enqueue when the request's idempotency
+// key was already used for a different request.
+var ErrIdempotencyConflict = errors.New("idempotency key was already used with a different request")
+
+// jobCounts reads the queue size and active job count from store's Stats.
+func jobCounts(store JobStore) (queued, active int) {
+	stats := store.Stats()
+	if size, ok := stats["queue_size"].(int64); ok {
+		queued = int(size)
+	}
+	active, _ = stats["active_jobs"].(int)
+	return queued, active
+}
+
+// NewJobStore returns the job store selected by JOB_STORE_BACKEND.
+func NewJobStore(client *redis.Client, cfg *config.Config) JobStore {
+	switch cfg.JobStoreBackend {
+	case "memory":
+		log.Println("Using in-memory job store")
+		return NewMemoryQueue(cfg)
+	case "redis", "":
+		return NewQueue(client, cfg)
+	default:
+		log.Printf("Unknown job store backend %q, falling back to redis", cfg.JobStoreBackend)
+		return NewQueue(client, cfg)
+	}
+}
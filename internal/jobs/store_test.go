@@ -0,0 +1,335 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"email-crawler/internal/config"
+)
+
+func newTestConfig() *config.Config {
+	cfg := config.Load()
+	cfg.AsyncMaxRetries = 2
+	cfg.AsyncRetryDelay = time.Second
+	cfg.AsyncDeadLetterSize = 10
+	cfg.AsyncJobTTL = time.Hour
+	cfg.AsyncDeduplicateJobs = false
+	return cfg
+}
+
+// newTestQueue returns a Queue on the Redis at REDIS_TEST_ADDR with keys
+// under a prefix of its own, skipping the test when the variable isn't set.
+// Every key under the prefix is deleted when the test ends.
+func newTestQueue(t *testing.T, cfg *config.Config) *Queue {
+	t.Helper()
+	address := os.Getenv("REDIS_TEST_ADDR")
+	if address == "" {
+		t.Skip("REDIS_TEST_ADDR not set")
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: address})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Fatalf("could not connect to Redis at %s: %v", address, err)
+	}
+	cfg.JobKeyPrefix = fmt.Sprintf("gurl-test:%d:", time.Now().UnixNano())
+
+	t.Cleanup(func() {
+		keys, _ := client.Keys(context.Background(), cfg.JobKeyPrefix+"*").Result()
+		if len(keys) > 0 {
+			client.Del(context.Background(), keys...)
+		}
+		client.Close()
+	})
+	return NewQueue(client, cfg)
+}
+
+// forEachStore runs test against every JobStore implementation, configured
+// by configure if it isn't nil.
+func forEachStore(t *testing.T, configure func(cfg *config.Config), test func(t *testing.T, store JobStore)) {
+	newConfig := func() *config.Config {
+		cfg := newTestConfig()
+		if configure != nil {
+			configure(cfg)
+		}
+		return cfg
+	}
+
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemoryQueue(newConfig()))
+	})
+	t.Run("redis", func(t *testing.T) {
+		test(t, newTestQueue(t, newConfig()))
+	})
+}
+
+func mustEnqueue(t *testing.T, store JobStore, rawURL string) *ScanJob {
+	t.Helper()
+	job, err := store.Enqueue(AsyncScanRequest{URL: rawURL, WebhookURL: "https://hooks.example.com"})
+	if err != nil {
+		t.Fatalf("Enqueue(%s): %v", rawURL, err)
+	}
+	return job
+}
+
+func mustDequeue(t *testing.T, store JobStore) *ScanJob {
+	t.Helper()
+	job, err := store.Dequeue(time.Second)
+	if err != nil {
+		t.Fatalf("Dequeue: %v", err)
+	}
+	if job == nil {
+		t.Fatal("Dequeue returned no job")
+	}
+	return job
+}
+
+func assertStatus(t *testing.T, store JobStore, jobID string, want JobStatus) *ScanJob {
+	t.Helper()
+	job, err := store.GetJob(jobID)
+	if err != nil {
+		t.Fatalf("GetJob(%s): %v", jobID, err)
+	}
+	if job.Status != want {
+		t.Fatalf("job %s is %s, want %s", jobID, job.Status, want)
+	}
+	return job
+}
+
+func TestJobStoreLifecycle(t *testing.T) {
+	forEachStore(t, nil, func(t *testing.T, store JobStore) {
+		queued := mustEnqueue(t, store, "https://example.com")
+		assertStatus(t, store, queued.ID, StatusQueued)
+		if queuedJobs, activeJobs := jobCounts(store); queuedJobs != 1 || activeJobs != 1 {
+			t.Fatalf("jobCounts = %d queued, %d active; want 1, 1", queuedJobs, activeJobs)
+		}
+
+		job := mustDequeue(t, store)
+		if job.ID != queued.ID {
+			t.Fatalf("dequeued %s, want %s", job.ID, queued.ID)
+		}
+		assertStatus(t, store, job.ID, StatusProcessing)
+
+		if err := store.CompleteJob(job, []string{"info@example.com"}, 3, "1s"); err != nil {
+			t.Fatalf("CompleteJob: %v", err)
+		}
+		done := assertStatus(t, store, job.ID, StatusCompleted)
+		if len(done.Emails) != 1 || done.PagesVisited != 3 || done.CompletedAt == nil {
+			t.Errorf("completed job = %+v, want its results recorded", done)
+		}
+		if queuedJobs, activeJobs := jobCounts(store); queuedJobs != 0 || activeJobs != 0 {
+			t.Errorf("jobCounts = %d queued, %d active; want 0, 0", queuedJobs, activeJobs)
+		}
+
+		if _, err := store.GetJob("missing"); err == nil {
+			t.Error("GetJob of an unknown job succeeded")
+		}
+	})
+}
+
+func TestJobStoreQueueOrder(t *testing.T) {
+	forEachStore(t, nil, func(t *testing.T, store JobStore) {
+		first := mustEnqueue(t, store, "https://one.example.com")
+		second := mustEnqueue(t, store, "https://two.example.com")
+
+		for jobID, want := range map[string]int{first.ID: 1, second.ID: 2} {
+			if position, err := store.QueuePosition(jobID); err != nil || position != want {
+				t.Errorf("QueuePosition(%s) = %d, %v; want %d", jobID, position, err, want)
+			}
+		}
+
+		job := mustDequeue(t, store)
+		if job.ID != first.ID {
+			t.Fatalf("dequeued %s, want the oldest job %s", job.ID, first.ID)
+		}
+		if position, _ := store.QueuePosition(job.ID); position != 0 {
+			t.Errorf("QueuePosition of a dequeued job = %d, want 0", position)
+		}
+
+		// A requeued job goes back to the front
+		if err := store.RequeueJob(job); err != nil {
+			t.Fatalf("RequeueJob: %v", err)
+		}
+		assertStatus(t, store, job.ID, StatusQueued)
+		if next := mustDequeue(t, store); next.ID != first.ID {
+			t.Errorf("dequeued %s after RequeueJob, want %s", next.ID, first.ID)
+		}
+	})
+}
+
+func TestJobStoreRetriesThenDeadLetters(t *testing.T) {
+	forEachStore(t, func(cfg *config.Config) {
+		cfg.AsyncMaxRetries = 1
+		// Redis schedules retries to the second
+		cfg.AsyncRetryDelay = 2 * time.Second
+	}, func(t *testing.T, store JobStore) {
+		mustEnqueue(t, store, "https://example.com")
+		job := mustDequeue(t, store)
+
+		requeued, err := store.FailJob(job, "502 Bad Gateway", true)
+		if err != nil || !requeued {
+			t.Fatalf("FailJob = %v, %v; want the job requeued", requeued, err)
+		}
+		assertStatus(t, store, job.ID, StatusQueued)
+		if promoted, _ := store.PromoteDueJobs(); promoted != 0 {
+			t.Fatalf("PromoteDueJobs promoted %d jobs before the retry delay", promoted)
+		}
+
+		time.Sleep(2100 * time.Millisecond)
+		if promoted, err := store.PromoteDueJobs(); err != nil || promoted != 1 {
+			t.Fatalf("PromoteDueJobs = %d, %v; want 1", promoted, err)
+		}
+		job = mustDequeue(t, store)
+		if job.RetryCount != 1 {
+			t.Errorf("RetryCount = %d, want 1", job.RetryCount)
+		}
+
+		if requeued, err := store.FailJob(job, "502 Bad Gateway", true); err != nil || requeued {
+			t.Fatalf("FailJob past ASYNC_MAX_RETRIES = %v, %v; want the job failed", requeued, err)
+		}
+		failed := assertStatus(t, store, job.ID, StatusFailed)
+		if len(failed.Attempts) != 2 {
+			t.Errorf("job has %d attempts recorded, want 2", len(failed.Attempts))
+		}
+
+		deadLetters, err := store.DeadLetters()
+		if err != nil || len(deadLetters) != 1 || deadLetters[0].ID != job.ID {
+			t.Fatalf("DeadLetters = %v, %v; want the failed job", deadLetters, err)
+		}
+
+		revived, err := store.RetryDeadLetter(job.ID)
+		if err != nil {
+			t.Fatalf("RetryDeadLetter: %v", err)
+		}
+		if revived.Status != StatusQueued || revived.RetryCount != 0 {
+			t.Errorf("revived job = %+v, want it queued with no retries", revived)
+		}
+		if _, err := store.RetryDeadLetter(job.ID); err != ErrNotDeadLettered {
+			t.Errorf("second RetryDeadLetter error = %v, want ErrNotDeadLettered", err)
+		}
+		if next := mustDequeue(t, store); next.ID != job.ID {
+			t.Errorf("dequeued %s, want the revived job %s", next.ID, job.ID)
+		}
+	})
+}
+
+func TestJobStoreCancel(t *testing.T) {
+	forEachStore(t, nil, func(t *testing.T, store JobStore) {
+		queued := mustEnqueue(t, store, "https://queued.example.com")
+		if err := store.CancelJob(queued.ID); err != nil {
+			t.Fatalf("CancelJob: %v", err)
+		}
+		assertStatus(t, store, queued.ID, StatusCancelled)
+		if position, _ := store.QueuePosition(queued.ID); position != 0 {
+			t.Errorf("cancelled job still queued at position %d", position)
+		}
+
+		mustEnqueue(t, store, "https://processing.example.com")
+		processing := mustDequeue(t, store)
+		if err := store.CancelJob(processing.ID); err != nil {
+			t.Fatalf("CancelJob: %v", err)
+		}
+		// The worker running it finishes the cancellation
+		assertStatus(t, store, processing.ID, StatusProcessing)
+		if !store.CancelRequested(processing.ID) {
+			t.Fatal("CancelRequested = false after cancelling a processing job")
+		}
+		if err := store.MarkCancelled(processing); err != nil {
+			t.Fatalf("MarkCancelled: %v", err)
+		}
+		assertStatus(t, store, processing.ID, StatusCancelled)
+		if _, activeJobs := jobCounts(store); activeJobs != 0 {
+			t.Errorf("%d jobs still active, want 0", activeJobs)
+		}
+	})
+}
+
+func TestJobStoreListJobs(t *testing.T) {
+	forEachStore(t, nil, func(t *testing.T, store JobStore) {
+		var ids []string
+		for i := 0; i < 3; i++ {
+			ids = append(ids, mustEnqueue(t, store, fmt.Sprintf("https://%d.example.com", i)).ID)
+			// The index orders jobs by creation time in milliseconds
+			time.Sleep(2 * time.Millisecond)
+		}
+		job := mustDequeue(t, store)
+		if err := store.CompleteJob(job, nil, 1, "1s"); err != nil {
+			t.Fatalf("CompleteJob: %v", err)
+		}
+
+		listed, total, err := store.ListJobs("", 2, 0)
+		if err != nil {
+			t.Fatalf("ListJobs: %v", err)
+		}
+		if len(listed) != 2 || listed[0].ID != ids[2] || listed[1].ID != ids[1] || total != 3 {
+			t.Errorf("first page = %v of %d, want the two newest jobs of 3", jobIDs(listed), total)
+		}
+		listed, total, _ = store.ListJobs("", 2, 2)
+		if len(listed) != 1 || listed[0].ID != ids[0] || total != 3 {
+			t.Errorf("second page = %v of %d, want the oldest job of 3", jobIDs(listed), total)
+		}
+		listed, total, _ = store.ListJobs("", 2, 5)
+		if len(listed) != 0 || total != 3 {
+			t.Errorf("page past the end = %v of %d, want no jobs of 3", jobIDs(listed), total)
+		}
+
+		listed, total, _ = store.ListJobs(StatusCompleted, 10, 0)
+		if len(listed) != 1 || listed[0].ID != job.ID || total != 1 {
+			t.Errorf("completed jobs = %v of %d, want [%s] of 1", jobIDs(listed), total, job.ID)
+		}
+		// The job left the queued and processing lists as it moved on
+		listed, total, _ = store.ListJobs(StatusQueued, 1, 0)
+		if len(listed) != 1 || listed[0].ID != ids[2] || total != 2 {
+			t.Errorf("queued jobs = %v of %d, want [%s] of 2", jobIDs(listed), total, ids[2])
+		}
+		if listed, total, _ = store.ListJobs(StatusProcessing, 10, 0); len(listed) != 0 || total != 0 {
+			t.Errorf("processing jobs = %v of %d, want none", jobIDs(listed), total)
+		}
+	})
+}
+
+func TestJobStoreIdempotencyAndDedup(t *testing.T) {
+	forEachStore(t, func(cfg *config.Config) {
+		cfg.AsyncDeduplicateJobs = true
+	}, func(t *testing.T, store JobStore) {
+		req := AsyncScanRequest{URL: "https://example.com", IdempotencyKey: "order-1", Fingerprint: "a"}
+		first, err := store.Enqueue(req)
+		if err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+		replayed, err := store.Enqueue(req)
+		if err != nil || replayed.ID != first.ID || !replayed.Replayed {
+			t.Fatalf("repeated Enqueue = %+v, %v; want job %s replayed", replayed, err, first.ID)
+		}
+		req.Fingerprint = "b"
+		if _, err := store.Enqueue(req); err != ErrIdempotencyConflict {
+			t.Errorf("Enqueue with a reused key error = %v, want ErrIdempotencyConflict", err)
+		}
+
+		duplicate, err := store.Enqueue(AsyncScanRequest{URL: "https://example.com"})
+		if err != nil || duplicate.ID != first.ID || !duplicate.Deduplicated {
+			t.Fatalf("duplicate Enqueue = %+v, %v; want job %s deduplicated", duplicate, err, first.ID)
+		}
+
+		job := mustDequeue(t, store)
+		if err := store.CompleteJob(job, nil, 1, "1s"); err != nil {
+			t.Fatalf("CompleteJob: %v", err)
+		}
+		fresh, err := store.Enqueue(AsyncScanRequest{URL: "https://example.com"})
+		if err != nil || fresh.ID == first.ID {
+			t.Errorf("Enqueue after completion = %+v, %v; want a new job", fresh, err)
+		}
+	})
+}
+
+func jobIDs(jobs []ScanJob) []string {
+	ids := make([]string, len(jobs))
+	for i, job := range jobs {
+		ids[i] = job.ID
+	}
+	return ids
+}
@@ -7,6 +7,7 @@ import (
 type JobStatus string
 
 const (
+	StatusScheduled  JobStatus = "scheduled"
 	StatusQueued     JobStatus = "queued"
 	StatusProcessing JobStatus = "processing"
 	StatusCompleted  JobStatus = "completed"
@@ -25,24 +26,162 @@ type ScanJob struct {
 	CompletedAt *time.Time `json:"completed_at,omitempty"`
 	CrawlTime   string    `json:"crawl_time,omitempty"`
 	Error       string    `json:"error,omitempty"`
+	// RetryCount is how many times the job was re-queued after a transient failure
+	RetryCount int `json:"retry_count,omitempty"`
+	// Attempts records each failed attempt, oldest first
+	Attempts []JobAttempt `json:"attempts,omitempty"`
+
+	// RunAt is when a scheduled job is queued
+	RunAt *time.Time `json:"run_at,omitempty"`
+
+	// Crawl options
+	IfModifiedSince *time.Time `json:"if_modified_since,omitempty"`
+	TenantID        string     `json:"tenant_id,omitempty"`
+	MaxDepth        *int       `json:"max_depth,omitempty"`
+	ForceRefresh    bool       `json:"force_refresh,omitempty"`
+
+	// Webhook options
+	WebhookCompress bool              `json:"webhook_compress,omitempty"`
+	NotifyOnEnqueue bool              `json:"notify_on_enqueue,omitempty"`
+	WebhookHeaders  map[string]string `json:"webhook_headers,omitempty"`
+	// WebhookURLs are further receivers notified alongside WebhookURL
+	WebhookURLs []string `json:"webhook_urls,omitempty"`
+	// WebhookDeliveries is the outcome of the last callback per receiver
+	WebhookDeliveries []WebhookDeliveryStatus `json:"webhook_deliveries,omitempty"`
+	// WebhookDelivered, WebhookAttempts and WebhookLastStatus summarize the
+	// terminal callback across receivers once it has been sent
+	WebhookDelivered  *bool `json:"webhook_delivered,omitempty"`
+	WebhookAttempts   int   `json:"webhook_attempts,omitempty"`
+	WebhookLastStatus int   `json:"webhook_last_status,omitempty"`
 	
 	// Results
 	Emails       []string `json:"emails,omitempty"`
 	PagesVisited int      `json:"pages_visited,omitempty"`
+
+	// Deduplicated is set on the existing job Enqueue returns in place of a
+	// duplicate, and Replayed when it returns it for a repeated idempotency
+	// key. Neither is stored.
+	Deduplicated bool `json:"-"`
+	Replayed     bool `json:"-"`
+}
+
+// Redacted returns a copy of the job safe to show to API clients, with the
+// values of its webhook headers hidden since they often carry credentials.
+func (j ScanJob) Redacted() ScanJob {
+	if len(j.WebhookHeaders) > 0 {
+		headers := make(map[string]string, len(j.WebhookHeaders))
+		for name := range j.WebhookHeaders {
+			headers[name] = redactedHeaderValue
+		}
+		j.WebhookHeaders = headers
+	}
+	return j
+}
+
+// WebhookTargets returns every URL the job's callbacks go to, WebhookURL
+// first, without duplicates.
+func (j *ScanJob) WebhookTargets() []string {
+	var targets []string
+	seen := make(map[string]bool)
+	for _, target := range append([]string{j.WebhookURL}, j.WebhookURLs...) {
+		if target == "" || seen[target] {
+			continue
+		}
+		seen[target] = true
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+// recordDeliveries stores the outcome of a callback on the job. It counts as
+// delivered only if every receiver accepted it; WebhookLastStatus is the
+// status code of the most recent attempt, 0 if it got no response.
+func (j *ScanJob) recordDeliveries(deliveries []WebhookDeliveryStatus) {
+	delivered := true
+	attempts := 0
+	var last WebhookDeliveryStatus
+	for _, d := range deliveries {
+		delivered = delivered && d.Delivered
+		attempts += d.Attempts
+		if !d.At.Before(last.At) {
+			last = d
+		}
+	}
+	j.WebhookDeliveries = deliveries
+	j.WebhookDelivered = &delivered
+	j.WebhookAttempts = attempts
+	j.WebhookLastStatus = last.StatusCode
+}
+
+// WebhookDeliveryStatus is the outcome of delivering a callback to one
+// receiver, after all of its attempts.
+type WebhookDeliveryStatus struct {
+	URL        string    `json:"url"`
+	Status     JobStatus `json:"status"`
+	Delivered  bool      `json:"delivered"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Attempts   int       `json:"attempts"`
+	Error      string    `json:"error,omitempty"`
+	At         time.Time `json:"at"`
+}
+
+// JobAttempt is one failed run of a job.
+type JobAttempt struct {
+	FailedAt time.Time `json:"failed_at"`
+	Error    string    `json:"error"`
 }
 
 type AsyncScanRequest struct {
 	URL        string `json:"url" binding:"required"`
 	WebhookURL string `json:"webhook_url" binding:"required"`
 	CallbackID string `json:"callback_id,omitempty"`
+
+	// WebhookURLs notifies further receivers, each delivered and retried on
+	// its own. The handler moves the first into WebhookURL when that is unset.
+	WebhookURLs []string `json:"webhook_urls,omitempty"`
+
+	// RunAt (RFC3339) or DelaySeconds schedules the job for later instead of
+	// queueing it now. The handler folds DelaySeconds into RunAt.
+	RunAt        *time.Time `json:"run_at,omitempty"`
+	DelaySeconds int        `json:"delay_seconds,omitempty"`
+
+	// IfModifiedSince limits extraction to pages modified after this time (RFC3339)
+	IfModifiedSince *time.Time `json:"if_modified_since,omitempty"`
+
+	// MaxDepth overrides the default crawl depth, up to CRAWLER_MAX_DEPTH_LIMIT
+	MaxDepth *int `json:"max_depth,omitempty"`
+
+	// ForceRefresh crawls even when a cached result exists, then replaces it
+	ForceRefresh bool `json:"force_refresh,omitempty"`
+
+	// WebhookCompress gzips large webhook payloads (Content-Encoding: gzip)
+	WebhookCompress bool `json:"webhook_compress,omitempty"`
+
+	// NotifyOnEnqueue sends a "queued" webhook as soon as the job is accepted
+	NotifyOnEnqueue bool `json:"notify_on_enqueue,omitempty"`
+
+	// WebhookHeaders are added to every webhook request for the job, e.g. an
+	// Authorization token. See ValidateWebhookHeaders for what is refused.
+	WebhookHeaders map[string]string `json:"webhook_headers,omitempty"`
+
+	// TenantID is set by the handler from the request headers, never the body
+	TenantID string `json:"-"`
+
+	// IdempotencyKey comes from the Idempotency-Key header and Fingerprint is
+	// a hash of the request body. A repeated key with the same fingerprint
+	// returns the original job.
+	IdempotencyKey string `json:"-"`
+	Fingerprint    string `json:"-"`
 }
 
 type AsyncScanResponse struct {
-	JobID          string `json:"job_id"`
-	Status         string `json:"status"`
-	EstimatedTime  string `json:"estimated_time"`
-	WebhookURL     string `json:"webhook_url"`
-	CheckStatusURL string `json:"check_status_url"`
+	JobID          string   `json:"job_id"`
+	Status         string   `json:"status"`
+	EstimatedTime  string   `json:"estimated_time"`
+	WebhookURL     string   `json:"webhook_url"`
+	WebhookURLs    []string `json:"webhook_urls,omitempty"`
+	CheckStatusURL string   `json:"check_status_url"`
+	Deduplicated   bool     `json:"deduplicated,omitempty"`
 }
 
 type WebhookPayload struct {
@@ -53,6 +192,6 @@ type WebhookPayload struct {
 	Emails       []string  `json:"emails,omitempty"`
 	CrawlTime    string    `json:"crawl_time,omitempty"`
 	PagesVisited int       `json:"pages_visited,omitempty"`
-	CompletedAt  time.Time `json:"completed_at"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
 	Error        string    `json:"error,omitempty"`
 }
\ No newline at end of file
@@ -0,0 +1,194 @@
+package jobs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/http/httpguts"
+
+	"email-crawler/internal/config"
+	"email-crawler/internal/ssrf"
+)
+
+// MaxWebhookURLs caps the receivers a single job may notify.
+const MaxWebhookURLs = 10
+
+// maxWebhookHeaders caps the custom headers a job or probe may send.
+const maxWebhookHeaders = 20
+
+// redactedHeaderValue replaces webhook header values in API responses.
+const redactedHeaderValue = "[redacted]"
+
+// reservedWebhookHeaders are set by postWebhook or the transport and can't be
+// overridden by clients.
+var reservedWebhookHeaders = map[string]bool{
+	"Connection":        true,
+	"Content-Encoding":  true,
+	"Content-Length":    true,
+	"Content-Type":      true,
+	"Host":              true,
+	"Keep-Alive":        true,
+	"Te":                true,
+	"Trailer":           true,
+	"Transfer-Encoding": true,
+	"Upgrade":           true,
+	"X-Signature":       true,
+	"X-Webhook-Test":    true,
+}
+
+// ValidateWebhookHeaders checks custom webhook headers: at most
+// maxWebhookHeaders, with valid names and values, none of them reserved.
+func ValidateWebhookHeaders(headers map[string]string) error {
+	if len(headers) > maxWebhookHeaders {
+		return fmt.Errorf("too many webhook headers (at most %d)", maxWebhookHeaders)
+	}
+	for name, value := range headers {
+		if !httpguts.ValidHeaderFieldName(name) {
+			return fmt.Errorf("invalid webhook header name %q", name)
+		}
+		if !httpguts.ValidHeaderFieldValue(value) {
+			return fmt.Errorf("invalid value for webhook header %q", name)
+		}
+		if reservedWebhookHeaders[http.CanonicalHeaderKey(name)] {
+			return fmt.Errorf("webhook header %q can't be overridden", name)
+		}
+	}
+	return nil
+}
+
+// WebhookProbeResult describes a single test delivery made by ProbeWebhook.
+type WebhookProbeResult struct {
+	WebhookURL string `json:"webhook_url"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Latency    string `json:"latency"`
+	Delivered  bool   `json:"delivered"`
+	Error      string `json:"error,omitempty"`
+}
+
+// webhookDelivery describes a single POST to a webhook receiver.
+type webhookDelivery struct {
+	URL     string
+	Body    []byte
+	Headers map[string]string
+	// Compress gzips bodies of at least WEBHOOK_COMPRESS_MIN_BYTES
+	Compress bool
+}
+
+// signWebhook returns the X-Signature header value for body sent at
+// timestamp: "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">". body is
+// the JSON payload before any gzip encoding.
+func signWebhook(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// postWebhook makes a single delivery attempt and returns the receiver's
+// status code. Each attempt is signed afresh when WEBHOOK_SIGNING_SECRET is
+// set, so retries carry a current timestamp.
+func postWebhook(ctx context.Context, client *http.Client, cfg *config.Config, d webhookDelivery) (int, error) {
+	body := d.Body
+	signature := ""
+	if cfg.WebhookSigningSecret != "" {
+		signature = signWebhook(cfg.WebhookSigningSecret, time.Now(), body)
+	}
+	compressed := d.Compress && len(body) >= cfg.WebhookCompressMinBytes
+	if compressed {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		if _, err := gz.Write(body); err != nil {
+			return 0, fmt.Errorf("failed to compress webhook payload: %v", err)
+		}
+		if err := gz.Close(); err != nil {
+			return 0, fmt.Errorf("failed to compress webhook payload: %v", err)
+		}
+		body = buf.Bytes()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	for name, value := range d.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if compressed {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if signature != "" {
+		req.Header.Set("X-Signature", signature)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
+
+// ProbeWebhook sends a sample payload to webhookURL without creating a job, so
+// clients can verify their receiver before submitting real scans. Private and
+// internal targets are refused unless ALLOW_PRIVATE_TARGETS is set.
+func ProbeWebhook(ctx context.Context, cfg *config.Config, webhookURL string, headers map[string]string, compress bool) (*WebhookProbeResult, error) {
+	now := time.Now()
+	payload := WebhookPayload{
+		JobID:        "webhook-test",
+		CallbackID:   "webhook-test",
+		Status:       StatusCompleted,
+		URL:          "https://example.com",
+		Emails:       []string{"contact@example.com"},
+		CrawlTime:    "1s",
+		PagesVisited: 1,
+		CompletedAt:  &now,
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal webhook payload: %v", err)
+	}
+
+	client := &http.Client{Timeout: cfg.AsyncWebhookTimeout}
+	if !cfg.AllowPrivateTargets {
+		client.Transport = ssrf.NewTransport()
+	}
+
+	probeHeaders := map[string]string{"X-Webhook-Test": "true"}
+	for name, value := range headers {
+		probeHeaders[name] = value
+	}
+
+	start := time.Now()
+	status, err := postWebhook(ctx, client, cfg, webhookDelivery{
+		URL:      webhookURL,
+		Body:     jsonData,
+		Headers:  probeHeaders,
+		Compress: compress || cfg.WebhookCompress,
+	})
+	result := &WebhookProbeResult{
+		WebhookURL: webhookURL,
+		StatusCode: status,
+		Latency:    time.Since(start).String(),
+		Delivered:  err == nil && status >= 200 && status < 300,
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+
+	return result, nil
+}
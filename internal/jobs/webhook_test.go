@@ -0,0 +1,86 @@
+package jobs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"email-crawler/internal/config"
+)
+
+func TestDeliverWebhookToRefusesPrivateReceivers(t *testing.T) {
+	var received atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+	}))
+	defer receiver.Close()
+
+	cfg := config.Load()
+	cfg.AsyncWebhookRetries = 1
+	job := &ScanJob{ID: "job", Status: StatusCompleted}
+
+	cfg.AllowPrivateTargets = false
+	delivery := deliverWebhookTo(cfg, "test", job, receiver.URL, []byte(`{}`))
+	if delivery.Delivered || !strings.Contains(delivery.Error, "private") {
+		t.Fatalf("delivery to %s = %+v, want it refused as private", receiver.URL, delivery)
+	}
+	if received.Load() != 0 {
+		t.Fatalf("receiver got %d requests, want 0", received.Load())
+	}
+
+	cfg.AllowPrivateTargets = true
+	if delivery := deliverWebhookTo(cfg, "test", job, receiver.URL, []byte(`{}`)); !delivery.Delivered {
+		t.Fatalf("delivery with ALLOW_PRIVATE_TARGETS = %+v, want delivered", delivery)
+	}
+}
+
+func TestDeliverWebhookToCompressesLargeBodies(t *testing.T) {
+	var encoding string
+	var received []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding = r.Header.Get("Content-Encoding")
+		body := io.Reader(r.Body)
+		if encoding == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			body = gz
+		}
+		received, _ = io.ReadAll(body)
+	}))
+	defer receiver.Close()
+
+	cfg := config.Load()
+	cfg.AllowPrivateTargets = true
+	cfg.AsyncWebhookRetries = 1
+	cfg.WebhookCompressMinBytes = 1024
+	job := &ScanJob{ID: "job", Status: StatusCompleted, WebhookCompress: true}
+
+	large := []byte(`{"emails":["` + strings.Repeat("a", 4096) + `@example.com"]}`)
+	small := []byte(`{"emails":["info@example.com"]}`)
+	for _, tt := range []struct {
+		name string
+		body []byte
+		want string
+	}{
+		{"above threshold", large, "gzip"},
+		{"below threshold", small, ""},
+	} {
+		if delivery := deliverWebhookTo(cfg, "test", job, receiver.URL, tt.body); !delivery.Delivered {
+			t.Fatalf("%s: delivery = %+v, want delivered", tt.name, delivery)
+		}
+		if encoding != tt.want {
+			t.Errorf("%s: Content-Encoding = %q, want %q", tt.name, encoding, tt.want)
+		}
+		if !bytes.Equal(received, tt.body) {
+			t.Errorf("%s: receiver decoded %d bytes that differ from the %d-byte payload", tt.name, len(received), len(tt.body))
+		}
+	}
+}
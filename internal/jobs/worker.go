@@ -1,37 +1,49 @@
 package jobs
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"email-crawler/internal/cache"
 	"email-crawler/internal/config"
 	"email-crawler/internal/crawler"
+	"email-crawler/internal/emailcheck"
+	"email-crawler/internal/metrics"
+	"email-crawler/internal/ssrf"
 )
 
 type WorkerPool struct {
-	queue        *Queue
-	cacheManager *cache.CacheManager
+	queue        JobStore
+	cacheManager cache.Cache
 	config       *config.Config
+	emailChecker *emailcheck.Checker
+	mu           sync.Mutex
 	workers      []chan bool
+	running      sync.WaitGroup // worker goroutines, including ones scaled away
+	statesMu     sync.Mutex
+	states       []*WorkerState // one per worker goroutine
 	ctx          context.Context
 	cancel       context.CancelFunc
 }
 
-func NewWorkerPool(queue *Queue, cacheManager *cache.CacheManager, config *config.Config) *WorkerPool {
+func NewWorkerPool(queue JobStore, cacheManager cache.Cache, config *config.Config) *WorkerPool {
 	ctx, cancel := context.WithCancel(context.Background())
 	
+	metrics.WatchJobs(func() (int, int) { return jobCounts(queue) })
+	
 	return &WorkerPool{
 		queue:        queue,
 		cacheManager: cacheManager,
 		config:       config,
-		workers:      make([]chan bool, config.AsyncWorkers),
+		emailChecker: emailcheck.NewChecker(config),
+		workers:      make([]chan bool, 0, config.AsyncWorkers),
 		ctx:          ctx,
 		cancel:       cancel,
 	}
@@ -39,27 +51,166 @@ func NewWorkerPool(queue *Queue, cacheManager *cache.CacheManager, config *confi
 
 func (wp *WorkerPool) Start() {
 	log.Printf("Starting %d async workers", wp.config.AsyncWorkers)
+	if wp.config.AsyncWorkers == 0 {
+		log.Println("No workers running: jobs will queue until the pool is scaled up")
+	}
 	
-	for i := 0; i < wp.config.AsyncWorkers; i++ {
-		wp.workers[i] = make(chan bool)
-		go wp.worker(i, wp.workers[i])
+	wp.Resize(wp.config.AsyncWorkers)
+
+	if wp.config.AsyncCleanupInterval > 0 {
+		go wp.cleanupLoop()
+	}
+	// Without leases every running job would look abandoned
+	if wp.config.AsyncReaperInterval > 0 && wp.config.AsyncLeaseTTL > 0 {
+		go wp.reapLoop()
+	}
+	go wp.dispatchLoop()
+}
+
+// dispatchInterval is how often scheduled jobs and retries are checked
+const dispatchInterval = time.Second
+
+// dispatchLoop moves scheduled jobs and retries onto the queue once due.
+func (wp *WorkerPool) dispatchLoop() {
+	ticker := time.NewTicker(dispatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-wp.ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := wp.queue.PromoteDueJobs(); err != nil {
+				log.Printf("Job dispatch error: %v", err)
+			}
+		}
 	}
 }
 
+// cleanupLoop periodically prunes phantom entries from the active set and queue
+// so Stats() doesn't report jobs whose data has already expired.
+func (wp *WorkerPool) cleanupLoop() {
+	ticker := time.NewTicker(wp.config.AsyncCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-wp.ctx.Done():
+			return
+		case <-ticker.C:
+			removed, err := wp.queue.CleanupStaleJobs()
+			if err != nil {
+				log.Printf("Job cleanup error: %v", err)
+				continue
+			}
+			if removed > 0 {
+				log.Printf("Job cleanup: removed %d stale entries", removed)
+			}
+		}
+	}
+}
+
+// Resize starts or stops workers until n are running. Stopped workers finish
+// their current job first. 0 is the lowest size: jobs are still accepted and
+// queue until the pool is resized up again, as with ASYNC_WORKERS=0. It
+// returns the previous number of workers.
+func (wp *WorkerPool) Resize(n int) int {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	
+	previous := len(wp.workers)
+	for len(wp.workers) < n {
+		id := len(wp.workers)
+		stop := make(chan bool)
+		wp.workers = append(wp.workers, stop)
+		wp.running.Add(1)
+		go wp.worker(id, stop)
+	}
+	for len(wp.workers) > n {
+		last := len(wp.workers) - 1
+		close(wp.workers[last])
+		wp.workers = wp.workers[:last]
+	}
+	return previous
+}
+
+// Size returns the number of running workers.
+func (wp *WorkerPool) Size() int {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	return len(wp.workers)
+}
+
+// Stop interrupts running jobs and stops every worker. Interrupted jobs are
+// retried, or left to resume when crawls are resumable.
 func (wp *WorkerPool) Stop() {
 	log.Println("Stopping worker pool...")
 	wp.cancel()
 	
 	// Signal all workers to stop
+	wp.mu.Lock()
 	for i, worker := range wp.workers {
 		log.Printf("Stopping worker %d", i)
 		close(worker)
 	}
+	wp.workers = nil
+	wp.mu.Unlock()
 	
+	// Workers finish recording their current job before exiting
+	wp.running.Wait()
 	log.Println("All workers stopped")
 }
 
+// Drain stops the workers from taking new jobs and waits for their current
+// jobs to finish, up to ctx's deadline, after which the remaining jobs are
+// interrupted as by Stop. Either way the pool is stopped when Drain returns;
+// the error is ctx's if it expired first.
+func (wp *WorkerPool) Drain(ctx context.Context) error {
+	log.Println("Draining worker pool...")
+	wp.mu.Lock()
+	for _, worker := range wp.workers {
+		close(worker)
+	}
+	wp.workers = nil
+	wp.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		wp.running.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+		log.Println("Worker pool drained")
+	case <-ctx.Done():
+		err = ctx.Err()
+		log.Println("Worker pool did not drain in time, interrupting running jobs")
+	}
+	// Also stops the dispatch and cleanup loops
+	wp.cancel()
+	<-drained
+	log.Println("All workers stopped")
+	return err
+}
+
+// stopping reports whether the worker owning stop has been told to exit.
+func (wp *WorkerPool) stopping(stop chan bool) bool {
+	select {
+	case <-stop:
+		return true
+	case <-wp.ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
+
 func (wp *WorkerPool) worker(id int, stop chan bool) {
+	defer wp.running.Done()
+	state := wp.track(id)
+	defer wp.untrack(state)
 	log.Printf("Worker %d started", id)
 	
 	for {
@@ -80,29 +231,74 @@ func (wp *WorkerPool) worker(id int, stop chan bool) {
 			
 			if job == nil {
 				// No jobs available, continue polling
+				wp.setState(state, "")
 				continue
 			}
+
+			// The job may have arrived while Dequeue was blocked and the pool
+			// began stopping; hand it back rather than strand it as processing
+			if wp.stopping(stop) {
+				log.Printf("Worker %d: returning job %s to the queue", id, job.ID)
+				if err := wp.queue.RequeueJob(job); err != nil {
+					log.Printf("Worker %d: failed to requeue job %s: %v", id, job.ID, err)
+				}
+				return
+			}
 			
 			log.Printf("Worker %d: processing job %s for URL: %s", id, job.ID, job.URL)
+			wp.setState(state, job.ID)
 			wp.processJob(id, job)
+			wp.setState(state, "")
 		}
 	}
 }
 
 func (wp *WorkerPool) processJob(workerID int, job *ScanJob) {
 	startTime := time.Now()
+	defer wp.holdLease(job.ID)()
+	
+	tenant, _ := wp.config.Tenant(job.TenantID)
+	cacheManager := wp.cacheManager
+	if tenant != nil && tenant.CachePrefix != "" {
+		cacheManager = cacheManager.WithPrefix(tenant.CachePrefix)
+	}
+	
+	// Incremental jobs only report recently modified pages, so they bypass the cache
+	incremental := job.IfModifiedSince != nil
+	
+	// Jobs with their own depth are cached apart from default-depth results
+	opts := crawler.OptionsForTenant(wp.config, tenant)
+	cacheKey := job.URL
+	if job.MaxDepth != nil {
+		cacheKey = cache.DepthKey(job.URL, *job.MaxDepth, opts.MaxDepth)
+		opts.MaxDepth = *job.MaxDepth
+	}
+	
+	// Retries are the same scan, so they are only counted once
+	if job.RetryCount == 0 {
+		metrics.ObserveScan(metrics.ModeAsync)
+	}
 	
 	// Check cache first
-	if cachedResult, found := wp.cacheManager.Get(job.URL); found {
+	var cachedResult *cache.CachedResult
+	found := false
+	if !incremental && !job.ForceRefresh {
+		cachedResult, found = cacheManager.Get(cacheKey)
+		// Jobs have no one waiting on them, so stale results are re-crawled
+		found = found && cachedResult.CoversDepth(opts.MaxDepth) && !cachedResult.IsStale(wp.config.CacheExpirationTime)
+		metrics.ObserveCacheLookup(found)
+	}
+	if found {
 		log.Printf("Worker %d: cache hit for job %s", workerID, job.ID)
 		
 		crawlTime := time.Since(startTime).String()
 		err := wp.queue.CompleteJob(job, cachedResult.Emails, cachedResult.CrawlInfo.PagesVisited, crawlTime)
 		if err != nil {
 			log.Printf("Worker %d: failed to complete cached job %s: %v", workerID, job.ID, err)
-			wp.queue.FailJob(job, fmt.Sprintf("Failed to complete job: %v", err))
+			wp.queue.FailJob(job, fmt.Sprintf("Failed to complete job: %v", err), false)
 			return
 		}
+		wp.queue.RecordDuration(time.Since(startTime))
 		
 		wp.sendWebhook(workerID, job)
 		return
@@ -112,52 +308,115 @@ func (wp *WorkerPool) processJob(workerID int, job *ScanJob) {
 	startURL, err := url.Parse(job.URL)
 	if err != nil {
 		log.Printf("Worker %d: invalid URL for job %s: %v", workerID, job.ID, err)
-		wp.queue.FailJob(job, fmt.Sprintf("Invalid URL: %v", err))
+		wp.queue.FailJob(job, fmt.Sprintf("Invalid URL: %v", err), false)
 		wp.sendWebhook(workerID, job)
 		return
 	}
 	
 	// Create crawler with timeout context
-	crawlerCtx, crawlerCancel := context.WithTimeout(wp.ctx, wp.config.AsyncJobTimeout)
+	timeout := wp.config.AsyncJobTimeout
+	if tenant != nil && tenant.Timeout() > 0 {
+		timeout = tenant.Timeout()
+	}
+	crawlerCtx, crawlerCancel := context.WithTimeout(wp.ctx, timeout)
 	defer crawlerCancel()
+	cancelled := wp.watchCancel(crawlerCtx, crawlerCancel, job.ID)
 	
 	// Perform crawl
-	c := crawler.New(wp.config.MaxDepth)
+	if incremental {
+		opts.IfModifiedSince = *job.IfModifiedSince
+	}
+	if wp.config.AsyncResumableCrawls {
+		wp.enableResume(workerID, job, &opts)
+	}
+	c := crawler.NewWithOptions(opts)
+	
+	// The crawl stops fetching once the job times out or the pool shuts down
+	foundEmailsMap := c.CrawlWithContext(crawlerCtx, startURL)
 	
-	// TODO: Add context support to crawler for cancellation
-	// For now, we'll rely on the timeout
-	foundEmailsMap := c.Crawl(startURL)
+	cacheManager.MarkCrawled(job.URL, wp.config.ScanMinInterval)
+	
+	// A cancelled job keeps nothing from the partial crawl
+	if cancelled.Load() {
+		log.Printf("Worker %d: job %s cancelled", workerID, job.ID)
+		wp.queue.DeleteFrontier(job.ID)
+		if err := wp.queue.MarkCancelled(job); err != nil {
+			log.Printf("Worker %d: failed to mark job %s cancelled: %v", workerID, job.ID, err)
+		}
+		return
+	}
 	
 	// Check if context was cancelled
 	select {
 	case <-crawlerCtx.Done():
+		if wp.ctx.Err() != nil && wp.config.AsyncResumableCrawls {
+			// Shutting down: keep the frontier and queue the job to resume
+			log.Printf("Worker %d: job %s interrupted by shutdown, requeueing it to resume", workerID, job.ID)
+			if err := wp.queue.RequeueJob(job); err != nil {
+				log.Printf("Worker %d: failed to requeue job %s: %v", workerID, job.ID, err)
+			}
+			return
+		}
 		log.Printf("Worker %d: job %s timed out", workerID, job.ID)
-		wp.queue.FailJob(job, "Job timed out")
+		metrics.ObserveCrawl(job.URL, time.Since(startTime), len(foundEmailsMap), metrics.OutcomeTimeout)
+		// A retry resumes from the saved frontier when crawls are resumable
+		if retried, _ := wp.queue.FailJob(job, "Job timed out", true); retried {
+			return
+		}
+		wp.queue.DeleteFrontier(job.ID)
 		wp.sendWebhook(workerID, job)
 		return
 	default:
 		// Continue processing
 	}
 	
+	// A start page that couldn't be fetched leaves nothing worth caching
+	if startErr := c.StartPageError(); startErr != nil && len(foundEmailsMap) == 0 {
+		log.Printf("Worker %d: job %s could not fetch %s: %v", workerID, job.ID, job.URL, startErr)
+		metrics.ObserveCrawl(job.URL, time.Since(startTime), 0, metrics.OutcomeError)
+		if retried, _ := wp.queue.FailJob(job, fmt.Sprintf("Failed to fetch start page: %v", startErr), retryableCrawlError(startErr)); retried {
+			return
+		}
+		wp.queue.DeleteFrontier(job.ID)
+		wp.sendWebhook(workerID, job)
+		return
+	}
+	
 	// Convert map to slice
 	emailList := make([]string, 0, len(foundEmailsMap))
 	for email := range foundEmailsMap {
 		emailList = append(emailList, email)
 	}
+	if wp.config.CrawlerValidateMX {
+		emailList = wp.emailChecker.DropUndeliverable(wp.ctx, emailList)
+	}
 	
 	// Cache the result
-	wp.cacheManager.Set(job.URL, emailList, wp.config.MaxDepth, len(foundEmailsMap))
+	if !incremental {
+		cacheManager.Set(cacheKey, cache.CachedResult{
+			Emails:    emailList,
+			CrawlInfo: cache.CrawlInfo{Depth: opts.MaxDepth, PagesVisited: c.PagesVisited(), DepthReached: c.MaxDepthReached(), Truncated: c.Truncated()},
+			Labels:    c.Labels(),
+
+			OriginalCase: c.OriginalCase(),
+			Sources:      c.Sources(),
+		})
+	}
 	
 	// Get deduplicated emails
-	deduplicatedEmails := wp.cacheManager.DeduplicateEmails(emailList)
+	deduplicatedEmails := cacheManager.DeduplicateEmails(emailList)
 	
 	crawlTime := time.Since(startTime).String()
+	metrics.ObserveCrawl(job.URL, time.Since(startTime), len(deduplicatedEmails), metrics.OutcomeSuccess)
 	
 	// Complete job
-	err = wp.queue.CompleteJob(job, deduplicatedEmails, len(foundEmailsMap), crawlTime)
+	wp.queue.DeleteFrontier(job.ID)
+	err = wp.queue.CompleteJob(job, deduplicatedEmails, c.PagesVisited(), crawlTime)
 	if err != nil {
 		log.Printf("Worker %d: failed to complete job %s: %v", workerID, job.ID, err)
-		wp.queue.FailJob(job, fmt.Sprintf("Failed to complete job: %v", err))
+		wp.queue.FailJob(job, fmt.Sprintf("Failed to complete job: %v", err), false)
+	} else {
+		wp.queue.RecordDuration(time.Since(startTime))
 	}
 	
 	log.Printf("Worker %d: completed job %s in %s, found %d emails", 
@@ -167,12 +426,92 @@ func (wp *WorkerPool) processJob(workerID int, job *ScanJob) {
 	wp.sendWebhook(workerID, job)
 }
 
+// cancelPollInterval is how often a running job checks for cancellation
+const cancelPollInterval = time.Second
+
+// watchCancel polls for a cancellation request for jobID until ctx is done,
+// calling cancel to stop the crawl when one arrives. The returned flag reports
+// whether the job was cancelled.
+func (wp *WorkerPool) watchCancel(ctx context.Context, cancel context.CancelFunc, jobID string) *atomic.Bool {
+	cancelled := &atomic.Bool{}
+	go func() {
+		ticker := time.NewTicker(cancelPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if wp.queue.CancelRequested(jobID) {
+					cancelled.Store(true)
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+	return cancelled
+}
+
+// enableResume makes the crawl checkpoint its frontier under the job ID and, if
+// an earlier attempt left one behind, continue from it.
+func (wp *WorkerPool) enableResume(workerID int, job *ScanJob, opts *crawler.Options) {
+	frontier, err := wp.queue.LoadFrontier(job.ID)
+	if err != nil {
+		log.Printf("Worker %d: failed to load frontier for job %s: %v", workerID, job.ID, err)
+	} else if frontier != nil {
+		log.Printf("Worker %d: resuming job %s (%d pages already visited)", workerID, job.ID, len(frontier.Visited))
+		opts.Resume = frontier
+	}
+
+	opts.Checkpoint = func(f *crawler.Frontier) {
+		if err := wp.queue.SaveFrontier(job.ID, f); err != nil {
+			log.Printf("Worker %d: failed to save frontier for job %s: %v", workerID, job.ID, err)
+		}
+	}
+}
+
+// sendWebhook delivers the job's terminal callback and records the outcome on
+// the job, so its status shows whether the callback got through.
 func (wp *WorkerPool) sendWebhook(workerID int, job *ScanJob) {
-	if job.WebhookURL == "" {
-		log.Printf("Worker %d: no webhook URL for job %s", workerID, job.ID)
+	wp.notify(fmt.Sprintf("Worker %d", workerID), job)
+}
+
+// notify is sendWebhook for callers other than a worker, named by source.
+func (wp *WorkerPool) notify(source string, job *ScanJob) {
+	deliveries := deliverWebhook(wp.config, source, job)
+	if len(deliveries) == 0 {
 		return
 	}
+	job.recordDeliveries(deliveries)
+	if err := wp.queue.UpdateJob(job); err != nil {
+		log.Printf("%s: failed to record webhook deliveries for job %s: %v", source, job.ID, err)
+	}
+}
+
+// NotifyEnqueued sends the job's webhook a "queued" callback in the background,
+// for requests that set notify_on_enqueue. The terminal callback follows later.
+func NotifyEnqueued(cfg *config.Config, job *ScanJob) {
+	go deliverWebhook(cfg, "Enqueue", job)
+}
+
+// deliverWebhook posts the job's current state to each of its webhook URLs
+// concurrently, each with its own retries, so a failing receiver doesn't hold
+// up the others. source prefixes log lines ("Worker 2", "Enqueue"). It returns
+// one status per receiver, in WebhookTargets order.
+func deliverWebhook(cfg *config.Config, source string, job *ScanJob) []WebhookDeliveryStatus {
+	targets := job.WebhookTargets()
+	if len(targets) == 0 {
+		log.Printf("%s: no webhook URL for job %s", source, job.ID)
+		return nil
+	}
 	
+	var completedAt *time.Time
+	if job.Status != StatusQueued && job.Status != StatusScheduled {
+		now := time.Now()
+		completedAt = &now
+	}
+
 	payload := WebhookPayload{
 		JobID:        job.ID,
 		CallbackID:   job.CallbackID,
@@ -181,33 +520,62 @@ func (wp *WorkerPool) sendWebhook(workerID int, job *ScanJob) {
 		Emails:       job.Emails,
 		CrawlTime:    job.CrawlTime,
 		PagesVisited: job.PagesVisited,
-		CompletedAt:  time.Now(),
+		CompletedAt:  completedAt,
 		Error:        job.Error,
 	}
 	
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
-		log.Printf("Worker %d: failed to marshal webhook payload for job %s: %v", workerID, job.ID, err)
-		return
+		log.Printf("%s: failed to marshal webhook payload for job %s: %v", source, job.ID, err)
+		return nil
 	}
-	
+
+	deliveries := make([]WebhookDeliveryStatus, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target string) {
+			defer wg.Done()
+			deliveries[i] = deliverWebhookTo(cfg, source, job, target, jsonData)
+		}(i, target)
+	}
+	wg.Wait()
+	return deliveries
+}
+
+// deliverWebhookTo posts body to a single receiver, retrying with backoff up
+// to ASYNC_WEBHOOK_RETRIES times.
+func deliverWebhookTo(cfg *config.Config, source string, job *ScanJob, target string, body []byte) WebhookDeliveryStatus {
+	delivery := WebhookDeliveryStatus{URL: target, Status: job.Status}
+	client := &http.Client{
+		Timeout: cfg.AsyncWebhookTimeout,
+	}
+	if !cfg.AllowPrivateTargets {
+		client.Transport = ssrf.NewTransport()
+	}
+
 	// Try webhook delivery with retries
-	for attempt := 1; attempt <= wp.config.AsyncWebhookRetries; attempt++ {
-		log.Printf("Worker %d: sending webhook for job %s (attempt %d/%d)", 
-			workerID, job.ID, attempt, wp.config.AsyncWebhookRetries)
-		
-		client := &http.Client{
-			Timeout: wp.config.AsyncWebhookTimeout,
-		}
+	for attempt := 1; attempt <= cfg.AsyncWebhookRetries; attempt++ {
+		log.Printf("%s: sending webhook for job %s to %s (attempt %d/%d)", 
+			source, job.ID, target, attempt, cfg.AsyncWebhookRetries)
+		delivery.Attempts = attempt
+		delivery.At = time.Now()
 		
-		resp, err := client.Post(job.WebhookURL, "application/json", bytes.NewBuffer(jsonData))
+		statusCode, err := postWebhook(context.Background(), client, cfg, webhookDelivery{
+			URL:      target,
+			Body:     body,
+			Headers:  job.WebhookHeaders,
+			Compress: job.WebhookCompress || cfg.WebhookCompress,
+		})
+		delivery.StatusCode = statusCode
 		if err != nil {
-			log.Printf("Worker %d: webhook attempt %d failed for job %s: %v", 
-				workerID, attempt, job.ID, err)
+			delivery.Error = err.Error()
+			log.Printf("%s: webhook attempt %d to %s failed for job %s: %v", 
+				source, attempt, target, job.ID, err)
 			
-			if attempt == wp.config.AsyncWebhookRetries {
-				log.Printf("Worker %d: all webhook attempts failed for job %s", workerID, job.ID)
-				return
+			if attempt == cfg.AsyncWebhookRetries {
+				log.Printf("%s: all webhook attempts to %s failed for job %s", source, target, job.ID)
+				return delivery
 			}
 			
 			// Exponential backoff
@@ -215,24 +583,26 @@ func (wp *WorkerPool) sendWebhook(workerID int, job *ScanJob) {
 			continue
 		}
 		
-		resp.Body.Close()
-		
-		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			log.Printf("Worker %d: webhook delivered successfully for job %s (status: %d)", 
-				workerID, job.ID, resp.StatusCode)
-			return
+		if statusCode >= 200 && statusCode < 300 {
+			log.Printf("%s: webhook delivered successfully to %s for job %s (status: %d)", 
+				source, target, job.ID, statusCode)
+			delivery.Delivered = true
+			delivery.Error = ""
+			return delivery
 		}
 		
-		log.Printf("Worker %d: webhook attempt %d returned status %d for job %s", 
-			workerID, attempt, resp.StatusCode, job.ID)
+		delivery.Error = fmt.Sprintf("receiver returned status %d", statusCode)
+		log.Printf("%s: webhook attempt %d to %s returned status %d for job %s", 
+			source, attempt, target, statusCode, job.ID)
 		
-		if attempt == wp.config.AsyncWebhookRetries {
-			log.Printf("Worker %d: webhook failed with status %d for job %s", 
-				workerID, resp.StatusCode, job.ID)
-			return
+		if attempt == cfg.AsyncWebhookRetries {
+			log.Printf("%s: webhook to %s failed with status %d for job %s", 
+				source, target, statusCode, job.ID)
+			return delivery
 		}
 		
 		// Exponential backoff
 		time.Sleep(time.Duration(attempt) * 2 * time.Second)
 	}
+	return delivery
 }
\ No newline at end of file
@@ -0,0 +1,95 @@
+package jobs
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"email-crawler/internal/cache"
+)
+
+func TestZeroWorkersQueueJobsUntilScaledUp(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.AllowPrivateTargets = true
+	cfg.CacheBackend = "memory"
+	cfg.AsyncWorkers = 0
+	cfg.AsyncWebhookRetries = 1
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body>info@site.test</body></html>`)
+	}))
+	defer site.Close()
+	delivered := make(chan struct{}, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered <- struct{}{}
+	}))
+	defer receiver.Close()
+
+	queue := NewMemoryQueue(cfg)
+	pool := NewWorkerPool(queue, cache.New(cfg), cfg)
+	pool.Start()
+	defer pool.Stop()
+
+	job, err := queue.Enqueue(AsyncScanRequest{URL: site.URL, WebhookURL: receiver.URL})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	select {
+	case <-delivered:
+		t.Fatal("a job was processed with zero workers")
+	case <-time.After(300 * time.Millisecond):
+	}
+	assertStatus(t, queue, job.ID, StatusQueued)
+
+	if previous := pool.Resize(1); previous != 0 || pool.Size() != 1 {
+		t.Fatalf("Resize(1) went from %d to %d workers, want 0 to 1", previous, pool.Size())
+	}
+	select {
+	case <-delivered:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the job was not processed after scaling up")
+	}
+	done := assertStatus(t, queue, job.ID, StatusCompleted)
+	if len(done.Emails) != 1 || done.Emails[0] != "info@site.test" {
+		t.Errorf("job emails = %v, want [info@site.test]", done.Emails)
+	}
+}
+
+func TestZeroLeaseRunsJobsWithoutReaper(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.AllowPrivateTargets = true
+	cfg.CacheBackend = "memory"
+	cfg.AsyncWorkers = 1
+	cfg.AsyncLeaseTTL = 0
+	cfg.AsyncWebhookRetries = 1
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body>info@site.test</body></html>`)
+	}))
+	defer site.Close()
+	delivered := make(chan struct{}, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered <- struct{}{}
+	}))
+	defer receiver.Close()
+
+	queue := NewMemoryQueue(cfg)
+	pool := NewWorkerPool(queue, cache.New(cfg), cfg)
+	pool.Start()
+	defer pool.Stop()
+
+	job, err := queue.Enqueue(AsyncScanRequest{URL: site.URL, WebhookURL: receiver.URL})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	select {
+	case <-delivered:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the job was not processed with ASYNC_LEASE_SECONDS=0")
+	}
+	assertStatus(t, queue, job.ID, StatusCompleted)
+}
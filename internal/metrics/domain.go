@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DomainMetrics records crawl outcomes labelled by domain. At most maxDomains
+// domains are tracked; past the cap the least recently used domain's series
+// are deleted to keep label cardinality bounded.
+type DomainMetrics struct {
+	mu         sync.Mutex
+	maxDomains int
+	lastUsed   map[string]time.Time
+
+	duration *prometheus.HistogramVec
+	emails   *prometheus.HistogramVec
+	crawls   *prometheus.CounterVec
+}
+
+// NewDomainMetrics creates the per-domain collectors and registers them with reg.
+func NewDomainMetrics(reg prometheus.Registerer, maxDomains int) *DomainMetrics {
+	m := &DomainMetrics{
+		maxDomains: maxDomains,
+		lastUsed:   make(map[string]time.Time),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "crawler_domain_crawl_duration_seconds",
+			Help:    "Crawl duration by domain.",
+			Buckets: []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+		}, []string{"domain"}),
+		emails: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "crawler_domain_emails_found",
+			Help:    "Emails found per crawl by domain.",
+			Buckets: []float64{0, 1, 2, 5, 10, 25, 50, 100},
+		}, []string{"domain"}),
+		crawls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "crawler_domain_crawls_total",
+			Help: "Crawls by domain and outcome.",
+		}, []string{"domain", "outcome"}),
+	}
+	reg.MustRegister(m.duration, m.emails, m.crawls)
+	return m
+}
+
+// Observe records one crawl of rawURL.
+func (m *DomainMetrics) Observe(rawURL string, duration time.Duration, emails int, outcome string) {
+	domain := domainOf(rawURL)
+	if domain == "" {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, tracked := m.lastUsed[domain]; !tracked && m.maxDomains > 0 && len(m.lastUsed) >= m.maxDomains {
+		m.evictOldest()
+	}
+	m.lastUsed[domain] = time.Now()
+
+	m.duration.WithLabelValues(domain).Observe(duration.Seconds())
+	m.emails.WithLabelValues(domain).Observe(float64(emails))
+	m.crawls.WithLabelValues(domain, outcome).Inc()
+}
+
+// Domains returns the domains currently holding series.
+func (m *DomainMetrics) Domains() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	domains := make([]string, 0, len(m.lastUsed))
+	for domain := range m.lastUsed {
+		domains = append(domains, domain)
+	}
+	return domains
+}
+
+// evictOldest drops every series of the least recently used domain. Callers
+// must hold m.mu.
+func (m *DomainMetrics) evictOldest() {
+	var oldest string
+	var oldestAt time.Time
+	for domain, at := range m.lastUsed {
+		if oldest == "" || at.Before(oldestAt) {
+			oldest, oldestAt = domain, at
+		}
+	}
+
+	delete(m.lastUsed, oldest)
+	m.duration.DeleteLabelValues(oldest)
+	m.emails.DeleteLabelValues(oldest)
+	m.crawls.DeletePartialMatch(prometheus.Labels{"domain": oldest})
+}
+
+// domainOf returns the lowercased host of rawURL without port or "www.".
+func domainOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// seriesDomains returns the domain label of every series of the named metric.
+func seriesDomains(t *testing.T, reg *prometheus.Registry, name string) []string {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	var domains []string
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "domain" {
+					domains = append(domains, label.GetValue())
+				}
+			}
+		}
+	}
+	sort.Strings(domains)
+	return domains
+}
+
+func TestDomainMetricsTrackCrawledHostsUpToTheCap(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := NewDomainMetrics(reg, 2)
+
+	observe := func(rawURL, outcome string) {
+		m.Observe(rawURL, time.Second, 3, outcome)
+		// Keep the last-used times distinct
+		time.Sleep(time.Millisecond)
+	}
+	observe("https://www.Alpha.com/contact", "success")
+	observe("http://beta.com:8080", "success")
+	observe("https://alpha.com", "timeout")
+
+	want := []string{"alpha.com", "beta.com"}
+	for _, name := range []string{"crawler_domain_crawl_duration_seconds", "crawler_domain_emails_found"} {
+		if got := seriesDomains(t, reg, name); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s series for %v, want %v", name, got, want)
+		}
+	}
+	if got := seriesDomains(t, reg, "crawler_domain_crawls_total"); !reflect.DeepEqual(got, []string{"alpha.com", "alpha.com", "beta.com"}) {
+		t.Errorf("crawls_total series for %v, want alpha.com twice (success and timeout) and beta.com", got)
+	}
+
+	// A third domain evicts the least recently used one, beta.com
+	observe("https://gamma.com", "success")
+	want = []string{"alpha.com", "gamma.com"}
+	for _, name := range []string{"crawler_domain_crawl_duration_seconds", "crawler_domain_emails_found"} {
+		if got := seriesDomains(t, reg, name); !reflect.DeepEqual(got, want) {
+			t.Errorf("after eviction, %s series for %v, want %v", name, got, want)
+		}
+	}
+	if got := seriesDomains(t, reg, "crawler_domain_crawls_total"); !reflect.DeepEqual(got, []string{"alpha.com", "alpha.com", "gamma.com"}) {
+		t.Errorf("after eviction, crawls_total series for %v, want beta.com gone", got)
+	}
+	domains := m.Domains()
+	sort.Strings(domains)
+	if !reflect.DeepEqual(domains, want) {
+		t.Errorf("Domains = %v, want %v", domains, want)
+	}
+}
@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"email-crawler/internal/config"
+)
+
+// Outcomes recorded for a crawl.
+const (
+	OutcomeSuccess = "success"
+	OutcomeTimeout = "timeout"
+	OutcomeError   = "error"
+)
+
+var (
+	registry = prometheus.NewRegistry()
+	service  *ServiceMetrics
+	domains  *DomainMetrics
+)
+
+// Init registers the collectors enabled in cfg. It must be called once at
+// startup, before any Observe call.
+func Init(cfg *config.Config) {
+	if cfg.MetricsEnabled {
+		service = NewServiceMetrics(registry)
+		log.Println("Service metrics enabled")
+	}
+	if cfg.MetricsPerDomain {
+		domains = NewDomainMetrics(registry, cfg.MetricsDomainCardinality)
+		log.Printf("Per-domain metrics enabled (max %d domains)", cfg.MetricsDomainCardinality)
+	}
+}
+
+// Enabled reports whether any collector is registered, i.e. whether /metrics
+// should be served.
+func Enabled() bool {
+	return service != nil || domains != nil
+}
+
+// Handler serves the registered metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObserveScan counts an accepted scan request. It is a no-op unless service
+// metrics are enabled, like the other Observe and Watch functions.
+func ObserveScan(mode string) {
+	if service != nil {
+		service.scans.WithLabelValues(mode).Inc()
+	}
+}
+
+// ObserveCacheLookup counts a cache lookup made before deciding to crawl.
+func ObserveCacheLookup(hit bool) {
+	if service == nil {
+		return
+	}
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	service.lookups.WithLabelValues(result).Inc()
+}
+
+// WatchJobs exposes the job queue size and active job count as gauges.
+func WatchJobs(counts func() (queued, active int)) {
+	if service != nil {
+		service.WatchJobs(counts)
+	}
+}
+
+// ObserveCrawl records a completed crawl of rawURL.
+func ObserveCrawl(rawURL string, duration time.Duration, emails int, outcome string) {
+	if service != nil {
+		service.Observe(duration, emails, outcome)
+	}
+	if domains != nil {
+		domains.Observe(rawURL, duration, emails, outcome)
+	}
+}
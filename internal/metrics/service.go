@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Scan modes recorded by ObserveScan.
+const (
+	ModeSync  = "sync"
+	ModeAsync = "async"
+)
+
+// ServiceMetrics records service-wide scan, cache and crawl activity.
+type ServiceMetrics struct {
+	reg prometheus.Registerer
+
+	scans    *prometheus.CounterVec
+	lookups  *prometheus.CounterVec
+	emails   prometheus.Counter
+	duration *prometheus.HistogramVec
+}
+
+// NewServiceMetrics creates the service-wide collectors and registers them
+// with reg.
+func NewServiceMetrics(reg prometheus.Registerer) *ServiceMetrics {
+	m := &ServiceMetrics{
+		reg: reg,
+		scans: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "crawler_scans_total",
+			Help: "Scan requests accepted, by mode (sync or async).",
+		}, []string{"mode"}),
+		lookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "crawler_cache_lookups_total",
+			Help: "Cache lookups before a crawl, by result (hit or miss).",
+		}, []string{"result"}),
+		emails: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "crawler_emails_found_total",
+			Help: "Emails found by crawls, excluding cache hits.",
+		}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "crawler_crawl_duration_seconds",
+			Help:    "Crawl duration by outcome.",
+			Buckets: []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+		}, []string{"outcome"}),
+	}
+	reg.MustRegister(m.scans, m.lookups, m.emails, m.duration)
+	return m
+}
+
+// Observe records one crawl.
+func (m *ServiceMetrics) Observe(duration time.Duration, emails int, outcome string) {
+	m.duration.WithLabelValues(outcome).Observe(duration.Seconds())
+	m.emails.Add(float64(emails))
+}
+
+// WatchJobs registers gauges that read the job queue size and active job count
+// from counts on every scrape.
+func (m *ServiceMetrics) WatchJobs(counts func() (queued, active int)) {
+	m.reg.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "crawler_queue_size",
+			Help: "Async jobs waiting in the queue.",
+		}, func() float64 {
+			queued, _ := counts()
+			return float64(queued)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "crawler_active_jobs",
+			Help: "Async jobs queued or being processed.",
+		}, func() float64 {
+			_, active := counts()
+			return float64(active)
+		}),
+	)
+}
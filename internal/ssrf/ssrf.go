@@ -0,0 +1,85 @@
+package ssrf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateTarget is returned when a target resolves to an address that must
+// not be reached from this service (loopback, private, link-local, ...).
+var ErrPrivateTarget = errors.New("target resolves to a private or internal address")
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which net.IP
+// doesn't classify as private.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// IsPrivateIP reports whether ip is loopback, private, link-local, multicast,
+// unspecified or otherwise not publicly routable.
+func IsPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified() ||
+		sharedAddressSpace.Contains(ip)
+}
+
+// CheckHost resolves host and returns ErrPrivateTarget if any of its addresses
+// is private. It gives callers a clear error up front; Control still guards
+// the actual connection against DNS rebinding.
+func CheckHost(ctx context.Context, host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if IsPrivateIP(ip) {
+			return fmt.Errorf("%w: %s", ErrPrivateTarget, host)
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %v", host, err)
+	}
+	for _, addr := range addrs {
+		if IsPrivateIP(addr.IP) {
+			return fmt.Errorf("%w: %s (%s)", ErrPrivateTarget, host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// Control is a net.Dialer Control hook that refuses to connect to private
+// addresses. It runs after DNS resolution, on the exact IP being dialed.
+func Control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || IsPrivateIP(ip) {
+		return fmt.Errorf("%w: %s", ErrPrivateTarget, host)
+	}
+	return nil
+}
+
+// NewTransport returns an http.Transport whose connections are checked by
+// Control. It ignores the proxy environment variables: through a proxy,
+// Control would only see the proxy's address, never the target's.
+func NewTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   Control,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+	return transport
+}
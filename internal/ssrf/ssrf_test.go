@@ -0,0 +1,46 @@
+package ssrf
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestIsPrivateIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"::1", true},
+		{"fe80::1", true},
+		{"93.184.216.34", false},
+		{"2606:2800:220:1:248:1893:25c8:1946", false},
+	}
+	for _, tt := range tests {
+		if got := IsPrivateIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("IsPrivateIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestControl(t *testing.T) {
+	if err := Control("tcp", "169.254.169.254:80", nil); !errors.Is(err, ErrPrivateTarget) {
+		t.Errorf("Control(169.254.169.254:80) = %v, want ErrPrivateTarget", err)
+	}
+	if err := Control("tcp", "93.184.216.34:443", nil); err != nil {
+		t.Errorf("Control(93.184.216.34:443) = %v, want nil", err)
+	}
+}
+
+func TestNewTransportIgnoresProxyEnvironment(t *testing.T) {
+	if NewTransport().Proxy != nil {
+		t.Fatal("NewTransport honours the proxy environment; Control would vet the proxy instead of the target")
+	}
+}